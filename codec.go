@@ -0,0 +1,442 @@
+package pubnub
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	cbor "github.com/brianolson/cbor_go"
+)
+
+// codecMetaKey is the meta key carrying the name of the codec a message was encoded with.
+// Messages without it are plain JSON.
+const codecMetaKey = "pn_codec"
+
+// StrInvalidCodecMeta shows `Meta must be a map to use a codec` message
+const StrInvalidCodecMeta = "Meta must be a map[string]interface{} to use a codec"
+
+// Codec encodes the messages into a string payload before encryption and decodes
+// them back after decryption. The name of the codec is sent in the message meta.
+type Codec interface {
+	Name() string
+	Encode(message interface{}) (string, error)
+	Decode(payload string) (interface{}, error)
+}
+
+// JSONCodec is the default codec, the messages are sent as JSON.
+type JSONCodec struct{}
+
+// NewJSONCodec returns the default JSON codec.
+func NewJSONCodec() *JSONCodec {
+	return &JSONCodec{}
+}
+
+// Name returns `json`.
+func (c *JSONCodec) Name() string {
+	return "json"
+}
+
+// Encode serializes the message to JSON.
+func (c *JSONCodec) Encode(message interface{}) (string, error) {
+	b, err := json.Marshal(message)
+	return string(b), err
+}
+
+// Decode parses the JSON payload.
+func (c *JSONCodec) Decode(payload string) (interface{}, error) {
+	var v interface{}
+	err := json.Unmarshal([]byte(payload), &v)
+	return v, err
+}
+
+// CBORCodec sends the messages as base64 encoded CBOR strings.
+type CBORCodec struct{}
+
+// NewCBORCodec returns the CBOR/base64 codec.
+func NewCBORCodec() *CBORCodec {
+	return &CBORCodec{}
+}
+
+// Name returns `cbor`.
+func (c *CBORCodec) Name() string {
+	return "cbor"
+}
+
+// Encode serializes the message to CBOR and encodes it to base64.
+func (c *CBORCodec) Encode(message interface{}) (string, error) {
+	b, err := cbor.Dumps(message)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// Decode decodes the base64 CBOR payload into the same types encoding/json
+// produces: map[string]interface{}, []interface{}, float64, string, bool and nil.
+func (c *CBORCodec) Decode(payload string) (interface{}, error) {
+	b, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, err
+	}
+	d := &cborDecoder{data: b}
+	v, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, errors.New("cbor: trailing data")
+	}
+	return v, nil
+}
+
+// cborDecoder is a minimal generic CBOR decoder, cbor_go can't decode nested
+// maps and arrays into interface{} values.
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+var errCBORBreak = errors.New("cbor: break")
+
+func (d *cborDecoder) read(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, errors.New("cbor: unexpected end of data")
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// length returns the length read from the data, -1 when it's greater than the remaining data.
+func (d *cborDecoder) length(arg uint64) int {
+	if arg > uint64(len(d.data)-d.pos) {
+		return -1
+	}
+	return int(arg)
+}
+
+func (d *cborDecoder) argument(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		b, err := d.read(1)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(b[0]), nil
+	case info == 25:
+		b, err := d.read(2)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case info == 26:
+		b, err := d.read(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	case info == 27:
+		b, err := d.read(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.BigEndian.Uint64(b), nil
+	}
+	return 0, fmt.Errorf("cbor: invalid additional info %d", info)
+}
+
+func (d *cborDecoder) decode() (interface{}, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+
+	if major == 7 {
+		return d.decodeSimple(info)
+	}
+	if info == 31 {
+		return d.decodeIndefinite(major)
+	}
+
+	arg, err := d.argument(info)
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case 0:
+		return float64(arg), nil
+	case 1:
+		return -1 - float64(arg), nil
+	case 2:
+		bytes, err := d.read(d.length(arg))
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(bytes), nil
+	case 3:
+		text, err := d.read(d.length(arg))
+		if err != nil {
+			return nil, err
+		}
+		return string(text), nil
+	case 4:
+		// each item takes at least a byte, the length is checked before the allocation
+		if d.length(arg) < 0 {
+			return nil, errors.New("cbor: unexpected end of data")
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 5:
+		// each entry takes at least two bytes
+		if d.length(arg) < 0 || arg > uint64(len(d.data)-d.pos)/2 {
+			return nil, errors.New("cbor: unexpected end of data")
+		}
+		m := make(map[string]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			if err := d.decodeEntry(m); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	default:
+		// tags are ignored, the tagged value is returned as is
+		return d.decode()
+	}
+}
+
+func (d *cborDecoder) decodeIndefinite(major byte) (interface{}, error) {
+	switch major {
+	case 2, 3:
+		s := ""
+		for {
+			chunk, err := d.decode()
+			if err == errCBORBreak {
+				if major == 2 {
+					return base64.StdEncoding.EncodeToString([]byte(s)), nil
+				}
+				return s, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			part, ok := chunk.(string)
+			if !ok {
+				return nil, errors.New("cbor: invalid indefinite string chunk")
+			}
+			if major == 2 {
+				raw, _ := base64.StdEncoding.DecodeString(part)
+				part = string(raw)
+			}
+			s += part
+		}
+	case 4:
+		items := []interface{}{}
+		for {
+			item, err := d.decode()
+			if err == errCBORBreak {
+				return items, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+	case 5:
+		m := make(map[string]interface{})
+		for {
+			err := d.decodeEntry(m)
+			if err == errCBORBreak {
+				return m, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, fmt.Errorf("cbor: invalid indefinite length for major type %d", major)
+}
+
+func (d *cborDecoder) decodeEntry(m map[string]interface{}) error {
+	key, err := d.decode()
+	if err != nil {
+		return err
+	}
+	value, err := d.decode()
+	if err != nil {
+		return err
+	}
+	if k, ok := key.(string); ok {
+		m[k] = value
+	} else {
+		m[fmt.Sprint(key)] = value
+	}
+	return nil
+}
+
+func (d *cborDecoder) decodeSimple(info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		b, err := d.read(2)
+		if err != nil {
+			return nil, err
+		}
+		return halfToFloat64(binary.BigEndian.Uint16(b)), nil
+	case 26:
+		b, err := d.read(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 27:
+		b, err := d.read(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 31:
+		return nil, errCBORBreak
+	}
+	return nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+}
+
+func halfToFloat64(h uint16) float64 {
+	exp := int((h >> 10) & 0x1f)
+	mant := float64(h & 0x3ff)
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			v = math.Inf(1)
+		} else {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -v
+	}
+	return v
+}
+
+// includeMeta reports if the meta of the stored messages is fetched: when it is
+// requested, or when a Codec is set on the Config, as the meta holds the codec
+// the messages are decoded with.
+func (pn *PubNub) includeMeta(requested bool) bool {
+	return requested || pn.Config.Codec != nil
+}
+
+// requestedMeta returns the meta of a stored message, without the codec when
+// the meta wasn't requested, nil when it held only the codec.
+func requestedMeta(meta interface{}, requested bool) interface{} {
+	m, ok := meta.(map[string]interface{})
+	if requested || !ok {
+		return meta
+	}
+	if _, ok := m[codecMetaKey]; !ok {
+		return meta
+	}
+	if len(m) == 1 {
+		return nil
+	}
+	stripped := make(map[string]interface{}, len(m)-1)
+	for k, v := range m {
+		if k != codecMetaKey {
+			stripped[k] = v
+		}
+	}
+	return stripped
+}
+
+// codecs returns the codecs known to this client to decode the received messages.
+// The Config codec comes first, it's preferred to a built-in codec of the same name.
+func (pn *PubNub) codecs() []Codec {
+	codecs := []Codec{}
+	if pn.Config.Codec != nil {
+		codecs = append(codecs, pn.Config.Codec)
+	}
+	return append(codecs, NewJSONCodec(), NewCBORCodec())
+}
+
+// outgoingCodec returns the codec of the builder or the one set on the Config,
+// nil when the message is sent as plain JSON.
+func (pn *PubNub) outgoingCodec(builderCodec Codec) Codec {
+	codec := builderCodec
+	if codec == nil {
+		codec = pn.Config.Codec
+	}
+	if codec == nil || codec.Name() == NewJSONCodec().Name() {
+		return nil
+	}
+	return codec
+}
+
+// copyMeta returns a copy of the user meta the SDK keys can be added to,
+// false if the meta is not a map.
+func copyMeta(meta interface{}) (map[string]interface{}, bool) {
+	metaMap := map[string]interface{}{}
+	if meta == nil {
+		return metaMap, true
+	}
+	userMeta, ok := meta.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	for k, v := range userMeta {
+		metaMap[k] = v
+	}
+	return metaMap, true
+}
+
+// encodeWithCodec encodes the message, pre serialized JSON messages are parsed first.
+// The encoded payload is sent as a JSON string.
+func encodeWithCodec(codec Codec, message interface{}, serialized bool) (string, error) {
+	if s, ok := message.(string); ok && serialized {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(s), &decoded); err != nil {
+			return "", err
+		}
+		message = decoded
+	}
+	return codec.Encode(message)
+}
+
+// decodeWithCodec decodes the (already decrypted) message with the codec named
+// in the meta. Messages without a codec name are returned as is.
+func decodeWithCodec(pn *PubNub, message interface{}, meta interface{}) (interface{}, error) {
+	name, ok := metaValue(meta, codecMetaKey)
+	if !ok || name == NewJSONCodec().Name() {
+		return message, nil
+	}
+	payload, ok := message.(string)
+	if !ok {
+		return message, fmt.Errorf("codec %s: expected a string payload, got %T", name, message)
+	}
+	for _, codec := range pn.codecs() {
+		if codec.Name() == name {
+			return codec.Decode(payload)
+		}
+	}
+	return message, fmt.Errorf("unknown codec %s", name)
+}
@@ -0,0 +1,215 @@
+package pubnub
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCBORCodecRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	codec := NewCBORCodec()
+	message := map[string]interface{}{
+		"text":   "hello",
+		"count":  42,
+		"ratio":  -2.5,
+		"ok":     true,
+		"none":   nil,
+		"nested": map[string]interface{}{"list": []interface{}{"a", 1, false}},
+	}
+
+	encoded, err := codec.Encode(message)
+	assert.Nil(err)
+
+	decoded, err := codec.Decode(encoded)
+	assert.Nil(err)
+
+	var expected interface{}
+	b, _ := json.Marshal(message)
+	json.Unmarshal(b, &expected)
+	assert.Equal(expected, decoded)
+
+	_, err = codec.Decode("not base64!")
+	assert.NotNil(err)
+}
+
+func TestCBORDecoderIndefiniteAndHalfFloat(t *testing.T) {
+	assert := assert.New(t)
+
+	d := &cborDecoder{data: []byte{
+		0xbf,                        // indefinite map
+		0x61, 'a', 0xf9, 0x3e, 0x00, // "a": 1.5 (half float)
+		0x61, 'b', 0x9f, 0x01, 0x20, 0xff, // "b": [1, -1]
+		0xff,
+	}}
+	v, err := d.decode()
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"a": 1.5, "b": []interface{}{float64(1), float64(-1)}}, v)
+}
+
+func TestCBORDecoderRejectsLengthsBeyondData(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := map[string][]byte{
+		"array":         {0x9b, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"huge array":    {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"map":           {0xbb, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"map entries":   {0xa2, 0x61, 'a', 0x01},
+		"text":          {0x7b, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 'a'},
+		"bytes":         {0x5a, 0xff, 0xff, 0xff, 0xff, 0x00},
+		"array of size": {0x83, 0x01, 0x02},
+	}
+	for name, data := range cases {
+		_, err := NewCBORCodec().Decode(base64.StdEncoding.EncodeToString(data))
+		assert.NotNil(err, name)
+	}
+}
+
+func TestConfigCodecPreferredToBuiltIn(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewDemoConfig()
+	config.Codec = &upperCodec{name: "cbor"}
+	pn := NewPubNub(config)
+
+	msg, err := decodeWithCodec(pn, "hi", map[string]interface{}{codecMetaKey: "cbor"})
+	assert.Nil(err)
+	assert.Equal("HI", msg)
+}
+
+// upperCodec sends the messages as upper case strings.
+type upperCodec struct {
+	name string
+}
+
+func (c *upperCodec) Name() string {
+	return c.name
+}
+
+func (c *upperCodec) Encode(message interface{}) (string, error) {
+	return strings.ToUpper(fmt.Sprint(message)), nil
+}
+
+func (c *upperCodec) Decode(payload string) (interface{}, error) {
+	return strings.ToUpper(payload), nil
+}
+
+func TestPublishWithCodecAndCipher(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewDemoConfig()
+	config.CipherKey = "enigma"
+	config.Codec = NewCBORCodec()
+	pn := NewPubNub(config)
+	defer pn.Destroy()
+
+	var payload string
+	var meta map[string]interface{}
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		parts := strings.Split(req.URL.Opaque, "/")
+		payload, _ = url.PathUnescape(parts[9])
+		rawMeta, _ := url.QueryUnescape(req.URL.Query().Get("meta"))
+		meta = nil
+		json.Unmarshal([]byte(rawMeta), &meta)
+		return stubResponse(req, `[1,"Sent","15000000000000000"]`), nil
+	})})
+
+	message := map[string]interface{}{"text": "hi", "n": float64(3)}
+	_, _, err := pn.Publish().Channel("ch").Message(message).Meta(map[string]interface{}{"a": "b"}).Execute()
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"a": "b", codecMetaKey: "cbor"}, meta)
+
+	var sent interface{}
+	assert.Nil(json.Unmarshal([]byte(payload), &sent))
+	decrypted, err := parseCipherInterface(sent, pn.Config, pn.getCryptoModule())
+	assert.Nil(err)
+	decoded, err := decodeWithCodec(pn, decrypted, meta)
+	assert.Nil(err)
+	assert.Equal(message, decoded)
+
+	// the builder codec overrides the config codec
+	_, _, err = pn.Publish().Channel("ch").Message(message).Codec(NewJSONCodec()).Execute()
+	assert.Nil(err)
+	assert.Nil(meta)
+
+	_, _, err = pn.Fire().Channel("ch").Message(message).Meta("meta").Execute()
+	assert.Contains(err.Error(), StrInvalidCodecMeta)
+}
+
+func TestDecodeWithCodec(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+
+	msg, err := decodeWithCodec(pn, "plain", nil)
+	assert.Nil(err)
+	assert.Equal("plain", msg)
+
+	_, err = decodeWithCodec(pn, "x", map[string]interface{}{codecMetaKey: "msgpack"})
+	assert.Equal("unknown codec msgpack", err.Error())
+
+	_, err = decodeWithCodec(pn, 1.0, map[string]interface{}{codecMetaKey: "cbor"})
+	assert.NotNil(err)
+}
+
+func TestFetchAndHistoryDecodeCodec(t *testing.T) {
+	assert := assert.New(t)
+
+	encoded, _ := NewCBORCodec().Encode(map[string]interface{}{"text": "hi"})
+
+	fetchJSON := []byte(fmt.Sprintf(`{"status": 200, "error": false, "error_message": "", "channels": {"test":[{"message":"%s","timetoken":"1","meta":{"pn_codec":"cbor"}},{"message":"plain","timetoken":"2","meta":""}]}}`, encoded))
+	fetchResp, _, err := newFetchResponse(fetchJSON, initFetchOpts(""), fakeResponseState)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"text": "hi"}, fetchResp.Messages["test"][0].Message)
+	assert.Nil(fetchResp.Messages["test"][0].Error)
+	assert.Equal("plain", fetchResp.Messages["test"][1].Message)
+
+	historyJSON := []byte(fmt.Sprintf(`[[{"message":"%s","timetoken":1,"meta":{"pn_codec":"cbor"}}],1,1]`, encoded))
+	historyResp, _, err := newHistoryResponse(historyJSON, &historyOpts{endpointOpts: endpointOpts{pubnub: pubnub}, WithMeta: true}, fakeResponseState)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"text": "hi"}, historyResp.Messages[0].Message)
+}
+
+func TestFetchAndHistoryDecodeCodecWithoutIncludeMeta(t *testing.T) {
+	assert := assert.New(t)
+
+	encoded, _ := NewCBORCodec().Encode(map[string]interface{}{"text": "hi"})
+	config := NewDemoConfig()
+	config.Codec = NewCBORCodec()
+	pn := NewPubNub(config)
+	defer pn.Destroy()
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		// the meta holding the codec is fetched when a codec is set
+		assert.Equal("true", req.URL.Query().Get("include_meta"))
+		if strings.Contains(req.URL.Opaque, "/v2/history/") {
+			return stubResponse(req, fmt.Sprintf(`[[{"message":"%s","timetoken":1,"meta":{"pn_codec":"cbor"}}],1,1]`, encoded)), nil
+		}
+		return stubResponse(req, fmt.Sprintf(`{"status": 200, "error": false, "channels": {"test":[{"message":"%s","timetoken":"1","meta":{"pn_codec":"cbor"}}]}}`, encoded)), nil
+	})})
+
+	fetchResp, _, err := pn.Fetch().Channels([]string{"test"}).Execute()
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"text": "hi"}, fetchResp.Messages["test"][0].Message)
+	assert.Nil(fetchResp.Messages["test"][0].Meta)
+
+	historyResp, _, err := pn.History().Channel("test").IncludeTimetoken(true).Execute()
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"text": "hi"}, historyResp.Messages[0].Message)
+	assert.Nil(historyResp.Messages[0].Meta)
+
+	// the meta isn't fetched otherwise
+	pn.Config.Codec = nil
+	query, _ := newFetchOpts(pn, pn.ctx, fetchOpts{Channels: []string{"test"}}).buildQuery()
+	assert.Equal("false", query.Get("include_meta"))
+
+	meta := map[string]interface{}{codecMetaKey: "cbor", "a": "b"}
+	assert.Equal(map[string]interface{}{"a": "b"}, requestedMeta(meta, false))
+	assert.Equal(meta, requestedMeta(meta, true))
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/pubnub/go/v7/crypto"
 	"log"
 	"sync"
+	"time"
 )
 
 const (
@@ -51,9 +52,17 @@ type Config struct {
 	UsePAMV3                     bool               // Use PAM version 2, Objects requets would still use PAM v3
 	StoreTokensOnGrant           bool               // Will store grant v3 tokens in token manager for further use.
 	FileMessagePublishRetryLimit int                // The number of tries made in case of Publish File Message failure.
+	FileSendRetryLimit           int                // The number of tries made in case of failure to generate the upload URL or to upload a file.
+	FileSendRetryBackoff         time.Duration      // The wait before retrying a failed step of SendFile, doubled after each try.
+	ChunkReassemblyTimeout       int                // The time in seconds to wait for the missing chunks of a chunked message before dropping it.
+	SuppressDeliveryAcks         bool               // When true the received messages published with delivery tracking are not acknowledged.
+	Codec                        Codec              // Codec used to encode the published messages, JSON when nil. Decodes the received messages, preferred to a built-in codec of the same name.
 	//DEPRECATED: please use CryptoModule
 	UseRandomInitializationVector bool                // When true the IV will be random for all requests and not just file upload. When false the IV will be hardcoded for all requests except File Upload
 	CryptoModule                  crypto.CryptoModule // A cryptography module used for encryption and decryption
+	// CryptoModuleResolver returns the cryptography module of a channel, used instead of
+	// CryptoModule for the messages and files of the channel. CryptoModule is used when it returns nil.
+	CryptoModuleResolver func(channel string) crypto.CryptoModule
 }
 
 // NewDemoConfig initiates the config with demo keys, for tests only.
@@ -88,6 +97,9 @@ func NewConfigWithUserId(userId UserId) *Config {
 		UsePAMV3:                      true,
 		StoreTokensOnGrant:            true,
 		FileMessagePublishRetryLimit:  5,
+		FileSendRetryLimit:            3,
+		FileSendRetryBackoff:          500 * time.Millisecond,
+		ChunkReassemblyTimeout:        60,
 		UseRandomInitializationVector: true,
 	}
 
@@ -71,6 +71,16 @@ func (c *aesCbcCryptor) EncryptStream(reader io.Reader) (*EncryptedStreamData, e
 	}, nil
 }
 
+func (c *aesCbcCryptor) encryptStreamWithSize(reader io.Reader, size int64) (*EncryptedStreamData, int64, error) {
+	encryptedStreamData, e := c.EncryptStream(reader)
+	return encryptedStreamData, paddedSize(size), e
+}
+
+// paddedSize returns the size of the data padded with PKCS7 to a multiple of the block size.
+func paddedSize(size int64) int64 {
+	return (size/aes.BlockSize + 1) * aes.BlockSize
+}
+
 func (c *aesCbcCryptor) DecryptStream(encryptedData *EncryptedStreamData) (io.Reader, error) {
 	if encryptedData.Metadata == nil {
 		return nil, errors.New("missing metadata")
@@ -0,0 +1,355 @@
+package crypto
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+)
+
+// The data is split in chunks, each one sealed with AES-256-GCM, so that streams
+// are authenticated chunk by chunk. The metadata holds a random salt and the chunk
+// size. Each message is sealed with its own key, derived from the key and the salt
+// with HKDF-SHA256, so the nonce of a chunk is only the index of the chunk and a
+// flag set on the last chunk, which detects reordered and truncated chunks.
+const gcmSaltLength = 32
+const gcmChunkSizeLength = 4
+const gcmMetadataLength = gcmSaltLength + gcmChunkSizeLength
+const gcmTagLength = 16
+
+var gcmKeyInfo = []byte("pubnub aes-gcm message key")
+
+// DefaultAesGcmChunkSize is the size of the plaintext of a chunk, 64 KiB.
+const DefaultAesGcmChunkSize = 64 * 1024
+
+// MaxAesGcmChunkSize is the max size of the plaintext of a chunk, 16 MiB.
+const MaxAesGcmChunkSize = 16 * 1024 * 1024
+
+var agcmId = "AGCM"
+
+var errGcmKeyZeroed = errors.New("aes-gcm key zeroed")
+var errGcmCryptorDestroyed = errors.New("aes-gcm cryptor destroyed")
+
+type aesGcmCryptor struct {
+	mu        sync.RWMutex
+	key       []byte
+	chunkSize int
+	destroyed bool
+}
+
+// NewAesGcmCryptor returns an AES-256-GCM cryptor, the key is the SHA-256 of the cipher key.
+func NewAesGcmCryptor(cipherKey string) (ExtendedCryptor, error) {
+	return NewAesGcmCryptorWithChunkSize(cipherKey, DefaultAesGcmChunkSize)
+}
+
+// NewAesGcmCryptorWithChunkSize returns an AES-256-GCM cryptor encrypting chunks
+// of chunkSize bytes. Data encrypted with any chunk size can be decrypted.
+func NewAesGcmCryptorWithChunkSize(cipherKey string, chunkSize int) (ExtendedCryptor, error) {
+	if chunkSize <= 0 || chunkSize > MaxAesGcmChunkSize {
+		return nil, fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+	key := sha256.Sum256([]byte(cipherKey))
+	cryptor, e := newAesGcmCryptorWithKey(key[:], chunkSize)
+	if e != nil {
+		return nil, e
+	}
+	return cryptor, nil
+}
+
+// newAesGcmCryptorWithKey returns a cryptor using the key, which zeroKey and destroy zero.
+func newAesGcmCryptorWithKey(key []byte, chunkSize int) (*aesGcmCryptor, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid key length %d", len(key))
+	}
+	return &aesGcmCryptor{
+		key:       key,
+		chunkSize: chunkSize,
+	}, nil
+}
+
+func (c *aesGcmCryptor) Id() string {
+	return agcmId
+}
+
+func (c *aesGcmCryptor) Encrypt(message []byte) (*EncryptedData, error) {
+	metadata := c.newMetadata()
+	aead, e := c.messageAead(metadata)
+	if e != nil {
+		return nil, e
+	}
+	sealed := make([]byte, 0, len(message)+(len(message)/c.chunkSize+1)*gcmTagLength)
+
+	for index := 0; ; index++ {
+		last := len(message) <= c.chunkSize
+		chunk := message
+		if !last {
+			chunk = message[:c.chunkSize]
+		}
+		nonce, e := gcmNonce(index, last)
+		if e != nil {
+			return nil, e
+		}
+		sealed = aead.Seal(sealed, nonce, chunk, metadata)
+		if last {
+			break
+		}
+		message = message[c.chunkSize:]
+	}
+
+	return &EncryptedData{
+		Metadata: metadata,
+		Data:     sealed,
+	}, nil
+}
+
+func (c *aesGcmCryptor) Decrypt(encryptedData *EncryptedData) ([]byte, error) {
+	chunkSize, e := gcmChunkSize(encryptedData.Metadata)
+	if e != nil {
+		return nil, e
+	}
+	aead, e := c.messageAead(encryptedData.Metadata)
+	if e != nil {
+		return nil, e
+	}
+	sealedChunkSize := chunkSize + gcmTagLength
+	data := encryptedData.Data
+	decrypted := make([]byte, 0, len(data))
+
+	for index := 0; ; index++ {
+		last := len(data) <= sealedChunkSize
+		chunk := data
+		if !last {
+			chunk = data[:sealedChunkSize]
+		}
+		if decrypted, e = gcmOpen(aead, decrypted, encryptedData.Metadata, index, last, chunk); e != nil {
+			return nil, e
+		}
+		if last {
+			return decrypted, nil
+		}
+		data = data[sealedChunkSize:]
+	}
+}
+
+func (c *aesGcmCryptor) EncryptStream(reader io.Reader) (*EncryptedStreamData, error) {
+	metadata := c.newMetadata()
+	aead, e := c.messageAead(metadata)
+	if e != nil {
+		return nil, e
+	}
+
+	return &EncryptedStreamData{
+		Metadata: metadata,
+		Reader: &gcmChunkReader{
+			r:         bufio.NewReader(reader),
+			chunkSize: c.chunkSize,
+			process: func(chunk []byte, index int, last bool) ([]byte, error) {
+				if c.isDestroyed() {
+					return nil, errGcmCryptorDestroyed
+				}
+				nonce, e := gcmNonce(index, last)
+				if e != nil {
+					return nil, e
+				}
+				return aead.Seal(nil, nonce, chunk, metadata), nil
+			},
+		},
+	}, nil
+}
+
+func (c *aesGcmCryptor) encryptStreamWithSize(reader io.Reader, size int64) (*EncryptedStreamData, int64, error) {
+	encryptedStreamData, e := c.EncryptStream(reader)
+	// each chunk is sealed with a tag, empty data is a single empty chunk
+	chunks := (size + int64(c.chunkSize) - 1) / int64(c.chunkSize)
+	if chunks == 0 {
+		chunks = 1
+	}
+	return encryptedStreamData, size + chunks*gcmTagLength, e
+}
+
+func (c *aesGcmCryptor) DecryptStream(encryptedData *EncryptedStreamData) (io.Reader, error) {
+	if encryptedData.Metadata == nil {
+		return nil, errors.New("missing metadata")
+	}
+	chunkSize, e := gcmChunkSize(encryptedData.Metadata)
+	if e != nil {
+		return nil, e
+	}
+	metadata := encryptedData.Metadata
+	aead, e := c.messageAead(metadata)
+	if e != nil {
+		return nil, e
+	}
+
+	return &gcmChunkReader{
+		r:         bufio.NewReader(encryptedData.Reader),
+		chunkSize: chunkSize + gcmTagLength,
+		process: func(chunk []byte, index int, last bool) ([]byte, error) {
+			if c.isDestroyed() {
+				return nil, errGcmCryptorDestroyed
+			}
+			return gcmOpen(aead, nil, metadata, index, last, chunk)
+		},
+	}, nil
+}
+
+// zeroKey zeroes the key, the cryptor fails afterwards. The streams in flight
+// go on with the key of their message, derived when they were started.
+func (c *aesGcmCryptor) zeroKey() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	zeroBytes(c.key)
+	c.key = nil
+}
+
+// destroy zeroes the key, the cryptor and the streams in flight fail afterwards.
+// The AES key schedules of the streams can't be zeroed, they stay in memory
+// until they are garbage collected.
+func (c *aesGcmCryptor) destroy() {
+	c.zeroKey()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.destroyed = true
+}
+
+func (c *aesGcmCryptor) isDestroyed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.destroyed
+}
+
+func (c *aesGcmCryptor) newMetadata() []byte {
+	metadata := generateIV(gcmMetadataLength)
+	binary.BigEndian.PutUint32(metadata[gcmSaltLength:], uint32(c.chunkSize))
+	return metadata
+}
+
+// messageAead returns the AEAD of the message, keyed with the key derived from its salt.
+func (c *aesGcmCryptor) messageAead(metadata []byte) (cipher.AEAD, error) {
+	if len(metadata) != gcmMetadataLength {
+		return nil, fmt.Errorf("invalid metadata length %d", len(metadata))
+	}
+	c.mu.RLock()
+	if c.destroyed {
+		c.mu.RUnlock()
+		return nil, errGcmCryptorDestroyed
+	}
+	if c.key == nil {
+		c.mu.RUnlock()
+		return nil, errGcmKeyZeroed
+	}
+	messageKey := hkdfSha256(c.key, metadata[:gcmSaltLength], gcmKeyInfo)
+	c.mu.RUnlock()
+	defer zeroBytes(messageKey)
+
+	block, e := aes.NewCipher(messageKey)
+	if e != nil {
+		return nil, e
+	}
+	return cipher.NewGCM(block)
+}
+
+// hkdfSha256 returns the 32 bytes key derived from the secret with HKDF-SHA256 (RFC 5869).
+func hkdfSha256(secret, salt, info []byte) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+	defer zeroBytes(prk)
+
+	expand := hmac.New(sha256.New, prk)
+	expand.Write(info)
+	expand.Write([]byte{1})
+	return expand.Sum(nil)
+}
+
+func gcmOpen(aead cipher.AEAD, dst, metadata []byte, index int, last bool, chunk []byte) ([]byte, error) {
+	nonce, e := gcmNonce(index, last)
+	if e != nil {
+		return nil, e
+	}
+	opened, e := aead.Open(dst, nonce, chunk, metadata)
+	if e != nil {
+		return nil, fmt.Errorf("chunk %d: %s", index, e.Error())
+	}
+	return opened, nil
+}
+
+func gcmChunkSize(metadata []byte) (int, error) {
+	if len(metadata) != gcmMetadataLength {
+		return 0, fmt.Errorf("invalid metadata length %d", len(metadata))
+	}
+	chunkSize := binary.BigEndian.Uint32(metadata[gcmSaltLength:])
+	if chunkSize == 0 || chunkSize > MaxAesGcmChunkSize {
+		return 0, fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+	return int(chunkSize), nil
+}
+
+// gcmNonce returns the nonce of a chunk, unique within the message, whose key is unique.
+func gcmNonce(index int, last bool) ([]byte, error) {
+	if uint64(index) > math.MaxUint32 {
+		return nil, errors.New("too many chunks")
+	}
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint32(nonce[7:], uint32(index))
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce, nil
+}
+
+// gcmChunkReader reads its input by chunks of chunkSize bytes, the last one
+// possibly shorter, and returns them processed.
+type gcmChunkReader struct {
+	r         *bufio.Reader
+	chunkSize int
+	process   func(chunk []byte, index int, last bool) ([]byte, error)
+	index     int
+	buffer    []byte
+	err       error
+}
+
+func (reader *gcmChunkReader) Read(p []byte) (int, error) {
+	for len(reader.buffer) == 0 {
+		if reader.err != nil {
+			return 0, reader.err
+		}
+		reader.buffer, reader.err = reader.nextChunk()
+	}
+	n := copy(p, reader.buffer)
+	reader.buffer = reader.buffer[n:]
+	return n, nil
+}
+
+// nextChunk returns the next processed chunk, with io.EOF after the last one.
+func (reader *gcmChunkReader) nextChunk() ([]byte, error) {
+	chunk := make([]byte, reader.chunkSize)
+	n, e := io.ReadFull(reader.r, chunk)
+	last := false
+	if e == io.EOF || e == io.ErrUnexpectedEOF {
+		last = true
+	} else if e != nil {
+		return nil, e
+	} else if _, e = reader.r.Peek(1); e == io.EOF {
+		last = true
+	} else if e != nil {
+		return nil, e
+	}
+
+	processed, e := reader.process(chunk[:n], reader.index, last)
+	if e != nil {
+		return nil, e
+	}
+	reader.index++
+	if last {
+		return processed, io.EOF
+	}
+	return processed, nil
+}
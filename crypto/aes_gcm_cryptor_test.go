@@ -0,0 +1,223 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+	"testing/quick"
+)
+
+const testGcmChunkSize = 16
+
+func canDecryptAesGcmEncryptStreamResult(in []byte) bool {
+	cryptor, e := NewAesGcmCryptorWithChunkSize("enigma", testGcmChunkSize)
+	if e != nil {
+		return false
+	}
+
+	output, err := cryptor.EncryptStream(bytes.NewReader(in))
+	if err != nil {
+		return false
+	}
+
+	encrData, err := io.ReadAll(output.Reader)
+	if err != nil {
+		return false
+	}
+
+	decrypted, err := cryptor.Decrypt(&EncryptedData{
+		Data:     encrData,
+		Metadata: output.Metadata,
+	})
+	if err != nil {
+		println(err.Error())
+		return false
+	}
+	return bytes.Equal(in, decrypted)
+}
+
+func canDecryptAesGcmStreamEncryptResult(in []byte) bool {
+	cryptor, e := NewAesGcmCryptorWithChunkSize("enigma", testGcmChunkSize)
+	if e != nil {
+		return false
+	}
+
+	output, err := cryptor.Encrypt(in)
+	if err != nil {
+		return false
+	}
+
+	decryptingReader, err := cryptor.DecryptStream(&EncryptedStreamData{
+		Reader:   bytes.NewReader(output.Data),
+		Metadata: output.Metadata,
+	})
+	if err != nil {
+		println(err.Error())
+		return false
+	}
+
+	decrypted, err := io.ReadAll(decryptingReader)
+	if err != nil {
+		println(err.Error())
+		return false
+	}
+
+	return bytes.Equal(in, decrypted)
+}
+
+func Test_AesGCM_EncryptStream(t *testing.T) {
+	if err := quick.Check(canDecryptAesGcmEncryptStreamResult, defaultPropertyTestConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_AesGCM_DecryptStream(t *testing.T) {
+	if err := quick.Check(canDecryptAesGcmStreamEncryptResult, defaultPropertyTestConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_AesGCM_ChunkBoundaries(t *testing.T) {
+	cryptor, _ := NewAesGcmCryptorWithChunkSize("enigma", testGcmChunkSize)
+	for _, size := range []int{0, 1, testGcmChunkSize - 1, testGcmChunkSize, testGcmChunkSize + 1, 3 * testGcmChunkSize} {
+		in := bytes.Repeat([]byte{'a'}, size)
+		encrypted, err := cryptor.Encrypt(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks := size/testGcmChunkSize + 1
+		if size > 0 && size%testGcmChunkSize == 0 {
+			chunks--
+		}
+		if len(encrypted.Data) != size+chunks*16 {
+			t.Errorf("size %d: unexpected encrypted size %d", size, len(encrypted.Data))
+		}
+
+		output, _ := cryptor.EncryptStream(bytes.NewReader(in))
+		streamed, _ := io.ReadAll(output.Reader)
+		if len(streamed) != len(encrypted.Data) {
+			t.Errorf("size %d: stream encrypted size %d, expected %d", size, len(streamed), len(encrypted.Data))
+		}
+	}
+}
+
+func Test_AesGCM_DetectsTampering(t *testing.T) {
+	cryptor, _ := NewAesGcmCryptorWithChunkSize("enigma", testGcmChunkSize)
+	in := bytes.Repeat([]byte("0123456789"), 10)
+	encrypted, _ := cryptor.Encrypt(in)
+	sealedChunkSize := testGcmChunkSize + 16
+
+	tampered := append([]byte{}, encrypted.Data...)
+	tampered[20] ^= 1
+	truncated := encrypted.Data[:2*sealedChunkSize]
+	reordered := append(append(append([]byte{}, encrypted.Data[sealedChunkSize:2*sealedChunkSize]...), encrypted.Data[:sealedChunkSize]...), encrypted.Data[2*sealedChunkSize:]...)
+	metadata := append([]byte{}, encrypted.Metadata...)
+	metadata[0] ^= 1
+
+	cases := map[string]*EncryptedData{
+		"tampered":          {Metadata: encrypted.Metadata, Data: tampered},
+		"truncated":         {Metadata: encrypted.Metadata, Data: truncated},
+		"reordered":         {Metadata: encrypted.Metadata, Data: reordered},
+		"tampered metadata": {Metadata: metadata, Data: encrypted.Data},
+		"invalid metadata":  {Metadata: encrypted.Metadata[1:], Data: encrypted.Data},
+	}
+	for name, data := range cases {
+		if _, err := cryptor.Decrypt(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+
+		reader, err := cryptor.DecryptStream(&EncryptedStreamData{Metadata: data.Metadata, Reader: bytes.NewReader(data.Data)})
+		if err == nil {
+			_, err = io.ReadAll(reader)
+		}
+		if err == nil {
+			t.Errorf("%s: expected a stream error", name)
+		}
+	}
+
+	// the chunks before the tampered one are returned before the error
+	tampered = append([]byte{}, encrypted.Data...)
+	tampered[2*sealedChunkSize] ^= 1
+	reader, _ := cryptor.DecryptStream(&EncryptedStreamData{Metadata: encrypted.Metadata, Reader: bytes.NewReader(tampered)})
+	decrypted, err := io.ReadAll(reader)
+	if err == nil || !bytes.Equal(in[:2*testGcmChunkSize], decrypted) {
+		t.Errorf("unexpected partial decryption %q, %v", decrypted, err)
+	}
+}
+
+func Test_AesGCM_InvalidChunkSize(t *testing.T) {
+	if _, err := NewAesGcmCryptorWithChunkSize("enigma", 0); err == nil {
+		t.Error("expected an error")
+	}
+	if _, err := NewAesGcmCryptorWithChunkSize("enigma", MaxAesGcmChunkSize+1); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func Test_AesGCM_Module(t *testing.T) {
+	module, err := NewAesGcmCryptoModule("enigma", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := []byte("Hello world encrypted with aesGcmModule")
+
+	encrypted, err := module.Encrypt(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encrypted[:4], sentinel[:]) || string(encrypted[5:9]) != "AGCM" {
+		t.Errorf("unexpected header %q", encrypted[:9])
+	}
+	decrypted, err := module.Decrypt(encrypted)
+	if err != nil || !bytes.Equal(in, decrypted) {
+		t.Errorf("unexpected decryption %q, %v", decrypted, err)
+	}
+
+	large := bytes.Repeat([]byte("large file "), 20000)
+	stream, err := module.EncryptStream(bytes.NewReader(large))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decryptedStream, err := module.DecryptStream(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err = io.ReadAll(decryptedStream)
+	if err != nil || !bytes.Equal(large, decrypted) {
+		t.Errorf("unexpected stream decryption of %d bytes, %v", len(decrypted), err)
+	}
+
+	tampered := append([]byte{}, encrypted...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := module.Decrypt(tampered); err == nil {
+		t.Error("expected an error")
+	}
+
+	legacyModule, _ := NewLegacyCryptoModule("enigma", true)
+	aesCbcModule, _ := NewAesCbcCryptoModule("enigma", true)
+	for name, fallback := range map[string]CryptoModule{"legacy": legacyModule, "aesCbc": aesCbcModule} {
+		encrypted, _ := fallback.Encrypt(in)
+		decrypted, err := module.Decrypt(encrypted)
+		if err != nil || !bytes.Equal(in, decrypted) {
+			t.Errorf("%s: unexpected decryption %q, %v", name, decrypted, err)
+		}
+	}
+}
+
+func Test_AesGCM_MessageKeys(t *testing.T) {
+	// RFC 5869 test case 1, the first 32 bytes of its OKM
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	okm := hkdfSha256(bytes.Repeat([]byte{0x0b}, 22), salt, info)
+	if hex.EncodeToString(okm) != "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf" {
+		t.Errorf("unexpected derived key %x", okm)
+	}
+
+	cryptor, _ := NewAesGcmCryptorWithChunkSize("enigma", testGcmChunkSize)
+	first, _ := cryptor.Encrypt([]byte("message"))
+	second, _ := cryptor.Encrypt([]byte("message"))
+	if bytes.Equal(first.Metadata[:gcmSaltLength], second.Metadata[:gcmSaltLength]) || bytes.Equal(first.Data, second.Data) {
+		t.Error("expected a salt and a key per message")
+	}
+}
@@ -23,3 +23,19 @@ type ExtendedCryptor interface {
 	EncryptStream(reader io.Reader) (*EncryptedStreamData, error)
 	DecryptStream(encryptedData *EncryptedStreamData) (io.Reader, error)
 }
+
+// sizedStreamEncryptor is implemented by the cryptors knowing the size of the
+// encrypted stream of an input of a given size.
+type sizedStreamEncryptor interface {
+	encryptStreamWithSize(reader io.Reader, size int64) (*EncryptedStreamData, int64, error)
+}
+
+// encryptStreamWithSize encrypts the stream and returns the size of the
+// encrypted stream, -1 when the cryptor doesn't know it.
+func encryptStreamWithSize(cryptor ExtendedCryptor, reader io.Reader, size int64) (*EncryptedStreamData, int64, error) {
+	if sized, ok := cryptor.(sizedStreamEncryptor); ok && size >= 0 {
+		return sized.encryptStreamWithSize(reader, size)
+	}
+	encryptedStreamData, e := cryptor.EncryptStream(reader)
+	return encryptedStreamData, -1, e
+}
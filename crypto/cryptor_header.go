@@ -4,10 +4,10 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	math "math"
-	"strconv"
 )
 
 const versionPosition = 4
@@ -78,6 +78,9 @@ func peekHeaderCryptorId(data []byte) (cryptorId *string, e error) {
 	if len(data) < len(sentinel) || !bytes.Equal(data[:len(sentinel)], sentinel[:]) {
 		return &legacyId, nil
 	}
+	if len(data) < sizePosition+1 {
+		return nil, errors.New("decryption error: truncated header")
+	}
 
 	if data[versionPosition] != versionV1 {
 		return nil, unsupportedHeaderVersion(int(data[versionPosition]))
@@ -100,22 +103,21 @@ func parseHeader(data []byte) (cryptorId *string, encrData *EncryptedData, e err
 	if data[sizePosition] == longSizeIndicator {
 		position += longSizeLength
 
-		headerSize, err = strconv.ParseInt(string(data[sizePosition:sizePosition+longSizeLength]), 10, 32)
-		if err != nil {
-			return nil, nil, err
+		if int64(len(data)) < position {
+			return nil, nil, errors.New("decryption error: truncated header")
 		}
+		headerSize = int64(binary.BigEndian.Uint16(data[sizePosition+1 : sizePosition+longSizeLength]))
 	} else {
 		position += shortSizeLength
 		headerSize = int64(data[sizePosition])
 	}
 
+	if int64(len(data)) < position+headerSize {
+		return nil, nil, errors.New("decryption error: truncated header")
+	}
 	metadata := data[position : position+headerSize]
 	position += int64(len(metadata))
 
-	if int64(len(data)) < position {
-		return nil, nil, fmt.Errorf("decryption error: %w", e)
-	}
-
 	return id, &EncryptedData{Data: data[position:], Metadata: metadata}, nil
 }
 
@@ -141,11 +143,7 @@ func parseHeaderStream(bufData *bufio.Reader) (cryptorId *string, encrypted *Enc
 	position := int64(sizePosition)
 	if peeked[sizePosition] == longSizeIndicator {
 		position += longSizeLength
-		var e error
-		metadataSize, e = strconv.ParseInt(string(peeked[sizePosition:sizePosition+longSizeLength]), 10, 32)
-		if e != nil {
-			return nil, nil, e
-		}
+		metadataSize = int64(binary.BigEndian.Uint16(peeked[sizePosition+1 : sizePosition+longSizeLength]))
 	} else {
 		position += shortSizeLength
 		metadataSize = int64(peeked[sizePosition])
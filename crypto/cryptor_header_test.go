@@ -1,8 +1,10 @@
 package crypto
 
 import (
+	"bufio"
 	"bytes"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"math"
 	"testing"
 )
@@ -27,3 +29,36 @@ func TestCryptorHeader_WithTooLargeMetadata(t *testing.T) {
 		assert.Fail(t, "expected error")
 	}
 }
+
+func TestCryptorHeader_ParseHeaderWithLargeMetadata(t *testing.T) {
+	metadata := bytes.Repeat([]byte{0x01, 0x02, 0x03}, 100)
+	header, _ := headerV1("abcd", metadata)
+	data := append(header, []byte("encrypted data")...)
+
+	id, encrypted, e := parseHeader(data)
+	assert.Nil(t, e)
+	assert.Equal(t, "abcd", *id)
+	assert.Equal(t, metadata, encrypted.Metadata)
+	assert.Equal(t, []byte("encrypted data"), encrypted.Data)
+
+	id, encryptedStream, e := parseHeaderStream(bufio.NewReader(bytes.NewReader(data)))
+	assert.Nil(t, e)
+	assert.Equal(t, "abcd", *id)
+	assert.Equal(t, metadata, encryptedStream.Metadata)
+	streamData, _ := io.ReadAll(encryptedStream.Reader)
+	assert.Equal(t, []byte("encrypted data"), streamData)
+
+	_, _, e = parseHeader(header[:len(header)-1])
+	assert.NotNil(t, e)
+}
+
+func TestCryptorHeader_ParseTruncatedHeader(t *testing.T) {
+	header, _ := headerV1("abcd", []byte{0x01})
+	module, _ := NewAesGcmCryptoModule("enigma", true)
+	for length := sentinelLength + 1; length <= sizePosition; length++ {
+		_, _, e := parseHeader(header[:length])
+		assert.NotNil(t, e, "length %d", length)
+		_, e = module.Decrypt(header[:length])
+		assert.NotNil(t, e, "length %d", length)
+	}
+}
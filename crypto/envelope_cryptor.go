@@ -0,0 +1,261 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultDataKeyTTL is how long a data-encryption key is cached.
+const DefaultDataKeyTTL = 5 * time.Minute
+
+var envelopeId = "PNEK"
+
+// dataKey is a cached data-encryption key, held by its cryptor.
+type dataKey struct {
+	wrappedKey []byte
+	cryptor    *aesGcmCryptor
+	expires    time.Time
+}
+
+// EnvelopeCryptor encrypts with AES-256-GCM data-encryption keys supplied by a
+// KeyProvider, the key wrapped by the provider is written in the metadata of the
+// header, before the metadata of the AES-GCM cryptor. The key used to encrypt is
+// renewed after the TTL, the unwrapped keys are cached for the TTL, so that the
+// provider isn't called for each message. The keys are zeroed when they expire
+// and on Destroy, see Destroy for what is left in memory. An operation whose key
+// expired before it was used is retried with the key now cached.
+type EnvelopeCryptor struct {
+	mu        sync.Mutex
+	provider  KeyProvider
+	ttl       time.Duration
+	now       func() time.Time
+	current   *dataKey
+	unwrapped map[string]*dataKey
+	destroyed bool
+}
+
+// NewEnvelopeCryptor returns a cryptor with the data-encryption keys of the
+// provider, cached for ttl, DefaultDataKeyTTL when 0.
+func NewEnvelopeCryptor(provider KeyProvider, ttl time.Duration) (*EnvelopeCryptor, error) {
+	if provider == nil {
+		return nil, errors.New("missing key provider")
+	}
+	if ttl <= 0 {
+		ttl = DefaultDataKeyTTL
+	}
+	return &EnvelopeCryptor{
+		provider:  provider,
+		ttl:       ttl,
+		now:       time.Now,
+		unwrapped: make(map[string]*dataKey),
+	}, nil
+}
+
+// NewEnvelopeCryptoModule returns a module encrypting with the data-encryption
+// keys of the provider. The caller owning the module calls its Destroy method
+// zeroing the keys once done with it: PubNub.Destroy doesn't destroy the modules
+// of the Config, which may be shared by several instances.
+func NewEnvelopeCryptoModule(provider KeyProvider, ttl time.Duration) (DestroyableCryptoModule, error) {
+	envelope, e := NewEnvelopeCryptor(provider, ttl)
+	if e != nil {
+		return nil, e
+	}
+	return newModule(envelope, nil), nil
+}
+
+func (c *EnvelopeCryptor) Id() string {
+	return envelopeId
+}
+
+func (c *EnvelopeCryptor) Encrypt(message []byte) (*EncryptedData, error) {
+	for {
+		key, e := c.encryptionKey()
+		if e != nil {
+			return nil, e
+		}
+		encryptedData, e := key.cryptor.Encrypt(message)
+		if e == errGcmKeyZeroed {
+			continue
+		}
+		if e != nil {
+			return nil, e
+		}
+		return &EncryptedData{
+			Metadata: envelopeMetadata(key.wrappedKey, encryptedData.Metadata),
+			Data:     encryptedData.Data,
+		}, nil
+	}
+}
+
+func (c *EnvelopeCryptor) Decrypt(encryptedData *EncryptedData) ([]byte, error) {
+	for {
+		key, metadata, e := c.parseMetadata(encryptedData.Metadata)
+		if e != nil {
+			return nil, e
+		}
+		decrypted, e := key.cryptor.Decrypt(&EncryptedData{Metadata: metadata, Data: encryptedData.Data})
+		if e != errGcmKeyZeroed {
+			return decrypted, e
+		}
+	}
+}
+
+func (c *EnvelopeCryptor) EncryptStream(reader io.Reader) (*EncryptedStreamData, error) {
+	encryptedStreamData, _, e := c.encryptStreamWithSize(reader, -1)
+	return encryptedStreamData, e
+}
+
+func (c *EnvelopeCryptor) encryptStreamWithSize(reader io.Reader, size int64) (*EncryptedStreamData, int64, error) {
+	for {
+		key, e := c.encryptionKey()
+		if e != nil {
+			return nil, -1, e
+		}
+		encryptedStreamData, encryptedSize, e := encryptStreamWithSize(key.cryptor, reader, size)
+		if e == errGcmKeyZeroed {
+			continue
+		}
+		if e != nil {
+			return nil, -1, e
+		}
+		return &EncryptedStreamData{
+			Metadata: envelopeMetadata(key.wrappedKey, encryptedStreamData.Metadata),
+			Reader:   encryptedStreamData.Reader,
+		}, encryptedSize, nil
+	}
+}
+
+func (c *EnvelopeCryptor) DecryptStream(encryptedData *EncryptedStreamData) (io.Reader, error) {
+	for {
+		key, metadata, e := c.parseMetadata(encryptedData.Metadata)
+		if e != nil {
+			return nil, e
+		}
+		reader, e := key.cryptor.DecryptStream(&EncryptedStreamData{Metadata: metadata, Reader: encryptedData.Reader})
+		if e != errGcmKeyZeroed {
+			return reader, e
+		}
+	}
+}
+
+// Destroy zeroes the cached keys and destroys the provider when it has a Destroy
+// method. The cryptor, and the streams it returned which are still read, fail
+// afterwards. The AES key schedules derived from the keys can't be zeroed, they
+// stay in memory until they are garbage collected.
+func (c *EnvelopeCryptor) Destroy() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.destroyed {
+		return
+	}
+	c.destroyed = true
+	if c.current != nil {
+		c.current.cryptor.destroy()
+		c.current = nil
+	}
+	for wrappedKey, key := range c.unwrapped {
+		key.cryptor.destroy()
+		delete(c.unwrapped, wrappedKey)
+	}
+	if provider, ok := c.provider.(interface{ Destroy() }); ok {
+		provider.Destroy()
+	}
+}
+
+// encryptionKey returns the cached key to encrypt with, a new one from the provider once expired.
+func (c *EnvelopeCryptor) encryptionKey() (*dataKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.destroyed {
+		return nil, errors.New("envelope cryptor destroyed")
+	}
+	now := c.now()
+	if c.current != nil && now.Before(c.current.expires) {
+		return c.current, nil
+	}
+
+	key, wrappedKey, e := c.provider.GenerateDataKey()
+	if e != nil {
+		return nil, fmt.Errorf("generate data key: %s", e.Error())
+	}
+	if c.current != nil {
+		c.current.cryptor.zeroKey()
+	}
+	if c.current, e = newDataKey(key, wrappedKey, now.Add(c.ttl)); e != nil {
+		return nil, e
+	}
+	return c.current, nil
+}
+
+// decryptionKey returns the cached key of the wrapped key, unwrapped by the provider when not cached.
+func (c *EnvelopeCryptor) decryptionKey(wrappedKey []byte) (*dataKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.destroyed {
+		return nil, errors.New("envelope cryptor destroyed")
+	}
+	now := c.now()
+	for cached, key := range c.unwrapped {
+		if !now.Before(key.expires) {
+			key.cryptor.zeroKey()
+			delete(c.unwrapped, cached)
+		}
+	}
+	if key, ok := c.unwrapped[string(wrappedKey)]; ok {
+		return key, nil
+	}
+
+	plainKey, e := c.provider.UnwrapDataKey(wrappedKey)
+	if e != nil {
+		return nil, fmt.Errorf("unwrap data key: %s", e.Error())
+	}
+	key, e := newDataKey(plainKey, wrappedKey, now.Add(c.ttl))
+	if e != nil {
+		return nil, e
+	}
+	c.unwrapped[string(wrappedKey)] = key
+	return key, nil
+}
+
+func newDataKey(key, wrappedKey []byte, expires time.Time) (*dataKey, error) {
+	if len(key) != DataKeyLength {
+		zeroBytes(key)
+		return nil, fmt.Errorf("invalid data key length %d", len(key))
+	}
+	cryptor, e := newAesGcmCryptorWithKey(key, DefaultAesGcmChunkSize)
+	if e != nil {
+		return nil, e
+	}
+	return &dataKey{
+		wrappedKey: append([]byte{}, wrappedKey...),
+		cryptor:    cryptor,
+		expires:    expires,
+	}, nil
+}
+
+func envelopeMetadata(wrappedKey []byte, metadata []byte) []byte {
+	r := make([]byte, 2, 2+len(wrappedKey)+len(metadata))
+	binary.BigEndian.PutUint16(r, uint16(len(wrappedKey)))
+	r = append(r, wrappedKey...)
+	return append(r, metadata...)
+}
+
+// parseMetadata returns the key of the data and the metadata of its cryptor.
+func (c *EnvelopeCryptor) parseMetadata(metadata []byte) (*dataKey, []byte, error) {
+	if len(metadata) < 2 {
+		return nil, nil, errors.New("invalid envelope metadata")
+	}
+	size := int(binary.BigEndian.Uint16(metadata))
+	if size == 0 || len(metadata) < 2+size {
+		return nil, nil, errors.New("invalid envelope metadata")
+	}
+	key, e := c.decryptionKey(metadata[2 : 2+size])
+	if e != nil {
+		return nil, nil, e
+	}
+	return key, metadata[2+size:], nil
+}
@@ -0,0 +1,194 @@
+package crypto
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+// countingKeyProvider counts the calls to the provider, its wrapped keys are padded to wrappedSize bytes.
+type countingKeyProvider struct {
+	KeyProvider
+	generated   int
+	unwrapped   int
+	wrappedSize int
+	destroyed   bool
+}
+
+func (p *countingKeyProvider) GenerateDataKey() ([]byte, []byte, error) {
+	p.generated++
+	key, wrapped, err := p.KeyProvider.GenerateDataKey()
+	if p.wrappedSize > len(wrapped) {
+		wrapped = append(wrapped, make([]byte, p.wrappedSize-len(wrapped))...)
+	}
+	return key, wrapped, err
+}
+
+func (p *countingKeyProvider) UnwrapDataKey(wrappedKey []byte) ([]byte, error) {
+	p.unwrapped++
+	if p.wrappedSize > 0 {
+		wrappedKey = bytes.TrimRight(wrappedKey, "\x00")
+	}
+	return p.KeyProvider.UnwrapDataKey(wrappedKey)
+}
+
+func (p *countingKeyProvider) Destroy() {
+	p.destroyed = true
+}
+
+func newCountingKeyProvider(t *testing.T) *countingKeyProvider {
+	local, err := NewLocalKeyProvider(map[string][]byte{"kek": testKey(7)}, "kek")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &countingKeyProvider{KeyProvider: local}
+}
+
+func Test_Envelope_Module(t *testing.T) {
+	provider := newCountingKeyProvider(t)
+	module, err := NewEnvelopeCryptoModule(provider, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := []byte("envelope encrypted message")
+
+	encrypted, err := module.Encrypt(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(encrypted[5:9]) != "PNEK" {
+		t.Errorf("unexpected header %q", encrypted[:9])
+	}
+	decrypted, err := module.Decrypt(encrypted)
+	if err != nil || !bytes.Equal(in, decrypted) {
+		t.Errorf("unexpected decryption %q, %v", decrypted, err)
+	}
+
+	large := bytes.Repeat([]byte("large file "), 20000)
+	stream, err := module.EncryptStream(bytes.NewReader(large))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := module.DecryptStream(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err = io.ReadAll(reader)
+	if err != nil || !bytes.Equal(large, decrypted) {
+		t.Errorf("unexpected stream decryption of %d bytes, %v", len(decrypted), err)
+	}
+
+	if provider.generated != 1 || provider.unwrapped != 1 {
+		t.Errorf("unexpected provider calls: %d generated, %d unwrapped", provider.generated, provider.unwrapped)
+	}
+
+	tampered := append([]byte{}, encrypted...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := module.Decrypt(tampered); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func Test_Envelope_TTL(t *testing.T) {
+	provider := newCountingKeyProvider(t)
+	cryptor, _ := NewEnvelopeCryptor(provider, time.Minute)
+	now := time.Now()
+	cryptor.now = func() time.Time { return now }
+	module := NewCryptoModule(cryptor, nil)
+
+	first, _ := module.Encrypt([]byte("first"))
+	module.Decrypt(first)
+	firstKey := cryptor.current.cryptor.key
+	inFlight, _ := module.EncryptStream(bytes.NewReader([]byte("in flight")))
+
+	now = now.Add(2 * time.Minute)
+	second, _ := module.Encrypt([]byte("second"))
+	if provider.generated != 2 {
+		t.Errorf("expected a new data key, %d generated", provider.generated)
+	}
+	if !bytes.Equal(make([]byte, DataKeyLength), firstKey) {
+		t.Errorf("expired key not zeroed %x", firstKey)
+	}
+	if bytes.Equal(first[:60], second[:60]) {
+		t.Error("expected another wrapped key in the header")
+	}
+	// the stream started before the key expired goes on with it
+	streamed, err := io.ReadAll(inFlight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := module.Decrypt(streamed)
+	if err != nil || string(decrypted) != "in flight" {
+		t.Errorf("unexpected stream decryption %q, %v", decrypted, err)
+	}
+
+	decrypted, err = module.Decrypt(first)
+	if err != nil || string(decrypted) != "first" {
+		t.Errorf("unexpected decryption %q, %v", decrypted, err)
+	}
+	if provider.unwrapped != 2 {
+		t.Errorf("expected the expired key to be unwrapped again, %d unwrapped", provider.unwrapped)
+	}
+}
+
+func Test_Envelope_LargeWrappedKey(t *testing.T) {
+	provider := newCountingKeyProvider(t)
+	provider.wrappedSize = 300
+	module, _ := NewEnvelopeCryptoModule(provider, time.Minute)
+	in := []byte("message with a large header")
+
+	encrypted, err := module.Encrypt(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encrypted[sizePosition] != longSizeIndicator {
+		t.Errorf("expected a long metadata size, got %d", encrypted[sizePosition])
+	}
+	decrypted, err := module.Decrypt(encrypted)
+	if err != nil || !bytes.Equal(in, decrypted) {
+		t.Errorf("unexpected decryption %q, %v", decrypted, err)
+	}
+
+	reader, err := module.DecryptStream(bytes.NewReader(encrypted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err = io.ReadAll(reader)
+	if err != nil || !bytes.Equal(in, decrypted) {
+		t.Errorf("unexpected stream decryption %q, %v", decrypted, err)
+	}
+}
+
+func Test_Envelope_Destroy(t *testing.T) {
+	provider := newCountingKeyProvider(t)
+	cryptoModule, _ := NewEnvelopeCryptoModule(provider, time.Minute)
+	cryptor := cryptoModule.(*module).encryptor.(*EnvelopeCryptor)
+
+	encrypted, _ := cryptoModule.Encrypt([]byte("message"))
+	cryptoModule.Decrypt(encrypted)
+	keys := [][]byte{cryptor.current.cryptor.key}
+	for _, key := range cryptor.unwrapped {
+		keys = append(keys, key.cryptor.key)
+	}
+	inFlight, _ := cryptoModule.EncryptStream(bytes.NewReader([]byte("in flight")))
+
+	cryptoModule.Destroy()
+	for _, key := range keys {
+		if !bytes.Equal(make([]byte, DataKeyLength), key) {
+			t.Errorf("key not zeroed %x", key)
+		}
+	}
+	if !provider.destroyed {
+		t.Error("expected the provider to be destroyed")
+	}
+	if _, err := cryptoModule.Encrypt([]byte("message")); err == nil {
+		t.Error("expected an error after Destroy")
+	}
+	if _, err := cryptoModule.Decrypt(encrypted); err == nil {
+		t.Error("expected an error after Destroy")
+	}
+	if _, err := io.ReadAll(inFlight); err == nil {
+		t.Error("expected the stream in flight to fail after Destroy")
+	}
+}
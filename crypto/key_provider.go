@@ -0,0 +1,170 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+// DataKeyLength is the length of the data-encryption keys, AES-256 keys.
+const DataKeyLength = 32
+
+// KeyProvider supplies the data-encryption keys of the envelope cryptor, wrapped
+// by key-encryption keys it keeps. KMS and HSM adapters implement it.
+type KeyProvider interface {
+	// GenerateDataKey returns a new DataKeyLength bytes data-encryption key and the
+	// key wrapped, the wrapped key is written in the header of the encrypted data.
+	GenerateDataKey() (key []byte, wrappedKey []byte, err error)
+	// UnwrapDataKey returns the data-encryption key of a wrapped key returned by GenerateDataKey.
+	UnwrapDataKey(wrappedKey []byte) ([]byte, error)
+}
+
+// LocalKeyProvider is a KeyProvider wrapping the data-encryption keys with
+// AES-256-GCM key-encryption keys held in memory, loaded from a keystore file or
+// passed by the application. The wrapped keys hold the ID of their key-encryption key.
+type LocalKeyProvider struct {
+	mu       sync.RWMutex
+	keys     map[string][]byte
+	activeId string
+}
+
+// localKeystore is the JSON keystore file: the ID of the active key-encryption
+// key and the key-encryption keys by ID, encoded in base64.
+type localKeystore struct {
+	ActiveKeyId string            `json:"active_key_id"`
+	Keys        map[string]string `json:"keys"`
+}
+
+// NewLocalKeyProvider returns a provider wrapping with the active key, the keys
+// are DataKeyLength bytes long and copied.
+func NewLocalKeyProvider(keys map[string][]byte, activeKeyId string) (*LocalKeyProvider, error) {
+	p := &LocalKeyProvider{keys: make(map[string][]byte, len(keys)), activeId: activeKeyId}
+	for id, key := range keys {
+		if len(id) == 0 || len(id) > MaxKeyIdLength {
+			p.Destroy()
+			return nil, fmt.Errorf("invalid key id length %d", len(id))
+		}
+		if len(key) != DataKeyLength {
+			p.Destroy()
+			return nil, fmt.Errorf("key %s: invalid key length %d", id, len(key))
+		}
+		p.keys[id] = append([]byte{}, key...)
+	}
+	if p.keys[activeKeyId] == nil {
+		p.Destroy()
+		return nil, fmt.Errorf("unknown key id %s", activeKeyId)
+	}
+	return p, nil
+}
+
+// LoadLocalKeyProvider returns a provider with the keys of a JSON keystore file:
+//
+//	{"active_key_id": "2024", "keys": {"2023": "<base64 key>", "2024": "<base64 key>"}}
+func LoadLocalKeyProvider(path string) (*LocalKeyProvider, error) {
+	content, e := ioutil.ReadFile(path)
+	if e != nil {
+		return nil, e
+	}
+	defer zeroBytes(content)
+
+	var keystore localKeystore
+	if e := json.Unmarshal(content, &keystore); e != nil {
+		return nil, fmt.Errorf("invalid keystore %s: %s", path, e.Error())
+	}
+	keys := make(map[string][]byte, len(keystore.Keys))
+	defer func() {
+		for _, key := range keys {
+			zeroBytes(key)
+		}
+	}()
+	for id, encoded := range keystore.Keys {
+		key, e := base64.StdEncoding.DecodeString(encoded)
+		if e != nil {
+			return nil, fmt.Errorf("invalid keystore %s: key %s: %s", path, id, e.Error())
+		}
+		keys[id] = key
+	}
+
+	return NewLocalKeyProvider(keys, keystore.ActiveKeyId)
+}
+
+func (p *LocalKeyProvider) GenerateDataKey() ([]byte, []byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.keys == nil {
+		return nil, nil, errors.New("key provider destroyed")
+	}
+
+	aead, e := localKeyAead(p.keys[p.activeId])
+	if e != nil {
+		return nil, nil, e
+	}
+	key := generateIV(DataKeyLength)
+	nonce := generateIV(aead.NonceSize())
+	wrapped := keyRingMetadata(p.activeId, nonce)
+
+	return key, aead.Seal(wrapped, nonce, key, []byte(p.activeId)), nil
+}
+
+func (p *LocalKeyProvider) UnwrapDataKey(wrappedKey []byte) ([]byte, error) {
+	if len(wrappedKey) == 0 {
+		return nil, errors.New("invalid wrapped key")
+	}
+	n := int(wrappedKey[0])
+	if n == 0 || n > MaxKeyIdLength || len(wrappedKey) < 1+n {
+		return nil, errors.New("invalid wrapped key")
+	}
+	id := string(wrappedKey[1 : 1+n])
+	wrappedKey = wrappedKey[1+len(id):]
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.keys == nil {
+		return nil, errors.New("key provider destroyed")
+	}
+	kek := p.keys[id]
+	if kek == nil {
+		return nil, fmt.Errorf("unknown key id %s", id)
+	}
+	aead, e := localKeyAead(kek)
+	if e != nil {
+		return nil, e
+	}
+	if len(wrappedKey) < aead.NonceSize() {
+		return nil, errors.New("invalid wrapped key")
+	}
+	key, e := aead.Open(nil, wrappedKey[:aead.NonceSize()], wrappedKey[aead.NonceSize():], []byte(id))
+	if e != nil {
+		return nil, fmt.Errorf("unwrap key: %s", e.Error())
+	}
+	return key, nil
+}
+
+// Destroy zeroes the key-encryption keys, the provider can't be used anymore.
+func (p *LocalKeyProvider) Destroy() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, key := range p.keys {
+		zeroBytes(key)
+	}
+	p.keys = nil
+}
+
+func localKeyAead(key []byte) (cipher.AEAD, error) {
+	block, e := aes.NewCipher(key)
+	if e != nil {
+		return nil, e
+	}
+	return cipher.NewGCM(block)
+}
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
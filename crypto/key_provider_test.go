@@ -0,0 +1,102 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, DataKeyLength)
+}
+
+func Test_LocalKeyProvider_WrapUnwrap(t *testing.T) {
+	provider, err := NewLocalKeyProvider(map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, wrapped, err := provider.GenerateDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != DataKeyLength || bytes.Contains(wrapped, key) || !bytes.HasPrefix(wrapped, []byte("\x02k2")) {
+		t.Errorf("unexpected key %x wrapped as %x", key, wrapped)
+	}
+	unwrapped, err := provider.UnwrapDataKey(wrapped)
+	if err != nil || !bytes.Equal(key, unwrapped) {
+		t.Errorf("unexpected unwrapped key %x, %v", unwrapped, err)
+	}
+
+	tampered := append([]byte{}, wrapped...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := provider.UnwrapDataKey(tampered); err == nil {
+		t.Error("expected an error for a tampered key")
+	}
+	// the key ID is authenticated
+	renamed := append([]byte("\x02k1"), wrapped[3:]...)
+	if _, err := provider.UnwrapDataKey(renamed); err == nil {
+		t.Error("expected an error for a renamed key")
+	}
+	if _, err := provider.UnwrapDataKey([]byte("\x02k3nonce")); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+	if _, err := provider.UnwrapDataKey(append([]byte{255}, make([]byte, 300)...)); err == nil {
+		t.Error("expected an error for a long key id")
+	}
+
+	kek := provider.keys["k1"]
+	provider.Destroy()
+	if !bytes.Equal(make([]byte, DataKeyLength), kek) {
+		t.Errorf("key not zeroed %x", kek)
+	}
+	if _, _, err := provider.GenerateDataKey(); err == nil {
+		t.Error("expected an error after Destroy")
+	}
+	if _, err := provider.UnwrapDataKey(wrapped); err == nil {
+		t.Error("expected an error after Destroy")
+	}
+}
+
+func Test_LocalKeyProvider_InvalidKeys(t *testing.T) {
+	if _, err := NewLocalKeyProvider(map[string][]byte{"k1": testKey(1)}, "k2"); err == nil {
+		t.Error("expected an error for an unknown active key")
+	}
+	if _, err := NewLocalKeyProvider(map[string][]byte{"k1": testKey(1)[1:]}, "k1"); err == nil {
+		t.Error("expected an error for a short key")
+	}
+}
+
+func Test_LocalKeyProvider_Load(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "keystore.json")
+	keystore := `{"active_key_id": "2024", "keys": {"2023": "` + base64.StdEncoding.EncodeToString(testKey(1)) + `", "2024": "` + base64.StdEncoding.EncodeToString(testKey(2)) + `"}}`
+	if err := ioutil.WriteFile(path, []byte(keystore), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := LoadLocalKeyProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.activeId != "2024" || !bytes.Equal(testKey(1), provider.keys["2023"]) || !bytes.Equal(testKey(2), provider.keys["2024"]) {
+		t.Errorf("unexpected keys %v, active %s", provider.keys, provider.activeId)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"active_key_id": "2024", "keys": {"2024": "not base64"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadLocalKeyProvider(path); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+	if _, err := LoadLocalKeyProvider(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected an error for a missing keystore")
+	}
+}
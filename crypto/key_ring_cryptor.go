@@ -0,0 +1,320 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// MaxKeyIdLength is the max length of a key ID, which is written in the header of each encrypted data.
+const MaxKeyIdLength = 32
+
+var keyRingId = "PNKR"
+
+type keyRingKey struct {
+	id        string
+	cipherKey string
+	cryptor   ExtendedCryptor
+}
+
+// KeyRingCryptor encrypts with the active key of a set of keys, the ID of the
+// key is written in the metadata of the header, before the metadata of the
+// cryptor of the key. Keys can be added, activated and retired at runtime, the
+// data encrypted with a retired key can't be decrypted anymore.
+type KeyRingCryptor struct {
+	mu         sync.RWMutex
+	newCryptor func(cipherKey string) (ExtendedCryptor, error)
+	// keys from the oldest to the newest
+	keys   []*keyRingKey
+	active *keyRingKey
+	legacy *keyRingKey
+}
+
+// NewKeyRingCryptor returns a key ring encrypting with AES-256-GCM, the key is the active key and the legacy key.
+func NewKeyRingCryptor(keyId string, cipherKey string) (*KeyRingCryptor, error) {
+	return NewKeyRingCryptorWithCryptor(NewAesGcmCryptor, keyId, cipherKey)
+}
+
+// NewKeyRingCryptorWithCryptor returns a key ring encrypting with the cryptors
+// returned by newCryptor for its keys, the key is the active key and the legacy key.
+func NewKeyRingCryptorWithCryptor(newCryptor func(cipherKey string) (ExtendedCryptor, error), keyId string, cipherKey string) (*KeyRingCryptor, error) {
+	ring := &KeyRingCryptor{newCryptor: newCryptor}
+	if e := ring.AddKey(keyId, cipherKey); e != nil {
+		return nil, e
+	}
+	if e := ring.SetActiveKey(keyId); e != nil {
+		return nil, e
+	}
+	if e := ring.SetLegacyKey(keyId); e != nil {
+		return nil, e
+	}
+	return ring, nil
+}
+
+// AddKey adds a key to decrypt with, which can then be activated to encrypt with.
+func (r *KeyRingCryptor) AddKey(keyId string, cipherKey string) error {
+	if len(keyId) == 0 || len(keyId) > MaxKeyIdLength {
+		return fmt.Errorf("invalid key id length %d", len(keyId))
+	}
+	cryptor, e := r.newCryptor(cipherKey)
+	if e != nil {
+		return e
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.key(keyId) != nil {
+		return fmt.Errorf("key id %s already exists", keyId)
+	}
+	r.keys = append(r.keys, &keyRingKey{id: keyId, cipherKey: cipherKey, cryptor: cryptor})
+	return nil
+}
+
+// SetActiveKey sets the key the data is encrypted with.
+func (r *KeyRingCryptor) SetActiveKey(keyId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := r.key(keyId)
+	if key == nil {
+		return fmt.Errorf("unknown key id %s", keyId)
+	}
+	r.active = key
+	return nil
+}
+
+// SetLegacyKey sets the key the legacy and the AES-CBC data encrypted without key
+// ID is decrypted with, the first key of the ring by default, an empty ID unsets
+// it. A wrong key is detected by the padding only for such data, which may
+// accept a wrong key, so no other key is tried, none without legacy key.
+func (r *KeyRingCryptor) SetLegacyKey(keyId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if keyId == "" {
+		r.legacy = nil
+		return nil
+	}
+	key := r.key(keyId)
+	if key == nil {
+		return fmt.Errorf("unknown key id %s", keyId)
+	}
+	r.legacy = key
+	return nil
+}
+
+// RetireKey removes a key, the active key can't be retired. The legacy key is
+// unset when it is retired.
+func (r *KeyRingCryptor) RetireKey(keyId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active != nil && r.active.id == keyId {
+		return fmt.Errorf("can't retire the active key %s", keyId)
+	}
+	for i, key := range r.keys {
+		if key.id == keyId {
+			r.keys = append(r.keys[:i:i], r.keys[i+1:]...)
+			if r.legacy == key {
+				r.legacy = nil
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown key id %s", keyId)
+}
+
+// ActiveKeyId returns the ID of the key the data is encrypted with.
+func (r *KeyRingCryptor) ActiveKeyId() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.active.id
+}
+
+// KeyIds returns the IDs of the keys, from the oldest to the newest.
+func (r *KeyRingCryptor) KeyIds() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]string, 0, len(r.keys))
+	for _, key := range r.keys {
+		ids = append(ids, key.id)
+	}
+	return ids
+}
+
+func (r *KeyRingCryptor) key(keyId string) *keyRingKey {
+	for _, key := range r.keys {
+		if key.id == keyId {
+			return key
+		}
+	}
+	return nil
+}
+
+// keysByPreference returns the keys to try on data without key ID, the active one
+// first then from the newest to the oldest.
+func (r *KeyRingCryptor) keysByPreference() []*keyRingKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := make([]*keyRingKey, 0, len(r.keys))
+	keys = append(keys, r.active)
+	for i := len(r.keys) - 1; i >= 0; i-- {
+		if r.keys[i] != r.active {
+			keys = append(keys, r.keys[i])
+		}
+	}
+	return keys
+}
+
+func (r *KeyRingCryptor) legacyKey() *keyRingKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.legacy
+}
+
+func (r *KeyRingCryptor) Id() string {
+	return keyRingId
+}
+
+func (r *KeyRingCryptor) Encrypt(message []byte) (*EncryptedData, error) {
+	r.mu.RLock()
+	key := r.active
+	r.mu.RUnlock()
+
+	encryptedData, e := key.cryptor.Encrypt(message)
+	if e != nil {
+		return nil, e
+	}
+	return &EncryptedData{
+		Metadata: keyRingMetadata(key.id, encryptedData.Metadata),
+		Data:     encryptedData.Data,
+	}, nil
+}
+
+func (r *KeyRingCryptor) Decrypt(encryptedData *EncryptedData) ([]byte, error) {
+	key, metadata, e := r.parseMetadata(encryptedData.Metadata)
+	if e != nil {
+		return nil, e
+	}
+	return key.cryptor.Decrypt(&EncryptedData{Metadata: metadata, Data: encryptedData.Data})
+}
+
+func (r *KeyRingCryptor) EncryptStream(reader io.Reader) (*EncryptedStreamData, error) {
+	encryptedStreamData, _, e := r.encryptStreamWithSize(reader, -1)
+	return encryptedStreamData, e
+}
+
+func (r *KeyRingCryptor) encryptStreamWithSize(reader io.Reader, size int64) (*EncryptedStreamData, int64, error) {
+	r.mu.RLock()
+	key := r.active
+	r.mu.RUnlock()
+
+	encryptedStreamData, encryptedSize, e := encryptStreamWithSize(key.cryptor, reader, size)
+	if e != nil {
+		return nil, -1, e
+	}
+	return &EncryptedStreamData{
+		Metadata: keyRingMetadata(key.id, encryptedStreamData.Metadata),
+		Reader:   encryptedStreamData.Reader,
+	}, encryptedSize, nil
+}
+
+func (r *KeyRingCryptor) DecryptStream(encryptedData *EncryptedStreamData) (io.Reader, error) {
+	key, metadata, e := r.parseMetadata(encryptedData.Metadata)
+	if e != nil {
+		return nil, e
+	}
+	return key.cryptor.DecryptStream(&EncryptedStreamData{Metadata: metadata, Reader: encryptedData.Reader})
+}
+
+func keyRingMetadata(keyId string, metadata []byte) []byte {
+	r := make([]byte, 0, 1+len(keyId)+len(metadata))
+	r = append(r, byte(len(keyId)))
+	r = append(r, keyId...)
+	return append(r, metadata...)
+}
+
+// parseMetadata returns the key of the data and the metadata of its cryptor.
+func (r *KeyRingCryptor) parseMetadata(metadata []byte) (*keyRingKey, []byte, error) {
+	if len(metadata) == 0 {
+		return nil, nil, errors.New("invalid key ring metadata")
+	}
+	n := int(metadata[0])
+	if n == 0 || n > MaxKeyIdLength || len(metadata) < 1+n {
+		return nil, nil, errors.New("invalid key ring metadata")
+	}
+	keyId := string(metadata[1 : 1+n])
+
+	r.mu.RLock()
+	key := r.key(keyId)
+	r.mu.RUnlock()
+	if key == nil {
+		return nil, nil, fmt.Errorf("unknown key id %s", keyId)
+	}
+
+	cryptorMetadata := metadata[1+len(keyId):]
+	if len(cryptorMetadata) == 0 {
+		cryptorMetadata = nil
+	}
+	return key, cryptorMetadata, nil
+}
+
+// keyRingFallbackCryptor decrypts the data encrypted without key ID by a cryptor
+// of the cryptor ID, trying each key of the ring, or only the legacy key when
+// the cryptor can't detect a wrong key.
+type keyRingFallbackCryptor struct {
+	id         string
+	ring       *KeyRingCryptor
+	newCryptor func(cipherKey string) (ExtendedCryptor, error)
+	legacyOnly bool
+}
+
+func (c *keyRingFallbackCryptor) Id() string {
+	return c.id
+}
+
+func (c *keyRingFallbackCryptor) Encrypt(message []byte) (*EncryptedData, error) {
+	return nil, errors.New("key ring fallback cryptors only decrypt")
+}
+
+func (c *keyRingFallbackCryptor) Decrypt(encryptedData *EncryptedData) ([]byte, error) {
+	keys := c.ring.keysByPreference()
+	if c.legacyOnly {
+		legacy := c.ring.legacyKey()
+		if legacy == nil {
+			return nil, errors.New("no legacy key in the key ring")
+		}
+		keys = []*keyRingKey{legacy}
+	}
+
+	var lastErr error
+	for _, key := range keys {
+		cryptor, e := c.newCryptor(key.cipherKey)
+		if e != nil {
+			lastErr = e
+			continue
+		}
+		decrypted, e := cryptor.Decrypt(encryptedData)
+		if e == nil {
+			return decrypted, nil
+		}
+		lastErr = e
+	}
+	return nil, fmt.Errorf("no key of the key ring decrypts the data: %v", lastErr)
+}
+
+// NewKeyRingCryptoModule returns a module encrypting with the key ring. The data
+// encrypted without key ID by the AES-GCM cryptor is decrypted by trying each key,
+// the active one first. Unlike it, the data encrypted by the legacy and the
+// AES-CBC cryptors is decrypted only with the legacy key of the ring, the first
+// key unless changed with SetLegacyKey: a wrong key can't be detected for such
+// data, trying each key could return garbage instead of an error.
+func NewKeyRingCryptoModule(ring *KeyRingCryptor, randomIv bool) CryptoModule {
+	newLegacyCryptor := func(cipherKey string) (ExtendedCryptor, error) {
+		return NewLegacyCryptor(cipherKey, randomIv)
+	}
+
+	return NewCryptoModule(ring, []Cryptor{
+		&keyRingFallbackCryptor{id: legacyId, ring: ring, newCryptor: newLegacyCryptor, legacyOnly: true},
+		&keyRingFallbackCryptor{id: crivId, ring: ring, newCryptor: NewAesCbcCryptor, legacyOnly: true},
+		&keyRingFallbackCryptor{id: agcmId, ring: ring, newCryptor: NewAesGcmCryptor},
+	})
+}
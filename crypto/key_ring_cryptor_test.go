@@ -0,0 +1,174 @@
+package crypto
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func Test_KeyRing_Rotation(t *testing.T) {
+	ring, err := NewKeyRingCryptor("2023", "old key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	module := NewKeyRingCryptoModule(ring, true)
+	in := []byte("rotated message")
+
+	oldData, _ := module.Encrypt(in)
+	if string(oldData[5:9]) != "PNKR" || !bytes.Contains(oldData[:20], []byte("2023")) {
+		t.Errorf("unexpected header %q", oldData[:20])
+	}
+
+	if err := ring.AddKey("2024", "new key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.SetActiveKey("2024"); err != nil {
+		t.Fatal(err)
+	}
+	newData, _ := module.Encrypt(in)
+	if !bytes.Contains(newData[:20], []byte("2024")) {
+		t.Errorf("unexpected header %q", newData[:20])
+	}
+	if ring.ActiveKeyId() != "2024" || strings.Join(ring.KeyIds(), ",") != "2023,2024" {
+		t.Errorf("unexpected keys %v, active %s", ring.KeyIds(), ring.ActiveKeyId())
+	}
+
+	for name, data := range map[string][]byte{"old": oldData, "new": newData} {
+		decrypted, err := module.Decrypt(data)
+		if err != nil || !bytes.Equal(in, decrypted) {
+			t.Errorf("%s: unexpected decryption %q, %v", name, decrypted, err)
+		}
+	}
+
+	if err := ring.RetireKey("2024"); err == nil {
+		t.Error("expected an error retiring the active key")
+	}
+	if err := ring.RetireKey("2023"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := module.Decrypt(oldData); err == nil || !strings.Contains(err.Error(), "unknown key id 2023") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if decrypted, err := module.Decrypt(newData); err != nil || !bytes.Equal(in, decrypted) {
+		t.Errorf("unexpected decryption %q, %v", decrypted, err)
+	}
+}
+
+func Test_KeyRing_Stream(t *testing.T) {
+	ring, _ := NewKeyRingCryptorWithCryptor(NewAesCbcCryptor, "k1", "enigma")
+	module := NewKeyRingCryptoModule(ring, true)
+	in := bytes.Repeat([]byte("streamed "), 10000)
+
+	encrypted, err := module.EncryptStream(bytes.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedBytes, _ := io.ReadAll(encrypted)
+
+	ring.AddKey("k2", "other")
+	ring.SetActiveKey("k2")
+
+	decrypted, err := module.Decrypt(encryptedBytes)
+	if err != nil || !bytes.Equal(in, decrypted) {
+		t.Errorf("unexpected decryption of %d bytes, %v", len(decrypted), err)
+	}
+	reader, err := module.DecryptStream(bytes.NewReader(encryptedBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err = io.ReadAll(reader)
+	if err != nil || !bytes.Equal(in, decrypted) {
+		t.Errorf("unexpected stream decryption of %d bytes, %v", len(decrypted), err)
+	}
+}
+
+func Test_KeyRing_FallbackWithoutKeyId(t *testing.T) {
+	ring, _ := NewKeyRingCryptor("new", "new key")
+	ring.AddKey("old", "old key")
+	module := NewKeyRingCryptoModule(ring, true)
+	in := []byte("message encrypted before the key ring")
+
+	// the first key is the legacy key by default
+	firstModule, _ := NewLegacyCryptoModule("new key", true)
+	encrypted, _ := firstModule.Encrypt(in)
+	if decrypted, err := module.Decrypt(encrypted); err != nil || !bytes.Equal(in, decrypted) {
+		t.Errorf("unexpected decryption with the first key %q, %v", decrypted, err)
+	}
+
+	legacyModule, _ := NewLegacyCryptoModule("old key", true)
+	aesCbcModule, _ := NewAesCbcCryptoModule("old key", true)
+	aesGcmModule, _ := NewAesGcmCryptoModule("old key", true)
+	cases := []struct {
+		name string
+		m    CryptoModule
+		// needsLegacyKey when the data is decrypted only with the legacy key, not the old one yet
+		needsLegacyKey bool
+	}{
+		{"legacy", legacyModule, true},
+		{"aesCbc", aesCbcModule, true},
+		{"aesGcm", aesGcmModule, false},
+	}
+	for _, c := range cases {
+		encrypted, _ := c.m.Encrypt(in)
+		decrypted, err := module.Decrypt(encrypted)
+		if c.needsLegacyKey && err == nil {
+			t.Errorf("%s: expected an error with another legacy key, decrypted %q", c.name, decrypted)
+		}
+		if !c.needsLegacyKey && (err != nil || !bytes.Equal(in, decrypted)) {
+			t.Errorf("%s: unexpected decryption %q, %v", c.name, decrypted, err)
+		}
+	}
+
+	if err := ring.SetLegacyKey("old"); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		encrypted, _ := c.m.Encrypt(in)
+		decrypted, err := module.Decrypt(encrypted)
+		if err != nil || !bytes.Equal(in, decrypted) {
+			t.Errorf("%s: unexpected decryption %q, %v", c.name, decrypted, err)
+		}
+	}
+
+	unknownModule, _ := NewAesGcmCryptoModule("unknown key", true)
+	encrypted, _ = unknownModule.Encrypt(in)
+	if _, err := module.Decrypt(encrypted); err == nil {
+		t.Error("expected an error")
+	}
+
+	ring.RetireKey("old")
+	encrypted, _ = legacyModule.Encrypt(in)
+	if _, err := module.Decrypt(encrypted); err == nil {
+		t.Error("expected an error once the legacy key is retired")
+	}
+}
+
+func Test_KeyRing_InvalidKeys(t *testing.T) {
+	if _, err := NewKeyRingCryptor("", "key"); err == nil {
+		t.Error("expected an error for an empty key id")
+	}
+	if _, err := NewKeyRingCryptor(strings.Repeat("k", MaxKeyIdLength+1), "key"); err == nil {
+		t.Error("expected an error for a long key id")
+	}
+
+	ring, _ := NewKeyRingCryptor("k1", "key")
+	if err := ring.AddKey("k1", "other"); err == nil {
+		t.Error("expected an error for a duplicate key id")
+	}
+	if err := ring.SetActiveKey("k2"); err == nil {
+		t.Error("expected an error for an unknown key id")
+	}
+	if err := ring.RetireKey("k2"); err == nil {
+		t.Error("expected an error for an unknown key id")
+	}
+	if _, err := ring.Decrypt(&EncryptedData{Metadata: []byte{5, 'k'}, Data: []byte("data")}); err == nil {
+		t.Error("expected an error for invalid metadata")
+	}
+	if _, err := ring.Decrypt(&EncryptedData{Metadata: append([]byte{255}, make([]byte, 300)...), Data: []byte("data")}); err == nil {
+		t.Error("expected an error for a long key id")
+	}
+	if err := ring.SetLegacyKey("k2"); err == nil {
+		t.Error("expected an error for an unknown key id")
+	}
+}
@@ -103,6 +103,12 @@ func (c *legacyCryptor) EncryptStream(reader io.Reader) (*EncryptedStreamData, e
 	}, nil
 }
 
+func (c *legacyCryptor) encryptStreamWithSize(reader io.Reader, size int64) (*EncryptedStreamData, int64, error) {
+	encryptedStreamData, e := c.EncryptStream(reader)
+	// the stream starts with the IV
+	return encryptedStreamData, aes.BlockSize + paddedSize(size), e
+}
+
 func (c *legacyCryptor) DecryptStream(encryptedData *EncryptedStreamData) (io.Reader, error) {
 	iv := make([]byte, aes.BlockSize)
 	_, err := io.ReadFull(encryptedData.Reader, iv)
@@ -16,6 +16,13 @@ type CryptoModule interface {
 	DecryptStream(input io.Reader) (io.Reader, error)
 }
 
+// DestroyableCryptoModule is a CryptoModule keeping keys which Destroy zeroes,
+// the module can't be used anymore once destroyed.
+type DestroyableCryptoModule interface {
+	CryptoModule
+	Destroy()
+}
+
 type module struct {
 	encryptor  ExtendedCryptor
 	decryptors map[string]ExtendedCryptor
@@ -49,8 +56,38 @@ func NewAesCbcCryptoModule(cipherKey string, randomIv bool) (CryptoModule, error
 	return NewCryptoModule(aesCbc, []Cryptor{legacy}), nil
 }
 
+// NewAesGcmCryptoModule returns a module encrypting with AES-256-GCM, which
+// decrypts the data encrypted with the legacy and the AES-CBC cryptors too.
+func NewAesGcmCryptoModule(cipherKey string, randomIv bool) (CryptoModule, error) {
+	return NewAesGcmCryptoModuleWithChunkSize(cipherKey, randomIv, DefaultAesGcmChunkSize)
+}
+
+// NewAesGcmCryptoModuleWithChunkSize returns a module encrypting with AES-256-GCM
+// by chunks of chunkSize bytes, with the legacy and the AES-CBC cryptors as decryptors.
+func NewAesGcmCryptoModuleWithChunkSize(cipherKey string, randomIv bool, chunkSize int) (CryptoModule, error) {
+	aesGcm, e := NewAesGcmCryptorWithChunkSize(cipherKey, chunkSize)
+	if e != nil {
+		return nil, e
+	}
+
+	legacy, e := NewLegacyCryptor(cipherKey, randomIv)
+	if e != nil {
+		return nil, e
+	}
+
+	aesCbc, e := NewAesCbcCryptor(cipherKey)
+	if e != nil {
+		return nil, e
+	}
+
+	return NewCryptoModule(aesGcm, []Cryptor{legacy, aesCbc}), nil
+}
+
 func NewCryptoModule(defaultCryptor Cryptor, decryptors []Cryptor) CryptoModule {
+	return newModule(defaultCryptor, decryptors)
+}
 
+func newModule(defaultCryptor Cryptor, decryptors []Cryptor) *module {
 	decryptorsMap := make(map[string]ExtendedCryptor, len(decryptors)+1)
 	for _, d := range decryptors {
 		decryptorsMap[d.Id()] = liftToExtendedCryptor(d)
@@ -65,6 +102,16 @@ func NewCryptoModule(defaultCryptor Cryptor, decryptors []Cryptor) CryptoModule
 	}
 }
 
+// Destroy destroys the cryptors having a Destroy method, zeroing the keys they
+// keep. The encryptor is one of the decryptors.
+func (m *module) Destroy() {
+	for _, cryptor := range m.decryptors {
+		if d, ok := cryptor.(interface{ Destroy() }); ok {
+			d.Destroy()
+		}
+	}
+}
+
 func (m *module) Encrypt(message []byte) ([]byte, error) {
 	if len(message) == 0 {
 		return nil, errors.New("encryption error: can't encrypt empty data")
@@ -115,27 +162,47 @@ func (m *module) Decrypt(data []byte) ([]byte, error) {
 }
 
 func (m *module) EncryptStream(input io.Reader) (io.Reader, error) {
+	encrypted, _, e := m.encryptStreamWithSize(input, -1)
+	return encrypted, e
+}
+
+// EncryptStreamWithSize encrypts the input of size bytes like EncryptStream and
+// returns the size of the encrypted stream, without reading it. The size is -1
+// when it isn't known in advance: for a negative input size, for the modules not
+// returned by the constructors of this package and for the custom cryptors.
+func EncryptStreamWithSize(cryptoModule CryptoModule, input io.Reader, size int64) (io.Reader, int64, error) {
+	if m, ok := cryptoModule.(*module); ok {
+		return m.encryptStreamWithSize(input, size)
+	}
+	encrypted, e := cryptoModule.EncryptStream(input)
+	return encrypted, -1, e
+}
+
+func (m *module) encryptStreamWithSize(input io.Reader, size int64) (io.Reader, int64, error) {
 	bufferedReader := bufio.NewReader(input)
 	peekedBytes, e := bufferedReader.Peek(1)
 	if len(peekedBytes) == 0 {
-		return nil, errors.New("encryption error: can't encrypt empty data")
+		return nil, -1, errors.New("encryption error: can't encrypt empty data")
 	}
 
-	encryptedStreamData, e := m.encryptor.EncryptStream(bufferedReader)
+	encryptedStreamData, encryptedSize, e := encryptStreamWithSize(m.encryptor, bufferedReader, size)
 	if e != nil {
-		return nil, e
+		return nil, -1, e
 	}
 	if m.encryptor.Id() == legacyId {
-		return encryptedStreamData.Reader, nil
+		return encryptedStreamData.Reader, encryptedSize, nil
 	}
 	header, e := headerV1(m.encryptor.Id(), encryptedStreamData.Metadata)
 	if e != nil {
-		return nil, e
+		return nil, -1, e
+	}
+	if encryptedSize >= 0 {
+		encryptedSize += int64(len(header))
 	}
 
 	headerReader := bytes.NewReader(header)
 
-	return io.MultiReader(headerReader, encryptedStreamData.Reader), nil
+	return io.MultiReader(headerReader, encryptedStreamData.Reader), encryptedSize, nil
 }
 
 func (m *module) DecryptStream(input io.Reader) (io.Reader, error) {
@@ -1,7 +1,10 @@
 package crypto
 
 import (
+	"bytes"
 	"encoding/base64"
+	"io"
+	"io/ioutil"
 	"testing"
 )
 
@@ -20,3 +23,36 @@ func TestToProduceData(t *testing.T) {
 	println(base64.StdEncoding.EncodeToString(r2))
 	println(base64.StdEncoding.EncodeToString(r3))
 }
+
+func Test_EncryptStreamWithSize(t *testing.T) {
+	legacy, _ := NewLegacyCryptoModule("enigma", true)
+	aesCbc, _ := NewAesCbcCryptoModule("enigma", true)
+	aesGcm, _ := NewAesGcmCryptoModuleWithChunkSize("enigma", true, 16)
+	ring, _ := NewKeyRingCryptor("k1", "enigma")
+	envelope, _ := NewEnvelopeCryptoModule(newCountingKeyProvider(t), 0)
+	modules := map[string]CryptoModule{
+		"legacy":   legacy,
+		"aesCbc":   aesCbc,
+		"aesGcm":   aesGcm,
+		"keyRing":  NewKeyRingCryptoModule(ring, true),
+		"envelope": envelope,
+	}
+
+	for name, module := range modules {
+		for _, size := range []int64{1, 15, 16, 17, 32, 100} {
+			encrypted, encryptedSize, err := EncryptStreamWithSize(module, bytes.NewReader(make([]byte, size)), size)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			n, err := io.Copy(ioutil.Discard, encrypted)
+			if err != nil || n != encryptedSize {
+				t.Errorf("%s: size %d encrypted to %d bytes, expected %d, %v", name, size, n, encryptedSize, err)
+			}
+		}
+	}
+
+	_, encryptedSize, err := EncryptStreamWithSize(aesCbc, bytes.NewReader([]byte("data")), -1)
+	if err != nil || encryptedSize != -1 {
+		t.Errorf("unexpected size %d, %v", encryptedSize, err)
+	}
+}
@@ -0,0 +1,288 @@
+package pubnub
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const (
+	// EditedActionType is the message action type of an edit, the action value is the new content of the message.
+	EditedActionType = "edited"
+	// DeletedActionType is the message action type marking a message as deleted.
+	DeletedActionType = "deleted"
+)
+
+// PNEffectiveMessage is a message with its message actions applied: the latest
+// edit, the deleted flag and the counts of the other actions (reactions).
+type PNEffectiveMessage struct {
+	Channel   string
+	Timetoken string
+	Publisher string
+	Meta      interface{}
+	// Message is the content of the latest edit, or the original message when not edited.
+	Message         interface{}
+	OriginalMessage interface{}
+	Edited          bool
+	EditedBy        string
+	EditTimetoken   string
+	Deleted         bool
+	DeletedBy       string
+	// Reactions holds the count of the actions other than edits and deletes, by type and value.
+	Reactions map[string]map[string]int
+	// ReactionAuthors holds the UUIDs which added the reactions, by type and value.
+	ReactionAuthors map[string]map[string][]string
+
+	actions map[PNMessageActionsResponse]bool
+}
+
+// apply recomputes the effective state of the message from its actions.
+func (m *PNEffectiveMessage) apply() {
+	m.Message = m.OriginalMessage
+	m.Edited, m.EditedBy, m.EditTimetoken = false, "", ""
+	m.Deleted, m.DeletedBy = false, ""
+	m.Reactions = make(map[string]map[string]int)
+	m.ReactionAuthors = make(map[string]map[string][]string)
+
+	var lastEdit, lastDelete int64 = -1, -1
+	for action := range m.actions {
+		tt, _ := strconv.ParseInt(action.ActionTimetoken, 10, 64)
+		switch action.ActionType {
+		case EditedActionType:
+			if tt > lastEdit {
+				lastEdit = tt
+				m.Edited = true
+				m.EditedBy = action.UUID
+				m.EditTimetoken = action.ActionTimetoken
+				m.Message = parseEditValue(action.ActionValue)
+			}
+		case DeletedActionType:
+			if tt > lastDelete {
+				lastDelete = tt
+				m.Deleted = true
+				m.DeletedBy = action.UUID
+			}
+		default:
+			if m.Reactions[action.ActionType] == nil {
+				m.Reactions[action.ActionType] = make(map[string]int)
+				m.ReactionAuthors[action.ActionType] = make(map[string][]string)
+			}
+			m.Reactions[action.ActionType][action.ActionValue]++
+			m.ReactionAuthors[action.ActionType][action.ActionValue] = append(m.ReactionAuthors[action.ActionType][action.ActionValue], action.UUID)
+		}
+	}
+	for _, values := range m.ReactionAuthors {
+		for _, authors := range values {
+			sort.Strings(authors)
+		}
+	}
+}
+
+// parseEditValue returns the JSON object or array carried by the edit, or the value as is.
+func parseEditValue(value string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(value), &v); err == nil {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			return v
+		}
+	}
+	return value
+}
+
+// EffectiveMessageView holds the messages of a channel in their effective state.
+// It is loaded from the history and kept current by applying the message actions
+// events and the new messages.
+type EffectiveMessageView struct {
+	sync.RWMutex
+	pubnub   *PubNub
+	channel  string
+	messages map[string]*PNEffectiveMessage
+}
+
+// NewEffectiveMessageView returns an empty view of the messages of the channel.
+func (pn *PubNub) NewEffectiveMessageView(channel string) *EffectiveMessageView {
+	return &EffectiveMessageView{
+		pubnub:   pn,
+		channel:  channel,
+		messages: make(map[string]*PNEffectiveMessage),
+	}
+}
+
+// Load fetches the messages between start and end (0 for no bound) with their
+// message actions, up to limit messages (0 for all).
+func (v *EffectiveMessageView) Load(ctx Context, start, end int64, limit int) error {
+	builder := v.pubnub.FetchWithContext(ctx).
+		Channels([]string{v.channel}).
+		IncludeMessageActions(true).
+		IncludeMeta(true)
+	if start > 0 {
+		builder.Start(start)
+	}
+	if end > 0 {
+		builder.End(end)
+	}
+
+	it := builder.Iterate(ctx)
+	count := 0
+	for (limit <= 0 || count < limit) && it.Next() {
+		v.addHistoryItem(it.Item())
+		count++
+	}
+
+	return it.Err()
+}
+
+// LoadActions fetches the message actions of the channel with GetMessageActions,
+// back to the oldest loaded message, and applies them to the loaded messages.
+func (v *EffectiveMessageView) LoadActions(ctx Context) error {
+	end := ""
+	if messages := v.Messages(); len(messages) > 0 {
+		end = messages[0].Timetoken
+	}
+
+	it := v.pubnub.GetMessageActionsWithContext(ctx).
+		Channel(v.channel).
+		End(end).
+		Iterate(ctx)
+	for it.Next() {
+		v.Lock()
+		v.addAction(it.Action())
+		v.Unlock()
+	}
+
+	return it.Err()
+}
+
+func (v *EffectiveMessageView) addHistoryItem(item FetchResponseItem) {
+	v.Lock()
+	defer v.Unlock()
+
+	m := &PNEffectiveMessage{
+		Channel:         v.channel,
+		Timetoken:       item.Timetoken,
+		Publisher:       item.UUID,
+		Meta:            item.Meta,
+		OriginalMessage: item.Message,
+		actions:         make(map[PNMessageActionsResponse]bool),
+	}
+	if existing, ok := v.messages[item.Timetoken]; ok {
+		m.actions = existing.actions
+	}
+	for actionType, values := range item.MessageActions {
+		for value, actions := range values.ActionsTypeValues {
+			for _, a := range actions {
+				m.actions[PNMessageActionsResponse{
+					ActionType:       actionType,
+					ActionValue:      value,
+					ActionTimetoken:  a.ActionTimetoken,
+					MessageTimetoken: item.Timetoken,
+					UUID:             a.UUID,
+				}] = true
+			}
+		}
+	}
+	m.apply()
+	v.messages[item.Timetoken] = m
+}
+
+// addAction applies the action to its message, returns false if the message is not in the view.
+func (v *EffectiveMessageView) addAction(action PNMessageActionsResponse) bool {
+	m, ok := v.messages[action.MessageTimetoken]
+	if !ok {
+		return false
+	}
+	m.actions[action] = true
+	m.apply()
+	return true
+}
+
+// AddMessage adds a message received on the channel to the view.
+func (v *EffectiveMessageView) AddMessage(message *PNMessage) {
+	if message == nil || message.Channel != v.channel {
+		return
+	}
+	v.addHistoryItem(FetchResponseItem{
+		Message:   message.Message,
+		Meta:      message.UserMetadata,
+		Timetoken: strconv.FormatInt(message.Timetoken, 10),
+		UUID:      message.Publisher,
+	})
+}
+
+// ApplyEvent applies an added or removed message action to the view.
+// It returns the updated message, false if the event doesn't change a message of the view.
+func (v *EffectiveMessageView) ApplyEvent(event *PNMessageActionsEvent) (PNEffectiveMessage, bool) {
+	if event == nil || event.Channel != v.channel {
+		return PNEffectiveMessage{}, false
+	}
+
+	v.Lock()
+	defer v.Unlock()
+
+	m, ok := v.messages[event.Data.MessageTimetoken]
+	if !ok {
+		return PNEffectiveMessage{}, false
+	}
+	switch event.Event {
+	case PNMessageActionsAdded:
+		m.actions[event.Data] = true
+	case PNMessageActionsRemoved:
+		delete(m.actions, event.Data)
+	default:
+		return PNEffectiveMessage{}, false
+	}
+	m.apply()
+
+	return *m, true
+}
+
+// Run keeps the view current with the events received on the channel, usually
+// the MessageActionsEvent channel of a Listener, until the context is done.
+// onChange, if not nil, is called with each updated message.
+func (v *EffectiveMessageView) Run(ctx Context, events <-chan *PNMessageActionsEvent, onChange func(PNEffectiveMessage)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if m, changed := v.ApplyEvent(event); changed && onChange != nil {
+				onChange(m)
+			}
+		}
+	}
+}
+
+// Message returns the message with the timetoken.
+func (v *EffectiveMessageView) Message(timetoken string) (PNEffectiveMessage, bool) {
+	v.RLock()
+	defer v.RUnlock()
+
+	m, ok := v.messages[timetoken]
+	if !ok {
+		return PNEffectiveMessage{}, false
+	}
+	return *m, true
+}
+
+// Messages returns the messages of the view from the oldest to the newest.
+func (v *EffectiveMessageView) Messages() []PNEffectiveMessage {
+	v.RLock()
+	defer v.RUnlock()
+
+	messages := make([]PNEffectiveMessage, 0, len(v.messages))
+	for _, m := range v.messages {
+		messages = append(messages, *m)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		ti, _ := strconv.ParseInt(messages[i].Timetoken, 10, 64)
+		tj, _ := strconv.ParseInt(messages[j].Timetoken, 10, 64)
+		return ti < tj
+	})
+
+	return messages
+}
@@ -0,0 +1,101 @@
+package pubnub
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEffectiveMessageViewLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	defer pn.Destroy()
+
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Opaque, "/message-actions/") {
+			assert.Equal("10", req.URL.Query().Get("end"))
+			return stubResponse(req, `{"status":200,"data":[{"type":"reaction","value":"smile","uuid":"c","actionTimetoken":"25","messageTimetoken":"20"},{"type":"reaction","value":"smile","uuid":"d","actionTimetoken":"26","messageTimetoken":"99"}]}`), nil
+		}
+		return stubResponse(req, `{"status":200,"channels":{"ch":[
+			{"message":{"text":"hi"},"timetoken":"10","uuid":"u1","actions":{
+				"edited":{"first edit":[{"uuid":"u1","actionTimetoken":"11"}],"{\"text\":\"hello\"}":[{"uuid":"u1","actionTimetoken":"13"}]},
+				"reaction":{"smile":[{"uuid":"b","actionTimetoken":"12"},{"uuid":"a","actionTimetoken":"14"}],"heart":[{"uuid":"a","actionTimetoken":"15"}]}}},
+			{"message":"bye","timetoken":"20","uuid":"u2","actions":{"deleted":{"deleted":[{"uuid":"admin","actionTimetoken":"21"}]}}}
+		]}}`), nil
+	})})
+
+	view := pn.NewEffectiveMessageView("ch")
+	assert.Nil(view.Load(context.Background(), 0, 0, 0))
+
+	messages := view.Messages()
+	assert.Equal(2, len(messages))
+
+	first := messages[0]
+	assert.Equal("10", first.Timetoken)
+	assert.Equal(map[string]interface{}{"text": "hello"}, first.Message)
+	assert.Equal(map[string]interface{}{"text": "hi"}, first.OriginalMessage)
+	assert.True(first.Edited)
+	assert.Equal("13", first.EditTimetoken)
+	assert.False(first.Deleted)
+	assert.Equal(map[string]map[string]int{"reaction": {"smile": 2, "heart": 1}}, first.Reactions)
+	assert.Equal([]string{"a", "b"}, first.ReactionAuthors["reaction"]["smile"])
+
+	second := messages[1]
+	assert.True(second.Deleted)
+	assert.Equal("admin", second.DeletedBy)
+	assert.Equal("bye", second.Message)
+
+	assert.Nil(view.LoadActions(context.Background()))
+	second, _ = view.Message("20")
+	assert.Equal(1, second.Reactions["reaction"]["smile"])
+	_, ok := view.Message("99")
+	assert.False(ok)
+}
+
+func TestEffectiveMessageViewLiveUpdates(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	view := pn.NewEffectiveMessageView("ch")
+	view.AddMessage(&PNMessage{Channel: "ch", Message: "hi", Timetoken: 10, Publisher: "u1"})
+	view.AddMessage(&PNMessage{Channel: "other", Message: "ignored", Timetoken: 11})
+
+	events := make(chan *PNMessageActionsEvent)
+	changes := make(chan PNEffectiveMessage)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		view.Run(ctx, events, func(m PNEffectiveMessage) { changes <- m })
+		done <- true
+	}()
+
+	edit := PNMessageActionsResponse{ActionType: EditedActionType, ActionValue: "hi!", ActionTimetoken: "12", MessageTimetoken: "10", UUID: "u1"}
+	events <- &PNMessageActionsEvent{Event: PNMessageActionsAdded, Channel: "ch", Data: edit}
+	m := <-changes
+	assert.Equal("hi!", m.Message)
+	assert.True(m.Edited)
+
+	events <- &PNMessageActionsEvent{Event: PNMessageActionsAdded, Channel: "ch", Data: PNMessageActionsResponse{ActionType: "reaction", ActionValue: "smile", ActionTimetoken: "13", MessageTimetoken: "10", UUID: "u2"}}
+	m = <-changes
+	assert.Equal(1, m.Reactions["reaction"]["smile"])
+
+	events <- &PNMessageActionsEvent{Event: PNMessageActionsRemoved, Channel: "ch", Data: edit}
+	m = <-changes
+	assert.Equal("hi", m.Message)
+	assert.False(m.Edited)
+	assert.Equal(1, m.Reactions["reaction"]["smile"])
+
+	// events for unknown messages or other channels don't change the view
+	_, changed := view.ApplyEvent(&PNMessageActionsEvent{Event: PNMessageActionsAdded, Channel: "ch", Data: PNMessageActionsResponse{MessageTimetoken: "99"}})
+	assert.False(changed)
+	_, changed = view.ApplyEvent(&PNMessageActionsEvent{Event: PNMessageActionsAdded, Channel: "other", Data: edit})
+	assert.False(changed)
+
+	cancel()
+	<-done
+	assert.Equal(1, len(view.Messages()))
+}
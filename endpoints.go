@@ -1,11 +1,10 @@
 package pubnub
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"log"
-	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -30,11 +29,13 @@ type endpoint interface {
 	buildPath() (string, error)
 	buildQuery() (*url.Values, error)
 	buildBody() ([]byte, error)
-	buildBodyMultipartFileUpload() (bytes.Buffer, *multipart.Writer, int64, error)
+	buildBodyMultipartFileUpload() (io.ReadCloser, string, int64, error)
 	httpMethod() string
 	operationType() OperationType
 	telemetryManager() *TelemetryManager
 	tokenManager() *TokenManager
+	useRequestWorkers() bool
+	requestHeaders() http.Header
 }
 
 func (o *endpointOpts) config() *Config {
@@ -77,14 +78,24 @@ func (o *endpointOpts) connectTimeout() int {
 	return o.pubnub.Config.ConnectTimeout
 }
 
-func (o *endpointOpts) buildBodyMultipartFileUpload() (bytes.Buffer, *multipart.Writer, int64, error) {
-	return bytes.Buffer{}, nil, 0, errors.New("Not required")
+func (o *endpointOpts) buildBodyMultipartFileUpload() (io.ReadCloser, string, int64, error) {
+	return nil, "", 0, errors.New("Not required")
 }
 
 func (o *endpointOpts) httpMethod() string {
 	return "GET"
 }
 
+// useRequestWorkers tells if the request is run by the request workers, along with the Publish and Grant requests.
+func (o *endpointOpts) useRequestWorkers() bool {
+	return false
+}
+
+// requestHeaders returns the headers added to the request.
+func (o *endpointOpts) requestHeaders() http.Header {
+	return nil
+}
+
 // SetQueryParam appends the query params map to the query string
 func SetQueryParam(q *url.Values, queryParam map[string]string) {
 	if queryParam != nil {
@@ -177,6 +177,9 @@ const (
 	PNReconnectionAttemptsExhausted
 	// PNRequestMessageCountExceededCategory is fired when the MessageQueueOverflowCount limit is exceeded by the number of messages received in a single subscribe request
 	PNRequestMessageCountExceededCategory
+	// PNChunkReassemblyTimeoutCategory as the StatusCategory means that not all the chunks of a chunked message
+	// were received within ChunkReassemblyTimeout and the message was dropped.
+	PNChunkReassemblyTimeoutCategory
 )
 
 const (
@@ -447,6 +450,9 @@ func (c StatusCategory) String() string {
 	case PNNoStubMatchedCategory:
 		return "No Stub Matched"
 
+	case PNChunkReassemblyTimeoutCategory:
+		return "Chunk Reassembly Timeout"
+
 	default:
 		return "No Stub Matched"
 
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 
 	"encoding/json"
 	"log"
@@ -222,6 +223,7 @@ func showHelp() {
 	showWhereNowHelp()
 	showUnsubscribeHelp()
 	showFetchHelp()
+	showExportHelp()
 	showFireHelp()
 	showSetStateHelp()
 	showGetStateHelp()
@@ -398,6 +400,13 @@ func showFetchHelp() {
 	fmt.Println("	fetch my-channel,test true 10 15210190573608384 15211140747622125 ")
 }
 
+func showExportHelp() {
+	fmt.Println(" EXPORT EXAMPLE: ")
+	fmt.Println("	export Format(jsonl|csv) Channels End Start File ")
+	fmt.Println("	export jsonl my-channel,test 15210190573608384 0 history.jsonl ")
+	fmt.Println("	The export is resumed from File.cursor when it exists")
+}
+
 func showFireHelp() {
 	fmt.Println(" FIRE EXAMPLE: ")
 	fmt.Println("	fire usePost \"my-message\" my-channel")
@@ -528,6 +537,8 @@ func readCommand(cmd string) {
 		unsubscribeRequest(command[1:])
 	case "fetch":
 		fetchRequest(command[1:])
+	case "export":
+		exportRequest(command[1:])
 	case "delmessages":
 		delMessageRequest(command[1:])
 	case "subs":
@@ -672,21 +683,17 @@ func downloadFile(args []string) {
 	id := args[1]
 	name := args[2]
 
-	resDLFile, status, err := pn.DownloadFile().Channel(ch).ID(id).Name(name).Execute()
+	out, err := os.Create("out.txt")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer out.Close()
+
+	n, status, err := pn.DownloadFile().Channel(ch).ID(id).Name(name).DownloadTo(out)
 	fmt.Println("status", status)
 	fmt.Println("err", err)
-	fmt.Println("res", resDLFile)
-
-	if resDLFile != nil {
-		out, _ := os.Create("out.txt")
-		defer out.Close()
-
-		_, err := io.Copy(out, resDLFile.File)
-
-		if err != nil {
-			fmt.Println(err)
-		}
-	}
+	fmt.Println("bytes", n)
 }
 
 func getMessageActionsRec2(args []string) {
@@ -1761,6 +1768,50 @@ func whereNowRequest(args []string) {
 	fmt.Println(fmt.Sprintf("%s", outputSuffix))
 }
 
+func exportRequest(args []string) {
+	if len(args) < 5 {
+		showExportHelp()
+		return
+	}
+
+	opts := pubnub.HistoryExportOptions{
+		Channels: strings.Split(args[1], ","),
+	}
+	if args[0] == "csv" {
+		opts.Format = pubnub.HistoryExportCSV
+	}
+	opts.End, _ = strconv.ParseInt(args[2], 10, 64)
+	opts.Start, _ = strconv.ParseInt(args[3], 10, 64)
+
+	fileName := args[4]
+	cursorFileName := fileName + ".cursor"
+	if cursor, err := ioutil.ReadFile(cursorFileName); err == nil {
+		if err := json.Unmarshal(cursor, &opts.ResumeFrom); err != nil {
+			showErr(err.Error())
+			return
+		}
+		opts.SkipHeader = true
+		fmt.Println("Resuming export from", opts.ResumeFrom)
+	}
+
+	f, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		showErr(err.Error())
+		return
+	}
+	defer f.Close()
+
+	res, err := pn.ExportHistory(nil, f, opts)
+	if cursor, errJSON := json.Marshal(res.Cursors); errJSON == nil {
+		ioutil.WriteFile(cursorFileName, cursor, 0644)
+	}
+	if err != nil {
+		showErr(fmt.Sprintf("Export stopped after %d messages: %s", res.Count, err.Error()))
+		return
+	}
+	fmt.Println(fmt.Sprintf("Exported %d messages to %s", res.Count, fileName))
+}
+
 func fetchRequest(args []string) {
 	if len(args) == 0 {
 		showFetchHelp()
@@ -0,0 +1,128 @@
+package pubnub
+
+import (
+	"sync"
+
+	"github.com/pubnub/go/v7/utils"
+)
+
+// maxChannelsPerFetch is the max number of channels of a single request of a bulk Fetch.
+const maxChannelsPerFetch = 25
+
+// maxFetchChannelsPathLength is the max length of the encoded channel list in the path of a bulk Fetch request.
+const maxFetchChannelsPathLength = 1500
+
+const defaultFetchBulkConcurrency = 5
+
+// ExecuteBulk fetches the messages of any number of channels. The channels are split
+// into chunks fetched concurrently and the messages are merged into one FetchResponse.
+// The channels whose chunk failed are reported in ChannelErrors, the error is
+// returned only when the request is invalid or every chunk failed. The status is
+// the one of the last chunk which succeeded, or of the last failed one when they all failed.
+//
+// When Count is more than 25 (or IncludeMessageActions is true) the channels are
+// fetched one per request, as the server caps the count of multi channel requests.
+func (b *fetchBuilder) ExecuteBulk() (*FetchResponse, StatusResponse, error) {
+	channels := []string{}
+	seen := make(map[string]bool, len(b.opts.Channels))
+	for _, ch := range b.opts.Channels {
+		if !seen[ch] {
+			seen[ch] = true
+			channels = append(channels, ch)
+		}
+	}
+
+	validateOpts := *b.opts
+	if len(channels) > 0 {
+		validateOpts.Channels = channels[:1]
+	}
+	if err := validateOpts.validate(); err != nil {
+		return emptyFetchResp, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+	}
+
+	chunks := b.opts.bulkChunks(channels)
+	concurrency := b.opts.BulkConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultFetchBulkConcurrency
+	}
+
+	resp := &FetchResponse{
+		Messages:      make(map[string][]FetchResponseItem, len(channels)),
+		ChannelErrors: make(map[string]error),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan []string)
+	var lastErr error
+	var status, errStatus StatusResponse
+
+	for i := 0; i < concurrency && i < len(chunks); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range jobs {
+				opts := *b.opts
+				opts.Channels = chunk
+				chunkResp, chunkStatus, err := (&fetchBuilder{opts: &opts}).Execute()
+
+				mu.Lock()
+				if err != nil {
+					b.opts.pubnub.Config.Log.Println("bulk fetch chunk failed", chunk, err)
+					lastErr, errStatus = err, chunkStatus
+					for _, ch := range chunk {
+						resp.ChannelErrors[ch] = err
+					}
+				} else {
+					status = chunkStatus
+					for _, ch := range chunk {
+						if items, ok := chunkResp.Messages[ch]; ok {
+							resp.Messages[ch] = items
+						}
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, chunk := range chunks {
+		jobs <- chunk
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(resp.ChannelErrors) == len(channels) {
+		return resp, errStatus, lastErr
+	}
+
+	return resp, status, nil
+}
+
+// bulkChunks splits the channels into chunks fitting in a single Fetch request.
+func (o *fetchOpts) bulkChunks(channels []string) [][]string {
+	size := o.BulkChunkSize
+	if size <= 0 || size > maxChannelsPerFetch {
+		size = maxChannelsPerFetch
+	}
+	if o.WithMessageActions || o.Count > maxCountFetchMoreThanOneChannel {
+		size = 1
+	}
+
+	chunks := [][]string{}
+	chunk := []string{}
+	length := 0
+	for _, ch := range channels {
+		chLength := len(utils.URLEncode(ch)) + 1
+		if len(chunk) > 0 && (len(chunk) == size || length+chLength > maxFetchChannelsPathLength) {
+			chunks = append(chunks, chunk)
+			chunk = []string{}
+			length = 0
+		}
+		chunk = append(chunk, ch)
+		length += chLength
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
@@ -0,0 +1,105 @@
+package pubnub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchBulkChunks(t *testing.T) {
+	assert := assert.New(t)
+
+	channels := []string{}
+	for i := 0; i < 60; i++ {
+		channels = append(channels, fmt.Sprintf("ch%d", i))
+	}
+
+	opts := newFetchOpts(pubnub, pubnub.ctx, fetchOpts{})
+	chunks := opts.bulkChunks(channels)
+	assert.Equal(3, len(chunks))
+	assert.Equal(25, len(chunks[0]))
+	assert.Equal(10, len(chunks[2]))
+
+	opts.BulkChunkSize = 7
+	assert.Equal(9, len(opts.bulkChunks(channels)))
+
+	opts.Count = 50
+	assert.Equal(60, len(opts.bulkChunks(channels)))
+
+	long := []string{strings.Repeat("a", 1000), strings.Repeat("b", 1000)}
+	opts.Count = 0
+	assert.Equal(2, len(opts.bulkChunks(long)))
+}
+
+func TestFetchExecuteBulk(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	defer pn.Destroy()
+
+	var mu sync.Mutex
+	inFlight, maxInFlight, requests := 0, 0, 0
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		requests++
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		path := req.URL.Opaque
+		rawChannels := path[strings.LastIndex(path, "/")+1:]
+		channels := map[string]interface{}{}
+		for _, raw := range strings.Split(rawChannels, ",") {
+			ch, _ := url.PathUnescape(raw)
+			if ch == "fail" {
+				resp := stubResponse(req, `{"status": 403, "error": true, "error_message": "Forbidden"}`)
+				resp.StatusCode = 403
+				return resp, nil
+			}
+			channels[ch] = []interface{}{map[string]interface{}{"message": ch, "timetoken": "1"}}
+		}
+		body, _ := json.Marshal(map[string]interface{}{"status": 200, "error": false, "channels": channels})
+		return stubResponse(req, string(body)), nil
+	})})
+
+	channels := []string{"fail"}
+	for i := 0; i < 99; i++ {
+		channels = append(channels, fmt.Sprintf("ch%d", i))
+	}
+	channels = append(channels, "ch0")
+
+	resp, status, err := pn.Fetch().Channels(channels).BulkChunkSize(10).BulkConcurrency(3).ExecuteBulk()
+	assert.Nil(err)
+	assert.Equal(200, status.StatusCode)
+	assert.Equal(10, requests)
+	assert.True(maxInFlight <= 3)
+	assert.Equal(90, len(resp.Messages))
+	assert.Equal("ch42", resp.Messages["ch42"][0].Message)
+
+	// the channels sharing the request of the failed channel are reported
+	assert.Equal(10, len(resp.ChannelErrors))
+	assert.NotNil(resp.ChannelErrors["fail"])
+	assert.NotNil(resp.ChannelErrors["ch8"])
+	assert.Nil(resp.ChannelErrors["ch9"])
+
+	_, status, err = pn.Fetch().Channels([]string{"fail"}).ExecuteBulk()
+	assert.NotNil(err)
+	assert.NotEqual(200, status.StatusCode)
+
+	_, status, err = pn.Fetch().ExecuteBulk()
+	assert.Contains(err.Error(), StrMissingChannel)
+	assert.Equal(PNUnknownCategory, status.Category)
+}
@@ -0,0 +1,231 @@
+package pubnub
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// fetchCursor is the position of the iteration in the history of a channel.
+type fetchCursor struct {
+	start    int64
+	setStart bool
+	end      int64
+	setEnd   bool
+	// max is the page size given by the `more` link, 0 for the Count of the request
+	max int
+}
+
+// followMore moves the cursor to the next page given by the `more` link, the
+// bounds of its URL are used when set. Otherwise the next page starts after
+// the last item of the page in the direction of the iteration. Returns false
+// when there are no more pages.
+func (c *fetchCursor) followMore(more PNFetchMore, reverse bool, bounds fetchPageBounds) bool {
+	if more.URL == "" && more.Start == "" {
+		return false
+	}
+	var q url.Values
+	if u, err := url.Parse(more.URL); err == nil {
+		q = u.Query()
+	}
+
+	moved := false
+	if start, err := strconv.ParseInt(q.Get("start"), 10, 64); err == nil {
+		c.start, c.setStart = start, true
+		moved = true
+	}
+	if end, err := strconv.ParseInt(q.Get("end"), 10, 64); err == nil {
+		c.end, c.setEnd = end, true
+		moved = true
+	}
+	if !moved {
+		if reverse {
+			// end is inclusive
+			c.end, c.setEnd = bounds.max+1, true
+		} else if start, err := strconv.ParseInt(more.Start, 10, 64); err == nil {
+			c.start, c.setStart = start, true
+		} else {
+			// start is exclusive
+			c.start, c.setStart = bounds.min, true
+		}
+	}
+
+	if max, err := strconv.Atoi(q.Get("max")); err == nil && max > 0 {
+		c.max = max
+	} else if more.Max > 0 {
+		c.max = more.Max
+	}
+	return true
+}
+
+type fetchIteratorEntry struct {
+	channel string
+	item    FetchResponseItem
+}
+
+// FetchIterator pages through the history of the channels of a Fetch request,
+// following the `more` links when the message actions are included.
+//
+// The items of each channel are yielded from the newest to the oldest, or from
+// the oldest to the newest when Reverse is true. The pages of different channels
+// may be interleaved. A chunked message split over several pages is yielded
+// once its last chunk is fetched.
+//
+//	it := pn.Fetch().Channels([]string{"ch"}).Start(start).End(end).Iterate(ctx)
+//	for it.Next() {
+//		fmt.Println(it.Channel(), it.Item().Message)
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type FetchIterator struct {
+	pageIterator
+	opts     fetchOpts
+	channels []string
+	cursors  map[string]*fetchCursor
+	buffer   []fetchIteratorEntry
+	current  fetchIteratorEntry
+	chunks   *fetchChunkStitcher
+}
+
+// Iterate returns an iterator paging through the history between Start and End
+// for all the channels. The iteration stops when the context is done.
+func (b *fetchBuilder) Iterate(ctx Context) *FetchIterator {
+	it := &FetchIterator{
+		pageIterator: newPageIterator(ctx, b.opts.endpointOpts),
+		opts:         *b.opts,
+		cursors:      make(map[string]*fetchCursor, len(b.opts.Channels)),
+	}
+	it.opts.ctx = it.ctx
+	it.chunks = newFetchChunkStitcher(&it.opts)
+
+	if err := b.opts.validate(); err != nil {
+		it.err = err
+		return it
+	}
+	for _, ch := range b.opts.Channels {
+		if _, ok := it.cursors[ch]; ok {
+			continue
+		}
+		it.channels = append(it.channels, ch)
+		it.cursors[ch] = &fetchCursor{
+			start:    b.opts.Start,
+			setStart: b.opts.setStart,
+			end:      b.opts.End,
+			setEnd:   b.opts.setEnd,
+		}
+	}
+
+	return it
+}
+
+// Next advances the iterator to the next item, fetching the next page when needed.
+// It returns false when the history is exhausted, on error or when the context is done.
+func (it *FetchIterator) Next() bool {
+	if !it.next(func() int { return len(it.buffer) }, func() bool { return len(it.channels) == 0 }, it.fetchPage) {
+		return false
+	}
+	it.current = it.buffer[0]
+	it.buffer = it.buffer[1:]
+
+	return true
+}
+
+// Channel returns the channel of the current item.
+func (it *FetchIterator) Channel() string {
+	return it.current.channel
+}
+
+// Item returns the current item.
+func (it *FetchIterator) Item() FetchResponseItem {
+	return it.current.item
+}
+
+// Err returns the error which stopped the iteration, nil when the history was exhausted.
+func (it *FetchIterator) Err() error {
+	return it.err
+}
+
+// nextGroup returns the channels sharing the cursor of the first remaining
+// channel, they are fetched in a single request.
+func (it *FetchIterator) nextGroup() []string {
+	first := *it.cursors[it.channels[0]]
+	if it.opts.WithMessageActions {
+		return it.channels[:1]
+	}
+	group := []string{}
+	for _, ch := range it.channels {
+		if *it.cursors[ch] == first {
+			group = append(group, ch)
+		}
+	}
+	return group
+}
+
+func (it *FetchIterator) fetchPage() {
+	group := it.nextGroup()
+	cursor := *it.cursors[group[0]]
+
+	opts := it.opts
+	opts.Channels = group
+	opts.Start, opts.setStart = cursor.start, cursor.setStart
+	opts.End, opts.setEnd = cursor.end, cursor.setEnd
+	if cursor.max > 0 {
+		opts.Count = cursor.max
+	}
+
+	resp, _, err := (&fetchBuilder{opts: &opts}).Execute()
+	if err != nil {
+		it.fail(err)
+		return
+	}
+	maxCount := opts.maxCount()
+
+	for _, ch := range group {
+		items := append([]FetchResponseItem{}, resp.Messages[ch]...)
+		sort.SliceStable(items, func(i, j int) bool {
+			ti, _ := strconv.ParseInt(items[i].Timetoken, 10, 64)
+			tj, _ := strconv.ParseInt(items[j].Timetoken, 10, 64)
+			if opts.Reverse {
+				return ti < tj
+			}
+			return ti > tj
+		})
+		for _, item := range items {
+			if item, ok := it.chunks.add(ch, item); ok {
+				it.buffer = append(it.buffer, fetchIteratorEntry{channel: ch, item: item})
+			}
+		}
+
+		bounds := resp.bounds[ch]
+		c := it.cursors[ch]
+		switch {
+		case opts.WithMessageActions:
+			previous := *c
+			if bounds.count == 0 || !c.followMore(resp.More, opts.Reverse, bounds) || *c == previous {
+				it.done(ch)
+			}
+		case bounds.count < maxCount:
+			it.done(ch)
+		case opts.Reverse:
+			// end is inclusive
+			c.end, c.setEnd = bounds.max+1, true
+		default:
+			// start is exclusive
+			c.start, c.setStart = bounds.min, true
+		}
+	}
+}
+
+func (it *FetchIterator) done(channel string) {
+	for i, ch := range it.channels {
+		if ch == channel {
+			it.channels = append(it.channels[:i], it.channels[i+1:]...)
+			break
+		}
+	}
+	delete(it.cursors, channel)
+	for _, item := range it.chunks.flush(channel) {
+		it.buffer = append(it.buffer, fetchIteratorEntry{channel: channel, item: item})
+	}
+}
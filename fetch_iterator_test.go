@@ -0,0 +1,280 @@
+package pubnub
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newFetchIteratorTestPubNub serves the history of the channels, each one holding
+// the messages with the given timetokens, honouring start, end, max and reverse.
+func newFetchIteratorTestPubNub(history map[string][]int64, requests *int) *PubNub {
+	pn := NewPubNub(NewDemoConfig())
+
+	var mu sync.Mutex
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		*requests++
+		mu.Unlock()
+
+		path := req.URL.Opaque
+		rawChannels := path[strings.LastIndex(path, "/")+1:]
+		q := req.URL.Query()
+		max, _ := strconv.Atoi(q.Get("max"))
+		reverse := q.Get("reverse") == "true"
+		start, hasStart := int64(0), q.Get("start") != ""
+		if hasStart {
+			start, _ = strconv.ParseInt(q.Get("start"), 10, 64)
+		}
+		end, hasEnd := int64(0), q.Get("end") != ""
+		if hasEnd {
+			end, _ = strconv.ParseInt(q.Get("end"), 10, 64)
+		}
+
+		channels := map[string]interface{}{}
+		for _, raw := range strings.Split(rawChannels, ",") {
+			ch, _ := url.PathUnescape(raw)
+			inRange := []int64{}
+			for _, tt := range history[ch] {
+				if (!hasStart || tt < start) && (!hasEnd || tt >= end) {
+					inRange = append(inRange, tt)
+				}
+			}
+			if len(inRange) > max {
+				if reverse {
+					inRange = inRange[:max]
+				} else {
+					inRange = inRange[len(inRange)-max:]
+				}
+			}
+			items := []interface{}{}
+			for _, tt := range inRange {
+				items = append(items, map[string]interface{}{
+					"message":   ch + "-" + strconv.FormatInt(tt, 10),
+					"timetoken": strconv.FormatInt(tt, 10),
+				})
+			}
+			channels[ch] = items
+		}
+		body, _ := json.Marshal(map[string]interface{}{"status": 200, "error": false, "channels": channels})
+		return stubResponse(req, string(body)), nil
+	})})
+
+	return pn
+}
+
+func timetokens(from, to int64) []int64 {
+	tts := []int64{}
+	for tt := from; tt <= to; tt++ {
+		tts = append(tts, tt)
+	}
+	return tts
+}
+
+func collectFetchIterator(it *FetchIterator) map[string][]string {
+	result := map[string][]string{}
+	for it.Next() {
+		result[it.Channel()] = append(result[it.Channel()], it.Item().Timetoken)
+	}
+	return result
+}
+
+func formatTimetokens(tts []int64) []string {
+	s := []string{}
+	for _, tt := range tts {
+		s = append(s, strconv.FormatInt(tt, 10))
+	}
+	return s
+}
+
+func TestFetchIteratorBackwards(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := 0
+	pn := newFetchIteratorTestPubNub(map[string][]int64{
+		"a": timetokens(1, 60),
+		"b": timetokens(100, 110),
+	}, &requests)
+	defer pn.Destroy()
+
+	it := pn.Fetch().Channels([]string{"a", "b"}).Iterate(context.Background())
+	result := collectFetchIterator(it)
+	assert.Nil(it.Err())
+
+	expectedA := timetokens(1, 60)
+	for i, j := 0, len(expectedA)-1; i < j; i, j = i+1, j-1 {
+		expectedA[i], expectedA[j] = expectedA[j], expectedA[i]
+	}
+	expectedB := timetokens(100, 110)
+	for i, j := 0, len(expectedB)-1; i < j; i, j = i+1, j-1 {
+		expectedB[i], expectedB[j] = expectedB[j], expectedB[i]
+	}
+	assert.Equal(formatTimetokens(expectedA), result["a"])
+	assert.Equal(formatTimetokens(expectedB), result["b"])
+	// one request for both channels (25 each), then up to 100 for channel a alone
+	assert.Equal(2, requests)
+}
+
+func TestFetchIteratorForwardsWithinRange(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := 0
+	pn := newFetchIteratorTestPubNub(map[string][]int64{"a": timetokens(1, 300)}, &requests)
+	defer pn.Destroy()
+
+	it := pn.Fetch().Channels([]string{"a"}).Start(251).End(20).Count(100).Reverse(true).Iterate(nil)
+	result := collectFetchIterator(it)
+	assert.Nil(it.Err())
+	assert.Equal(formatTimetokens(timetokens(20, 250)), result["a"])
+	assert.Equal(3, requests)
+}
+
+func TestFetchIteratorContextCancelled(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := 0
+	pn := newFetchIteratorTestPubNub(map[string][]int64{"a": timetokens(1, 300)}, &requests)
+	defer pn.Destroy()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	it := pn.Fetch().Channels([]string{"a"}).Iterate(ctx)
+	count := 0
+	for it.Next() {
+		count++
+		if count == 10 {
+			cancel()
+		}
+	}
+	assert.Equal(10, count)
+	assert.Equal(context.Canceled, it.Err())
+	assert.Equal(1, requests)
+}
+
+func TestFetchIteratorFollowsMore(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	defer pn.Destroy()
+
+	starts := []string{}
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		assert.Contains(req.URL.Opaque, "/history-with-actions/")
+		start := req.URL.Query().Get("start")
+		starts = append(starts, start)
+		if start == "" {
+			return stubResponse(req, `{"status":200,"channels":{"a":[{"message":"m3","timetoken":"30"},{"message":"m2","timetoken":"20"}]},"more":{"url":"/v3/history-with-actions/sub-key/demo/channel/a?start=20&max=2","start":"20","max":2}}`), nil
+		}
+		return stubResponse(req, `{"status":200,"channels":{"a":[{"message":"m1","timetoken":"10"}]}}`), nil
+	})})
+
+	it := pn.Fetch().Channels([]string{"a"}).IncludeMessageActions(true).Count(2).Iterate(nil)
+	result := collectFetchIterator(it)
+	assert.Nil(it.Err())
+	assert.Equal([]string{"30", "20", "10"}, result["a"])
+	assert.Equal([]string{"", "20"}, starts)
+}
+
+// newFetchActionsTestPubNub serves the history with message actions of channel a,
+// honouring start, end, max and reverse. The `more` link holds the bounds of the
+// next page when moreBounds is true, only its max otherwise.
+func newFetchActionsTestPubNub(t *testing.T, history []int64, moreBounds bool, queries *[]string) *PubNub {
+	pn := NewPubNub(NewDemoConfig())
+
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		assert.Contains(t, req.URL.Opaque, "/history-with-actions/")
+		q := req.URL.Query()
+		*queries = append(*queries, "start="+q.Get("start")+"&end="+q.Get("end")+"&max="+q.Get("max"))
+		max, _ := strconv.Atoi(q.Get("max"))
+		reverse := q.Get("reverse") == "true"
+		start, _ := strconv.ParseInt(q.Get("start"), 10, 64)
+		end, _ := strconv.ParseInt(q.Get("end"), 10, 64)
+
+		inRange := []int64{}
+		for _, tt := range history {
+			if (start == 0 || tt < start) && tt >= end {
+				inRange = append(inRange, tt)
+			}
+		}
+		page := inRange
+		if len(page) > max {
+			if reverse {
+				page = page[:max]
+			} else {
+				page = page[len(page)-max:]
+			}
+		}
+		items := []interface{}{}
+		for _, tt := range page {
+			items = append(items, map[string]interface{}{"message": "m", "timetoken": strconv.FormatInt(tt, 10)})
+		}
+		resp := map[string]interface{}{"status": 200, "channels": map[string]interface{}{"a": items}}
+		if len(page) < len(inRange) {
+			more := "/v3/history-with-actions/sub-key/demo/channel/a?max=" + strconv.Itoa(max)
+			next := page[0]
+			if reverse {
+				next = page[len(page)-1] + 1
+				if moreBounds {
+					more += "&end=" + strconv.FormatInt(next, 10)
+				}
+			} else if moreBounds {
+				more += "&start=" + strconv.FormatInt(next, 10)
+			}
+			if start != 0 {
+				more += "&start=" + strconv.FormatInt(start, 10)
+			}
+			resp["more"] = map[string]interface{}{"url": more, "start": strconv.FormatInt(page[0], 10), "max": max}
+		}
+		body, _ := json.Marshal(resp)
+		return stubResponse(req, string(body)), nil
+	})})
+
+	return pn
+}
+
+func TestFetchIteratorFollowsMoreReverse(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, moreBounds := range []bool{true, false} {
+		queries := []string{}
+		pn := newFetchActionsTestPubNub(t, timetokens(1, 5), moreBounds, &queries)
+
+		it := pn.Fetch().Channels([]string{"a"}).IncludeMessageActions(true).Reverse(true).Count(2).Iterate(nil)
+		result := collectFetchIterator(it)
+		assert.Nil(it.Err())
+		assert.Equal(formatTimetokens(timetokens(1, 5)), result["a"], "more bounds %v", moreBounds)
+		assert.Equal([]string{"start=&end=&max=2", "start=&end=3&max=2", "start=&end=5&max=2"}, queries, "more bounds %v", moreBounds)
+		pn.Destroy()
+	}
+}
+
+func TestFetchIteratorFollowsMoreWithinRange(t *testing.T) {
+	assert := assert.New(t)
+
+	queries := []string{}
+	pn := newFetchActionsTestPubNub(t, timetokens(1, 10), true, &queries)
+	defer pn.Destroy()
+
+	it := pn.Fetch().Channels([]string{"a"}).IncludeMessageActions(true).Reverse(true).Start(8).End(3).Count(2).Iterate(nil)
+	result := collectFetchIterator(it)
+	assert.Nil(it.Err())
+	assert.Equal(formatTimetokens(timetokens(3, 7)), result["a"])
+	assert.Equal([]string{"start=8&end=3&max=2", "start=8&end=5&max=2", "start=8&end=7&max=2"}, queries)
+}
+
+func TestFetchIteratorValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+
+	it := pn.Fetch().Iterate(nil)
+	assert.False(it.Next())
+	assert.Contains(it.Err().Error(), StrMissingChannel)
+}
@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strconv"
 
+	"github.com/pubnub/go/v7/crypto"
 	"github.com/pubnub/go/v7/pnerr"
 	"github.com/pubnub/go/v7/utils"
 
@@ -60,6 +61,16 @@ func (b *fetchBuilder) End(end int64) *fetchBuilder {
 	return b
 }
 
+// WithStart sets the Start Timetoken for the Fetch request.
+func (b *fetchBuilder) WithStart(start Timetoken) *fetchBuilder {
+	return b.Start(start.Int64())
+}
+
+// WithEnd sets the End Timetoken for the Fetch request.
+func (b *fetchBuilder) WithEnd(end Timetoken) *fetchBuilder {
+	return b.End(end.Int64())
+}
+
 // Count sets the number of items to return in the Fetch request.
 func (b *fetchBuilder) Count(count int) *fetchBuilder {
 	b.opts.Count = count
@@ -96,6 +107,18 @@ func (b *fetchBuilder) IncludeMessageType(withMessageType bool) *fetchBuilder {
 	return b
 }
 
+// BulkChunkSize sets the max number of channels per request of ExecuteBulk (default and max 25).
+func (b *fetchBuilder) BulkChunkSize(size int) *fetchBuilder {
+	b.opts.BulkChunkSize = size
+	return b
+}
+
+// BulkConcurrency sets the number of requests of ExecuteBulk running concurrently (default 5).
+func (b *fetchBuilder) BulkConcurrency(concurrency int) *fetchBuilder {
+	b.opts.BulkConcurrency = concurrency
+	return b
+}
+
 // QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
 func (b *fetchBuilder) QueryParam(queryParam map[string]string) *fetchBuilder {
 	b.opts.QueryParam = queryParam
@@ -103,6 +126,14 @@ func (b *fetchBuilder) QueryParam(queryParam map[string]string) *fetchBuilder {
 	return b
 }
 
+// CryptoModule sets the cryptography module the messages are decrypted with,
+// overriding the Config one and the ones resolved for the channels.
+func (b *fetchBuilder) CryptoModule(cryptoModule crypto.CryptoModule) *fetchBuilder {
+	b.opts.CryptoModule = cryptoModule
+
+	return b
+}
+
 // Transport sets the Transport for the Fetch request.
 func (b *fetchBuilder) Transport(tr http.RoundTripper) *fetchBuilder {
 	b.opts.Transport = tr
@@ -148,6 +179,11 @@ type fetchOpts struct {
 
 	QueryParam map[string]string
 
+	BulkChunkSize   int
+	BulkConcurrency int
+
+	CryptoModule crypto.CryptoModule
+
 	// nil hacks
 	setStart bool
 	setEnd   bool
@@ -195,6 +231,20 @@ func (o *fetchOpts) buildQuery() (*url.Values, error) {
 		q.Set("end", strconv.FormatInt(o.End, 10))
 	}
 
+	q.Set("max", strconv.Itoa(o.maxCount()))
+
+	q.Set("reverse", strconv.FormatBool(o.Reverse))
+	q.Set("include_meta", strconv.FormatBool(o.pubnub.includeMeta(o.WithMeta)))
+	q.Set("include_message_type", strconv.FormatBool(o.WithMessageType))
+	q.Set("include_uuid", strconv.FormatBool(o.WithUUID))
+
+	SetQueryParam(q, o.QueryParam)
+
+	return q, nil
+}
+
+// maxCount returns the number of messages per channel requested, capped by the server limits.
+func (o *fetchOpts) maxCount() int {
 	maxCount := maxCountFetch
 
 	if o.WithMessageActions {
@@ -206,19 +256,9 @@ func (o *fetchOpts) buildQuery() (*url.Values, error) {
 	}
 
 	if o.Count > 0 && o.Count <= maxCount {
-		q.Set("max", strconv.Itoa(o.Count))
-	} else {
-		q.Set("max", strconv.Itoa(maxCount))
+		return o.Count
 	}
-
-	q.Set("reverse", strconv.FormatBool(o.Reverse))
-	q.Set("include_meta", strconv.FormatBool(o.WithMeta))
-	q.Set("include_message_type", strconv.FormatBool(o.WithMessageType))
-	q.Set("include_uuid", strconv.FormatBool(o.WithUUID))
-
-	SetQueryParam(q, o.QueryParam)
-
-	return q, nil
+	return maxCount
 }
 
 func (o *fetchOpts) operationType() OperationType {
@@ -278,24 +318,36 @@ func (o *fetchOpts) parseMessageActions(actions interface{}) map[string]PNHistor
 	return resp
 }
 
+func (o *fetchOpts) cryptoModule(channel string) crypto.CryptoModule {
+	if o.CryptoModule != nil {
+		return o.CryptoModule
+	}
+	return o.pubnub.channelCryptoModule(channel)
+}
+
 // {"status": 200, "error": false, "error_message": "", "channels": {"ch1":[{"message_type": "", "message": {"text": "hey"}, "timetoken": "15959610984115342", "meta": "", "uuid": "db9c5e39-7c95-40f5-8d71-125765b6f561"}]}}
-func (o *fetchOpts) fetchMessages(channels map[string]interface{}) map[string][]FetchResponseItem {
+func (o *fetchOpts) fetchMessages(channels map[string]interface{}) (map[string][]FetchResponseItem, map[string]fetchPageBounds) {
 	messages := make(map[string][]FetchResponseItem, len(channels))
+	bounds := make(map[string]fetchPageBounds, len(channels))
 
 	for channel, histResponseSliceMap := range channels {
 		if histResponseMap, ok2 := histResponseSliceMap.([]interface{}); ok2 {
 			o.pubnub.Config.Log.Printf("Channel:%s, count:%d\n", channel, len(histResponseMap))
 			items := make([]FetchResponseItem, len(histResponseMap))
+			rawMessages := make([]interface{}, len(histResponseMap))
 			count := 0
 
 			for _, val := range histResponseMap {
 				if histResponse, ok3 := val.(map[string]interface{}); ok3 {
-					msg, err := parseCipherInterface(histResponse["message"], o.pubnub.Config, o.pubnub.getCryptoModule())
+					msg, err := parseCipherInterface(histResponse["message"], o.pubnub.Config, o.cryptoModule(channel))
+					if _, isChunk := parseChunkEnvelope(msg); err == nil && !isChunk {
+						msg, err = decodeWithCodec(o.pubnub, msg, histResponse["meta"])
+					}
 
 					histItem := FetchResponseItem{
 						Message:   msg,
 						Timetoken: histResponse["timetoken"].(string),
-						Meta:      histResponse["meta"],
+						Meta:      requestedMeta(histResponse["meta"], o.WithMeta),
                         Error:     err,
 					}
 					if d, ok := histResponse["message_type"]; ok {
@@ -327,11 +379,16 @@ func (o *fetchOpts) fetchMessages(channels map[string]interface{}) map[string][]
 
 						if f.Name != "" && f.ID != "" {
 							histItem.File = f
+							histItem.Files = ParseFileList(filesPayload)
 							histItem.Message = m
 						}
 					}
 
 					items[count] = histItem
+					rawMessages[count] = histResponse["message"]
+					if tt, err := strconv.ParseInt(histItem.Timetoken, 10, 64); err == nil {
+						bounds[channel] = bounds[channel].add(tt)
+					}
 					o.pubnub.Config.Log.Printf("Channel:%s, count:%d %d\n", channel, count, len(items))
 					count++
 				} else {
@@ -339,14 +396,14 @@ func (o *fetchOpts) fetchMessages(channels map[string]interface{}) map[string][]
 					continue
 				}
 			}
-			messages[channel] = items
+			messages[channel] = o.reassembleFetchChunks(channel, items[:count], rawMessages[:count])
 			o.pubnub.Config.Log.Printf("Channel:%s, count:%d\n", channel, len(messages[channel]))
 		} else {
 			o.pubnub.Config.Log.Printf("histResponseSliceMap not an []interface %v\n", histResponseSliceMap)
 			continue
 		}
 	}
-	return messages
+	return messages, bounds
 }
 
 func newFetchResponse(jsonBytes []byte, o *fetchOpts,
@@ -368,11 +425,18 @@ func newFetchResponse(jsonBytes []byte, o *fetchOpts,
 		o.pubnub.Config.Log.Println(result["channels"])
 		if channels, ok1 := result["channels"].(map[string]interface{}); ok1 {
 			if channels != nil {
-				resp.Messages = o.fetchMessages(channels)
+				resp.Messages, resp.bounds = o.fetchMessages(channels)
 			} else {
 				o.pubnub.Config.Log.Printf("type assertion to map failed %v\n", result)
 			}
 		}
+		if more, ok := result["more"].(map[string]interface{}); ok {
+			resp.More.URL, _ = more["url"].(string)
+			resp.More.Start, _ = more["start"].(string)
+			if max, ok := more["max"].(float64); ok {
+				resp.More.Max = int(max)
+			}
+		}
 	} else {
 		o.pubnub.Config.Log.Printf("type assertion to map failed %v\n", value)
 	}
@@ -383,6 +447,36 @@ func newFetchResponse(jsonBytes []byte, o *fetchOpts,
 // FetchResponse is the response to Fetch request. It contains a map of type FetchResponseItem
 type FetchResponse struct {
 	Messages map[string][]FetchResponseItem
+	// More is set when IncludeMessageActions is true and there are more messages to fetch.
+	More PNFetchMore
+	// ChannelErrors holds the error of each channel whose request failed, set only by ExecuteBulk.
+	ChannelErrors map[string]error
+
+	bounds map[string]fetchPageBounds
+}
+
+// PNFetchMore is the struct used when the FetchResponse has more link
+type PNFetchMore struct {
+	URL   string `json:"url"`
+	Start string `json:"start"`
+	Max   int    `json:"max"`
+}
+
+// fetchPageBounds holds the number of raw items of a channel in a page and their timetoken range.
+type fetchPageBounds struct {
+	count    int
+	min, max int64
+}
+
+func (b fetchPageBounds) add(tt int64) fetchPageBounds {
+	if b.count == 0 || tt < b.min {
+		b.min = tt
+	}
+	if b.count == 0 || tt > b.max {
+		b.max = tt
+	}
+	b.count++
+	return b
 }
 
 // FetchResponseItem contains the message and the associated timetoken.
@@ -392,10 +486,17 @@ type FetchResponseItem struct {
 	Meta           interface{}                               `json:"meta"`
 	MessageActions map[string]PNHistoryMessageActionsTypeMap `json:"actions"`
 	File           PNFileDetails                             `json:"file"`
+	Files          []PNFileDetails                           `json:"files"`
 	Timetoken      string                                    `json:"timetoken"`
 	UUID           string                                    `json:"uuid"`
 	MessageType    int                                       `json:"message_type"`
     Error          error
+	// ChunkTimetokens are the timetokens of the chunks of a chunked message,
+	// by chunk index. The first one is Timetoken.
+	ChunkTimetokens []string `json:"-"`
+
+	// chunk is the chunk of an incomplete chunk set
+	chunk *chunkEnvelope
 }
 
 // PNHistoryMessageActionsTypeMap is the struct used in the Fetch request that includes Message Actions
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"testing"
 
+	"github.com/pubnub/go/v7/crypto"
 	h "github.com/pubnub/go/v7/tests/helpers"
 	"github.com/stretchr/testify/assert"
 )
@@ -607,3 +608,30 @@ func TestNewFetchResponseValueError(t *testing.T) {
 	_, _, err := newFetchResponse(jsonBytes, opts, StatusResponse{})
 	assert.Equal("pubnub/parsing: Error unmarshalling response: {s}", err.Error())
 }
+
+func TestFetchResponseCryptoModule(t *testing.T) {
+	assert := assert.New(t)
+
+	jsonString := []byte(`{"status": 200, "error": false, "error_message": "", "channels": {"test":[{"message":"Wi24KS4pcTzvyuGOHubiXg==","timetoken":"15229448184080121"}],"my-channel":[{"message":"Wi24KS4pcTzvyuGOHubiXg==","timetoken":"15229448086016618"}]}}`)
+	module, err := crypto.NewLegacyCryptoModule("enigma", false)
+	assert.Nil(err)
+
+	opts := initFetchOpts("")
+	opts.CryptoModule = module
+	resp, _, err := newFetchResponse(jsonString, opts, fakeResponseState)
+	assert.Nil(err)
+	assert.Equal("yay!", resp.Messages["test"][0].Message)
+	assert.Equal("yay!", resp.Messages["my-channel"][0].Message)
+
+	opts = initFetchOpts("")
+	opts.pubnub.Config.CryptoModuleResolver = func(channel string) crypto.CryptoModule {
+		if channel == "test" {
+			return module
+		}
+		return nil
+	}
+	resp, _, err = newFetchResponse(jsonString, opts, fakeResponseState)
+	assert.Nil(err)
+	assert.Equal("yay!", resp.Messages["test"][0].Message)
+	assert.Equal("Wi24KS4pcTzvyuGOHubiXg==", resp.Messages["my-channel"][0].Message)
+}
@@ -7,14 +7,18 @@ type PNPublishMessage struct {
 
 // PNFileInfoForPublish is the part of the message struct used in Publish File
 type PNFileInfoForPublish struct {
-	Name string `json:"name"`
-	ID   string `json:"id"`
+	Name        string                 `json:"name"`
+	ID          string                 `json:"id"`
+	ContentType string                 `json:"content_type,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // PNPublishFileMessage is the message struct used in Publish File
 type PNPublishFileMessage struct {
 	PNMessage *PNPublishMessage     `json:"message"`
 	PNFile    *PNFileInfoForPublish `json:"file"`
+	// PNFiles are all the files of a message sent for several files, PNFile being the first one.
+	PNFiles []*PNFileInfoForPublish `json:"files,omitempty"`
 }
 
 // PNFileInfo is the File Upload API struct returned on for each file.
@@ -48,12 +52,18 @@ type PNFileDetails struct {
 	Name string `json:"name"`
 	ID   string `json:"id"`
 	URL  string
+	// ContentType is the content type of the file, empty when not sent by the publisher.
+	ContentType string `json:"content_type"`
+	// Metadata is the custom metadata of the file, nil when not sent by the publisher.
+	Metadata map[string]interface{} `json:"metadata"`
 }
 
 // PNFileMessageAndDetails is used to store the file message and file info
 type PNFileMessageAndDetails struct {
 	PNMessage PNPublishMessage `json:"message"`
 	PNFile    PNFileDetails    `json:"file"`
+	// PNFiles are all the files of a message sent for several files, empty for a single file.
+	PNFiles []PNFileDetails `json:"files"`
 }
 
 // ParseFileInfo is a function extract file info and add to the struct PNFileMessageAndDetails
@@ -73,6 +83,7 @@ func ParseFileInfo(filesPayload map[string]interface{}) (PNFileDetails, PNPublis
 			if d, ok := data["name"]; ok {
 				resp.PNFile.Name = d.(string)
 			}
+			resp.PNFile.ContentType, resp.PNFile.Metadata = parseFileAttributes(data)
 		}
 	}
 	if m, ok := filesPayload["message"]; ok {
@@ -85,3 +96,31 @@ func ParseFileInfo(filesPayload map[string]interface{}) (PNFileDetails, PNPublis
 	}
 	return resp.PNFile, resp.PNMessage
 }
+
+// ParseFileList extracts the files of a message sent for several files, nil for a single file.
+func ParseFileList(filesPayload map[string]interface{}) []PNFileDetails {
+	list, ok := filesPayload["files"].([]interface{})
+	if !ok {
+		return nil
+	}
+	files := make([]PNFileDetails, 0, len(list))
+	for _, f := range list {
+		data, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		file := PNFileDetails{}
+		file.ID, _ = data["id"].(string)
+		file.Name, _ = data["name"].(string)
+		file.ContentType, file.Metadata = parseFileAttributes(data)
+		files = append(files, file)
+	}
+	return files
+}
+
+// parseFileAttributes extracts the content type and the custom metadata of a file of a message.
+func parseFileAttributes(data map[string]interface{}) (string, map[string]interface{}) {
+	contentType, _ := data["content_type"].(string)
+	metadata, _ := data["metadata"].(map[string]interface{})
+	return contentType, metadata
+}
@@ -26,3 +26,19 @@ func TestParseFileInfoNotNil(t *testing.T) {
 	assert.Equal(f.ID, "9076246e-5036-42af-b3a3-767b514c93c8")
 	assert.Equal(m.Text, "test file")
 }
+
+func TestParseFileInfoContentTypeAndMetadata(t *testing.T) {
+	assert := assert.New(t)
+	resp := make(map[string]interface{})
+	resp["message"] = map[string]interface{}{"text": "test file"}
+	resp["file"] = map[string]interface{}{"name": "photo.png", "id": "file-id", "content_type": "image/png", "metadata": map[string]interface{}{"width": float64(640)}}
+
+	f, _ := ParseFileInfo(resp)
+	assert.Equal("image/png", f.ContentType)
+	assert.Equal(map[string]interface{}{"width": float64(640)}, f.Metadata)
+
+	resp["file"] = map[string]interface{}{"name": "photo.png", "id": "file-id"}
+	f, _ = ParseFileInfo(resp)
+	assert.Equal("", f.ContentType)
+	assert.Nil(f.Metadata)
+}
@@ -1,11 +1,17 @@
 package pubnub
 
 import (
+	"bytes"
 	"fmt"
-	"github.com/pubnub/go/v7/crypto"
 	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
+	"strconv"
+
+	"github.com/pubnub/go/v7/crypto"
+	"github.com/pubnub/go/v7/pnerr"
 )
 
 var emptyDownloadFileResponse *PNDownloadFileResponse
@@ -69,64 +75,90 @@ func (b *downloadFileBuilder) Transport(tr http.RoundTripper) *downloadFileBuild
 	return b
 }
 
+// CryptoModule sets the cryptography module the file is decrypted with, overriding
+// CipherKey, the Config one and the one resolved for the channel.
+func (b *downloadFileBuilder) CryptoModule(cryptoModule crypto.CryptoModule) *downloadFileBuilder {
+	b.opts.CryptoModule = cryptoModule
+
+	return b
+}
+
+// Offset sets the byte of the file to start the download at, to resume a partial
+// download. It can't be used when the file is encrypted.
+func (b *downloadFileBuilder) Offset(offset int64) *downloadFileBuilder {
+	b.opts.Offset = offset
+
+	return b
+}
+
+// OnProgress sets a callback called with the number of bytes of the file downloaded,
+// including the Offset, and the size of the file, -1 when unknown.
+func (b *downloadFileBuilder) OnProgress(onProgress func(received, total int64)) *downloadFileBuilder {
+	b.opts.OnProgress = onProgress
+
+	return b
+}
+
+// ExpectedSize sets the size the stored file must have, as listed by ListFiles.
+// The download fails when the size differs.
+func (b *downloadFileBuilder) ExpectedSize(size int64) *downloadFileBuilder {
+	b.opts.ExpectedSize = size
+
+	return b
+}
+
+// ExpectedContentType sets the content type the stored file must have, the
+// parameters of the content type are ignored. The download fails when it differs.
+func (b *downloadFileBuilder) ExpectedContentType(contentType string) *downloadFileBuilder {
+	b.opts.ExpectedContentType = contentType
+
+	return b
+}
+
+// Execute runs the downloadFile request. The file is streamed from File, which
+// has to be read to the end or closed (when it is an io.Closer) to free the connection.
 func (b *downloadFileBuilder) Execute() (*PNDownloadFileResponse, StatusResponse, error) {
-	u, _ := buildURL(b.opts)
-	stat := StatusResponse{
-		AffectedChannels: []string{b.opts.Channel},
-		AuthKey:          b.opts.config().AuthKey,
-		Category:         PNUnknownCategory,
-		Operation:        PNGetFileURLOperation,
-		StatusCode:       200,
-		TLSEnabled:       b.opts.config().Secure,
-		Origin:           b.opts.config().Origin,
-		UUID:             b.opts.config().UUID,
-	}
-	b.opts.pubnub.Config.Log.Printf("u.RequestURI(): %s", u.RequestURI())
-	resp, err := b.opts.client().Get(u.RequestURI())
+	res, status, err := executeStreamRequest(b.opts)
+	status.AffectedChannels = []string{b.opts.Channel}
 	if err != nil {
-		b.opts.pubnub.Config.Log.Printf("err %s", err)
-		return nil, stat, err
-	}
-	if resp.StatusCode != 200 {
-		stat.StatusCode = resp.StatusCode
-		return nil, stat, err
+		return emptyDownloadFileResponse, status, err
 	}
 
-	var respDL *PNDownloadFileResponse
-	if b.opts.CipherKey == "" && b.opts.pubnub.getCryptoModule() == nil {
-		respDL = &PNDownloadFileResponse{
-			File: resp.Body,
-		}
-	} else {
-		var e error
-		cryptoModule := b.opts.pubnub.getCryptoModule()
-		if b.opts.CipherKey != "" {
-			cryptoModule, e = crypto.NewLegacyCryptoModule(b.opts.CipherKey, true)
-			if e != nil {
-				return nil, stat, e
-			}
-		}
+	return newPNDownloadFileStreamResponse(res, b.opts, status)
+}
 
-		r, e := cryptoModule.DecryptStream(resp.Body)
-		if e != nil {
-			return nil, stat, e
-		}
-		respDL = &PNDownloadFileResponse{
-			File: r,
-		}
+// DownloadTo runs the downloadFile request and writes the file to w, it returns
+// the number of bytes written.
+func (b *downloadFileBuilder) DownloadTo(w io.Writer) (int64, StatusResponse, error) {
+	resp, status, err := b.Execute()
+	if err != nil {
+		return 0, status, err
+	}
+	if closer, ok := resp.File.(io.Closer); ok {
+		defer closer.Close()
+	}
 
+	n, err := io.Copy(w, resp.File)
+	if err != nil {
+		b.opts.config().Log.Println("download file failed", err)
+		status.Error = err
 	}
 
-	return respDL, stat, nil
+	return n, status, err
 }
 
 type downloadFileOpts struct {
 	endpointOpts
-	Channel    string
-	CipherKey  string
-	ID         string
-	Name       string
-	QueryParam map[string]string
+	Channel             string
+	CipherKey           string
+	CryptoModule        crypto.CryptoModule
+	ID                  string
+	Name                string
+	Offset              int64
+	OnProgress          func(received, total int64)
+	ExpectedSize        int64
+	ExpectedContentType string
+	QueryParam          map[string]string
 
 	Transport http.RoundTripper
 }
@@ -147,9 +179,49 @@ func (o *downloadFileOpts) validate() error {
 		return newValidationError(o, StrMissingFileID)
 	}
 
+	if o.Offset < 0 {
+		return newValidationError(o, StrInvalidDownloadOffset)
+	}
+
+	if o.Offset > 0 && o.encrypted() {
+		return newValidationError(o, StrDownloadOffsetEncrypted)
+	}
+
 	return nil
 }
 
+func (o *downloadFileOpts) encrypted() bool {
+	return o.CryptoModule != nil || o.CipherKey != "" || o.pubnub.channelCryptoModule(o.Channel) != nil
+}
+
+func (o *downloadFileOpts) cryptoModule() (crypto.CryptoModule, error) {
+	if o.CryptoModule != nil {
+		return o.CryptoModule, nil
+	}
+	if o.CipherKey != "" {
+		return crypto.NewLegacyCryptoModule(o.CipherKey, true)
+	}
+	return o.pubnub.channelCryptoModule(o.Channel), nil
+}
+
+// client returns the client of the PubNub instance, with the Transport of the request when set.
+func (o *downloadFileOpts) client() *http.Client {
+	client := o.endpointOpts.client()
+	if o.Transport == nil {
+		return client
+	}
+	withTransport := *client
+	withTransport.Transport = o.Transport
+	return &withTransport
+}
+
+func (o *downloadFileOpts) requestHeaders() http.Header {
+	if o.Offset == 0 {
+		return nil
+	}
+	return http.Header{"Range": []string{fmt.Sprintf("bytes=%d-", o.Offset)}}
+}
+
 func (o *downloadFileOpts) buildPath() (string, error) {
 	return fmt.Sprintf(downloadFilePath,
 		o.pubnub.Config.SubscribeKey, o.Channel, o.ID, o.Name), nil
@@ -172,6 +244,10 @@ func (o *downloadFileOpts) operationType() OperationType {
 type PNDownloadFileResponse struct {
 	status int       `json:"status"`
 	File   io.Reader `json:"data"`
+	// Size is the size of the stored file, -1 when unknown.
+	Size int64
+	// ContentType is the content type of the stored file.
+	ContentType string
 }
 
 func newPNDownloadFileResponse(jsonBytes []byte, o *downloadFileOpts,
@@ -181,3 +257,121 @@ func newPNDownloadFileResponse(jsonBytes []byte, o *downloadFileOpts,
 
 	return resp, status, nil
 }
+
+func newPNDownloadFileStreamResponse(res *http.Response, o *downloadFileOpts,
+	status StatusResponse) (*PNDownloadFileResponse, StatusResponse, error) {
+
+	fail := func(msg, details string) (*PNDownloadFileResponse, StatusResponse, error) {
+		res.Body.Close()
+		e := pnerr.NewResponseParsingError(msg, ioutil.NopCloser(bytes.NewBufferString(details)), nil)
+		o.config().Log.Println(e.Error())
+		status.Error = e
+		return emptyDownloadFileResponse, status, e
+	}
+
+	size := res.ContentLength
+	if res.StatusCode == http.StatusPartialContent {
+		start, total, err := parseContentRange(res.Header.Get("Content-Range"))
+		if err != nil || start != o.Offset {
+			return fail(StrInvalidContentRange, res.Header.Get("Content-Range"))
+		}
+		size = total
+	} else if o.Offset > 0 {
+		// the range is ignored by the server, the downloaded part is skipped
+		if _, err := io.CopyN(ioutil.Discard, res.Body, o.Offset); err != nil {
+			return fail(StrFileSizeMismatch, fmt.Sprintf("offset %d is beyond the end of the file: %s", o.Offset, err))
+		}
+	}
+
+	if o.ExpectedSize > 0 {
+		if size >= 0 && size != o.ExpectedSize {
+			return fail(StrFileSizeMismatch, fmt.Sprintf("expected %d bytes, got %d", o.ExpectedSize, size))
+		}
+		size = o.ExpectedSize
+	}
+
+	contentType := res.Header.Get("Content-Type")
+	if o.ExpectedContentType != "" {
+		expected, _, _ := mime.ParseMediaType(o.ExpectedContentType)
+		got, _, _ := mime.ParseMediaType(contentType)
+		if expected != got {
+			return fail(StrFileContentTypeMismatch, fmt.Sprintf("expected %s, got %s", o.ExpectedContentType, contentType))
+		}
+	}
+
+	body := &downloadReader{
+		body:       res.Body,
+		received:   o.Offset,
+		total:      size,
+		onProgress: o.OnProgress,
+	}
+	resp := &PNDownloadFileResponse{
+		File:        body,
+		Size:        size,
+		ContentType: contentType,
+	}
+
+	if o.encrypted() {
+		cryptoModule, err := o.cryptoModule()
+		if err != nil {
+			res.Body.Close()
+			return emptyDownloadFileResponse, status, err
+		}
+		decrypted, err := cryptoModule.DecryptStream(body)
+		if err != nil {
+			res.Body.Close()
+			return emptyDownloadFileResponse, status, err
+		}
+		resp.File = &decryptedReader{Reader: decrypted, Closer: res.Body}
+	}
+
+	return resp, status, nil
+}
+
+// parseContentRange returns the first byte and the total size of a
+// `bytes start-end/total` content range, the size is -1 when unknown.
+func parseContentRange(contentRange string) (int64, int64, error) {
+	var start, end int64
+	var total string
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%s", &start, &end, &total); err != nil {
+		return 0, 0, err
+	}
+	if total == "*" {
+		return start, -1, nil
+	}
+	size, err := strconv.ParseInt(total, 10, 64)
+	return start, size, err
+}
+
+// downloadReader reports the bytes downloaded to the progress callback and
+// checks the size of the file once read.
+type downloadReader struct {
+	body       io.ReadCloser
+	received   int64
+	total      int64
+	onProgress func(received, total int64)
+}
+
+func (r *downloadReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if n > 0 {
+		r.received += int64(n)
+		if r.onProgress != nil {
+			r.onProgress(r.received, r.total)
+		}
+	}
+	if err == io.EOF && r.total >= 0 && r.received != r.total {
+		err = pnerr.NewResponseParsingError(StrFileSizeMismatch,
+			ioutil.NopCloser(bytes.NewBufferString(fmt.Sprintf("expected %d bytes, got %d", r.total, r.received))), io.ErrUnexpectedEOF)
+	}
+	return n, err
+}
+
+func (r *downloadReader) Close() error {
+	return r.body.Close()
+}
+
+type decryptedReader struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,199 @@
+package pubnub
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pubnub/go/v7/crypto"
+	"github.com/pubnub/go/v7/pnerr"
+	"github.com/stretchr/testify/assert"
+)
+
+// newDownloadTestPubNub serves the content from the storage the download URL
+// redirects to, honouring the Range header.
+func newDownloadTestPubNub(content []byte, contentType string, ranges bool, requests *[]*http.Request) *PubNub {
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		*requests = append(*requests, req)
+		if req.URL.Host != "files.example.com" {
+			resp := stubResponse(req, "")
+			resp.StatusCode = http.StatusTemporaryRedirect
+			resp.Header.Set("Location", "https://files.example.com/file-id/name.txt")
+			return resp, nil
+		}
+		resp := stubResponse(req, "")
+		resp.Header.Set("Content-Type", contentType)
+		body := content
+		if r := req.Header.Get("Range"); r != "" && ranges {
+			var start int
+			fmt.Sscanf(r, "bytes=%d-", &start)
+			body = content[start:]
+			resp.StatusCode = http.StatusPartialContent
+			resp.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(content)-1, len(content)))
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+		resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		return resp, nil
+	})})
+	return pn
+}
+
+func TestDownloadFileTo(t *testing.T) {
+	assert := assert.New(t)
+
+	content := bytes.Repeat([]byte("hello "), 10000)
+	requests := []*http.Request{}
+	pn := newDownloadTestPubNub(content, "text/plain; charset=utf-8", true, &requests)
+	defer pn.Destroy()
+
+	var received, total int64
+	var out bytes.Buffer
+	n, status, err := pn.DownloadFile().
+		Channel("ch").
+		ID("file-id").
+		Name("name.txt").
+		ExpectedSize(int64(len(content))).
+		ExpectedContentType("text/plain").
+		OnProgress(func(r, t int64) {
+			received, total = r, t
+		}).
+		DownloadTo(&out)
+
+	assert.Nil(err)
+	assert.Equal(int64(len(content)), n)
+	assert.Equal(content, out.Bytes())
+	assert.Equal(200, status.StatusCode)
+	assert.Equal([]string{"ch"}, status.AffectedChannels)
+	assert.Equal(int64(len(content)), received)
+	assert.Equal(int64(len(content)), total)
+	assert.Contains(requests[0].URL.Opaque, "/v1/files/demo/channels/ch/files/file-id/name.txt")
+	assert.Contains(requests[0].URL.RawQuery, "pnsdk=")
+}
+
+func TestDownloadFileResume(t *testing.T) {
+	assert := assert.New(t)
+
+	content := []byte("0123456789abcdefghij")
+	for _, ranges := range []bool{true, false} {
+		requests := []*http.Request{}
+		pn := newDownloadTestPubNub(content, "text/plain", ranges, &requests)
+
+		progress := []int64{}
+		var out bytes.Buffer
+		n, _, err := pn.DownloadFile().
+			Channel("ch").
+			ID("file-id").
+			Name("name.txt").
+			Offset(10).
+			OnProgress(func(r, t int64) {
+				progress = append(progress, r)
+				assert.Equal(int64(20), t)
+			}).
+			DownloadTo(&out)
+
+		assert.Nil(err)
+		assert.Equal(int64(10), n)
+		assert.Equal("abcdefghij", out.String())
+		assert.Equal(int64(20), progress[len(progress)-1])
+		assert.Equal("bytes=10-", requests[len(requests)-1].Header.Get("Range"))
+		pn.Destroy()
+	}
+}
+
+func TestDownloadFileEncrypted(t *testing.T) {
+	assert := assert.New(t)
+
+	cryptoModule, _ := crypto.NewLegacyCryptoModule("enigma", true)
+	encrypted, err := cryptoModule.EncryptStream(strings.NewReader("secret content"))
+	assert.Nil(err)
+	content, _ := ioutil.ReadAll(encrypted)
+
+	requests := []*http.Request{}
+	pn := newDownloadTestPubNub(content, "application/octet-stream", true, &requests)
+	defer pn.Destroy()
+
+	resp, _, err := pn.DownloadFile().Channel("ch").ID("file-id").Name("name.txt").CipherKey("enigma").Execute()
+	assert.Nil(err)
+	assert.Equal(int64(len(content)), resp.Size)
+	decrypted, err := ioutil.ReadAll(resp.File)
+	assert.Nil(err)
+	assert.Equal("secret content", string(decrypted))
+
+	_, _, err = pn.DownloadFile().Channel("ch").ID("file-id").Name("name.txt").CipherKey("enigma").Offset(10).Execute()
+	assert.Contains(err.Error(), StrDownloadOffsetEncrypted)
+}
+
+func TestDownloadFileChecks(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := []*http.Request{}
+	pn := newDownloadTestPubNub([]byte("content"), "image/png", true, &requests)
+	defer pn.Destroy()
+
+	_, _, err := pn.DownloadFile().Channel("ch").ID("file-id").Name("name.txt").ExpectedSize(10).Execute()
+	assert.Contains(err.Error(), StrFileSizeMismatch)
+
+	_, _, err = pn.DownloadFile().Channel("ch").ID("file-id").Name("name.txt").ExpectedContentType("text/plain").Execute()
+	assert.Contains(err.Error(), StrFileContentTypeMismatch)
+
+	_, _, err = pn.DownloadFile().Channel("ch").ID("file-id").Name("name.txt").Offset(-1).Execute()
+	assert.Contains(err.Error(), StrInvalidDownloadOffset)
+}
+
+func TestDownloadFileServerError(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp := stubResponse(req, `{"status":404,"error":{"message":"Not Found"}}`)
+		resp.StatusCode = 404
+		return resp, nil
+	})})
+	defer pn.Destroy()
+
+	resp, status, err := pn.DownloadFile().Channel("ch").ID("file-id").Name("name.txt").Execute()
+	assert.Nil(resp)
+	assert.Equal(404, status.StatusCode)
+	serverErr, ok := err.(*pnerr.ServerError)
+	if assert.True(ok, err) {
+		assert.Equal(404, serverErr.StatusCode)
+	}
+}
+
+func TestDownloadFileContextAndTransport(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, fmt.Errorf("client transport used")
+	})})
+	defer pn.Destroy()
+
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		resp := stubResponse(req, "content")
+		resp.ContentLength = -1
+		return resp, nil
+	})
+
+	var out bytes.Buffer
+	_, _, err := pn.DownloadFile().Channel("ch").ID("file-id").Name("name.txt").Transport(transport).DownloadTo(&out)
+	assert.Nil(err)
+	assert.Equal("content", out.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = pn.DownloadFileWithContext(ctx).Channel("ch").ID("file-id").Name("name.txt").Transport(transport).Execute()
+	_, ok := err.(*pnerr.ConnectionError)
+	assert.True(ok, err)
+	assert.Contains(err.Error(), context.Canceled.Error())
+}
@@ -0,0 +1,77 @@
+package pubnub
+
+// FilesIterator pages through the files of the channel of a ListFiles request,
+// following the `next` cursors.
+//
+//	it := pn.ListFiles().Channel("ch").Iterate(ctx)
+//	for it.Next() {
+//		fmt.Println(it.File().Name, it.File().Created)
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type FilesIterator struct {
+	pageIterator
+	opts    listFilesOpts
+	started bool
+	buffer  []PNFileInfo
+	current PNFileInfo
+}
+
+// Iterate returns an iterator paging through all the files of the channel,
+// Limit files per page starting at Next. The iteration stops when the context is done.
+func (b *listFilesBuilder) Iterate(ctx Context) *FilesIterator {
+	it := &FilesIterator{
+		pageIterator: newPageIterator(ctx, b.opts.endpointOpts),
+		opts:         *b.opts,
+	}
+	it.opts.ctx = it.ctx
+
+	if err := b.opts.validate(); err != nil {
+		it.err = err
+	} else if b.opts.Channel == "" {
+		it.err = newValidationError(b.opts, StrMissingChannel)
+	}
+
+	return it
+}
+
+// Next advances the iterator to the next file, fetching the next page when needed.
+// It returns false when all the files were returned, on error or when the context is done.
+func (it *FilesIterator) Next() bool {
+	if !it.next(func() int { return len(it.buffer) }, func() bool { return it.started && it.opts.Next == "" }, it.fetchPage) {
+		return false
+	}
+	it.current = it.buffer[0]
+	it.buffer = it.buffer[1:]
+
+	return true
+}
+
+// File returns the current file.
+func (it *FilesIterator) File() PNFileInfo {
+	return it.current
+}
+
+// Err returns the error which stopped the iteration, nil when all the files were returned.
+func (it *FilesIterator) Err() error {
+	return it.err
+}
+
+func (it *FilesIterator) fetchPage() {
+	opts := it.opts
+	resp, _, err := (&listFilesBuilder{opts: &opts}).Execute()
+	if err != nil {
+		it.fail(err)
+		return
+	}
+	it.started = true
+
+	it.buffer = append(it.buffer, resp.Data...)
+	if len(resp.Data) == 0 {
+		// an empty page ends the iteration even with a cursor, not to loop forever
+		it.opts.Next = ""
+		return
+	}
+	it.opts.Next = resp.Next
+}
@@ -0,0 +1,112 @@
+package pubnub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newFilesTestPubNub serves the files of the channels, limit files per page with
+// the index of the next file as cursor.
+func newFilesTestPubNub(files map[string][]PNFileInfo, requests *int) *PubNub {
+	pn := NewPubNub(NewDemoConfig())
+
+	var mu sync.Mutex
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		*requests++
+		mu.Unlock()
+
+		path := strings.TrimSuffix(req.URL.Opaque, "/files")
+		ch := path[strings.LastIndex(path, "/")+1:]
+		q := req.URL.Query()
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		from, _ := strconv.Atoi(q.Get("next"))
+
+		page := files[ch][from:]
+		next := ""
+		if len(page) > limit {
+			page = page[:limit]
+			next = strconv.Itoa(from + limit)
+		}
+		body, _ := json.Marshal(map[string]interface{}{"status": 200, "data": page, "count": len(page), "next": next})
+		return stubResponse(req, string(body)), nil
+	})})
+	return pn
+}
+
+func testFiles(count int) []PNFileInfo {
+	files := []PNFileInfo{}
+	for i := 0; i < count; i++ {
+		files = append(files, PNFileInfo{ID: fmt.Sprintf("id-%d", i), Name: fmt.Sprintf("file-%d.txt", i)})
+	}
+	return files
+}
+
+func TestFilesIterator(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := 0
+	pn := newFilesTestPubNub(map[string][]PNFileInfo{"ch": testFiles(25)}, &requests)
+	defer pn.Destroy()
+
+	it := pn.ListFiles().Channel("ch").Limit(10).Iterate(context.Background())
+	ids := []string{}
+	for it.Next() {
+		ids = append(ids, it.File().ID)
+	}
+	assert.Nil(it.Err())
+	assert.Equal(25, len(ids))
+	assert.Equal("id-0", ids[0])
+	assert.Equal("id-24", ids[24])
+	assert.Equal(3, requests)
+}
+
+func TestFilesIteratorEmpty(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := 0
+	pn := newFilesTestPubNub(map[string][]PNFileInfo{}, &requests)
+	defer pn.Destroy()
+
+	it := pn.ListFiles().Channel("ch").Iterate(context.Background())
+	assert.False(it.Next())
+	assert.Nil(it.Err())
+	assert.Equal(1, requests)
+}
+
+func TestFilesIteratorValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := 0
+	pn := newFilesTestPubNub(map[string][]PNFileInfo{}, &requests)
+	defer pn.Destroy()
+
+	it := pn.ListFiles().Iterate(context.Background())
+	assert.False(it.Next())
+	assert.Contains(it.Err().Error(), StrMissingChannel)
+	assert.Equal(0, requests)
+}
+
+func TestFilesIteratorContextCancelled(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := 0
+	pn := newFilesTestPubNub(map[string][]PNFileInfo{"ch": testFiles(25)}, &requests)
+	defer pn.Destroy()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it := pn.ListFiles().Channel("ch").Limit(10).Iterate(ctx)
+	assert.True(it.Next())
+	cancel()
+	assert.False(it.Next())
+	assert.Equal(context.Canceled, it.Err())
+	assert.Equal(1, requests)
+}
@@ -0,0 +1,306 @@
+package pubnub
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pubnub/go/v7/pnerr"
+)
+
+const (
+	defaultCleanFilesConcurrency = 5
+	// defaultFileMessageSearchWindow is how long after the creation of a file its message is looked for.
+	defaultFileMessageSearchWindow = 10 * time.Minute
+)
+
+const (
+	// FileExpiredByAge is the reason of the files older than MaxAge.
+	FileExpiredByAge = "age"
+	// FileExpiredByCount is the reason of the files beyond the MaxCount newest files.
+	FileExpiredByCount = "count"
+)
+
+// CleanFilesOptions configures CleanFiles. At least one of MaxAge and MaxCount must be set.
+type CleanFilesOptions struct {
+	Channels []string
+	// MaxAge deletes the files created more than MaxAge ago, no age limit when 0.
+	MaxAge time.Duration
+	// MaxCount keeps the MaxCount newest files of each channel, no count limit when 0.
+	MaxCount int
+	// NamePattern restricts the policy to the files whose name matches, all the files when nil.
+	// The files which don't match are neither deleted nor counted.
+	NamePattern *regexp.Regexp
+	// Concurrency is the max number of files deleted concurrently, 5 when 0.
+	Concurrency int
+	// RateLimit is the max number of file deletions started per second, no limit when 0.
+	RateLimit int
+	// DeleteMessage deletes the message of each deleted file with DeleteMessages.
+	DeleteMessage bool
+	// MessageSearchWindow is how long after the creation of a file its message is
+	// looked for in the history, 10 minutes when 0.
+	MessageSearchWindow time.Duration
+	// Tombstone returns the message published on the channel for each deleted file, none when nil.
+	Tombstone func(channel string, file PNFileInfo) interface{}
+	// DryRun selects the files without deleting them.
+	DryRun bool
+	// Output receives a tab separated line per selected file: channel, ID, name,
+	// creation date, reason and outcome.
+	Output io.Writer
+}
+
+// PNCleanedFile is a file selected by the retention policy.
+type PNCleanedFile struct {
+	Channel string
+	File    PNFileInfo
+	// Reason is FileExpiredByAge or FileExpiredByCount.
+	Reason  string
+	Deleted bool
+	// MessageTimetoken is the timetoken of the file message, 0 when not looked for or not found.
+	MessageTimetoken   int64
+	MessageDeleted     bool
+	TombstoneTimetoken int64
+	// Error is the error which stopped the cleanup of the file.
+	Error error
+}
+
+// CleanFilesResult is the outcome of CleanFiles.
+type CleanFilesResult struct {
+	// Listed is the number of files listed.
+	Listed int
+	// Files are the files selected, by channel from the newest to the oldest.
+	Files []PNCleanedFile
+}
+
+// CleanFiles applies a retention policy to the files of the channels. The files
+// are listed with ListFiles, the expired ones are deleted with DeleteFile, with
+// their message and a tombstone message when configured. The files are deleted
+// concurrently, the files whose cleanup failed report their error, the error is
+// returned only when the options are invalid, a listing failed or every cleanup failed.
+func (pn *PubNub) CleanFiles(ctx Context, opts CleanFilesOptions) (CleanFilesResult, error) {
+	result := CleanFilesResult{Files: []PNCleanedFile{}}
+
+	if len(opts.Channels) == 0 {
+		return result, pnerr.NewValidationError("CleanFiles", StrMissingChannel)
+	}
+	if opts.MaxAge <= 0 && opts.MaxCount <= 0 {
+		return result, pnerr.NewValidationError("CleanFiles", "Missing MaxAge or MaxCount")
+	}
+	if !opts.DryRun && opts.DeleteMessage && pn.Config.SecretKey == "" {
+		return result, pnerr.NewValidationError("CleanFiles", StrMissingSecretKey)
+	}
+	if ctx == nil {
+		ctx = pn.ctx
+	}
+
+	now := time.Now()
+	for _, ch := range opts.Channels {
+		files, err := pn.expiredFiles(ctx, ch, opts, now, &result)
+		if err != nil {
+			return result, err
+		}
+		result.Files = append(result.Files, files...)
+	}
+
+	var err error
+	if !opts.DryRun && len(result.Files) > 0 {
+		err = pn.deleteExpiredFiles(ctx, opts, result.Files)
+	}
+	if opts.Output != nil {
+		writeCleanedFiles(opts.Output, result.Files, opts.DryRun)
+	}
+
+	return result, err
+}
+
+// expiredFiles lists the files of the channel and returns the ones selected by the policy.
+func (pn *PubNub) expiredFiles(ctx Context, channel string, opts CleanFilesOptions, now time.Time, result *CleanFilesResult) ([]PNCleanedFile, error) {
+	type listedFile struct {
+		info    PNFileInfo
+		created time.Time
+	}
+	files := []listedFile{}
+
+	it := pn.ListFilesWithContext(ctx).Channel(channel).Iterate(ctx)
+	for it.Next() {
+		result.Listed++
+		file := it.File()
+		if opts.NamePattern != nil && !opts.NamePattern.MatchString(file.Name) {
+			continue
+		}
+		// the files with an unknown creation date are never expired and sorted as the newest
+		created, _ := time.Parse(time.RFC3339, file.Created)
+		files = append(files, listedFile{info: file, created: created})
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].created.IsZero() || files[j].created.IsZero() {
+			return files[i].created.IsZero() && !files[j].created.IsZero()
+		}
+		return files[i].created.After(files[j].created)
+	})
+
+	expired := []PNCleanedFile{}
+	for i, file := range files {
+		reason := ""
+		if opts.MaxCount > 0 && i >= opts.MaxCount {
+			reason = FileExpiredByCount
+		} else if opts.MaxAge > 0 && !file.created.IsZero() && now.Sub(file.created) > opts.MaxAge {
+			reason = FileExpiredByAge
+		}
+		if reason != "" {
+			expired = append(expired, PNCleanedFile{Channel: channel, File: file.info, Reason: reason})
+		}
+	}
+
+	return expired, nil
+}
+
+// deleteExpiredFiles cleans the files up concurrently, the outcome is set on each file.
+func (pn *PubNub) deleteExpiredFiles(ctx Context, opts CleanFilesOptions, files []PNCleanedFile) error {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultCleanFilesConcurrency
+	}
+
+	var ticks <-chan time.Time
+	if opts.RateLimit > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(opts.RateLimit))
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	for i := 0; i < concurrency && i < len(files); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				pn.cleanFile(ctx, opts, &files[j], ticks)
+				if files[j].Error != nil {
+					pn.Config.Log.Println("file cleanup failed", files[j].Channel, files[j].File.ID, files[j].Error)
+				}
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed := 0
+	var lastErr error
+	for _, file := range files {
+		if file.Error != nil {
+			failed++
+			lastErr = file.Error
+		}
+	}
+	if failed == len(files) {
+		return lastErr
+	}
+
+	return nil
+}
+
+// cleanFile deletes the file, its message and publishes the tombstone, after the next tick when rate limited.
+func (pn *PubNub) cleanFile(ctx Context, opts CleanFilesOptions, file *PNCleanedFile, ticks <-chan time.Time) {
+	if ticks != nil {
+		select {
+		case <-ctx.Done():
+			file.Error = ctx.Err()
+			return
+		case <-ticks:
+		}
+	}
+
+	if opts.DeleteMessage {
+		file.MessageTimetoken, file.Error = pn.findFileMessage(ctx, file.Channel, file.File, opts.MessageSearchWindow)
+		if file.Error != nil {
+			return
+		}
+	}
+
+	_, _, file.Error = pn.DeleteFileWithContext(ctx).
+		Channel(file.Channel).
+		ID(file.File.ID).
+		Name(file.File.Name).
+		Execute()
+	if file.Error != nil {
+		return
+	}
+	file.Deleted = true
+
+	if file.MessageTimetoken != 0 {
+		_, _, file.Error = pn.DeleteMessagesWithContext(ctx).
+			Channel(file.Channel).
+			Start(file.MessageTimetoken - 1).
+			End(file.MessageTimetoken).
+			Execute()
+		if file.Error != nil {
+			return
+		}
+		file.MessageDeleted = true
+	}
+
+	if opts.Tombstone != nil {
+		resp, _, err := pn.PublishWithContext(ctx).
+			Channel(file.Channel).
+			Message(opts.Tombstone(file.Channel, file.File)).
+			Execute()
+		if err != nil {
+			file.Error = err
+			return
+		}
+		file.TombstoneTimetoken = resp.Timestamp
+	}
+}
+
+// findFileMessage returns the timetoken of the message of the file, looked for
+// in the history from a minute before its creation to window after, 0 when not found.
+func (pn *PubNub) findFileMessage(ctx Context, channel string, file PNFileInfo, window time.Duration) (int64, error) {
+	created, err := time.Parse(time.RFC3339, file.Created)
+	if err != nil {
+		return 0, nil
+	}
+	if window <= 0 {
+		window = defaultFileMessageSearchWindow
+	}
+
+	it := pn.FetchWithContext(ctx).
+		Channels([]string{channel}).
+		WithStart(TimetokenFromTime(created.Add(window))).
+		WithEnd(TimetokenFromTime(created.Add(-time.Minute))).
+		Iterate(ctx)
+	for it.Next() {
+		if it.Item().File.ID == file.ID {
+			return strconv.ParseInt(it.Item().Timetoken, 10, 64)
+		}
+	}
+
+	return 0, it.Err()
+}
+
+func writeCleanedFiles(w io.Writer, files []PNCleanedFile, dryRun bool) {
+	for _, file := range files {
+		outcome := "deleted"
+		switch {
+		case dryRun:
+			outcome = "dry run"
+		case file.Error != nil:
+			outcome = "error: " + file.Error.Error()
+		case !file.Deleted:
+			outcome = "not deleted"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", file.Channel, file.File.ID, file.File.Name, file.File.Created, file.Reason, outcome)
+	}
+}
@@ -0,0 +1,229 @@
+package pubnub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// filesRetentionServer stubs the files, the file messages and the requests
+// deleting them.
+type filesRetentionServer struct {
+	sync.Mutex
+	files map[string][]PNFileInfo
+	// messages holds the timetoken of the message of each file ID
+	messages        map[string]int64
+	deletedFiles    []string
+	deletedMessages []string
+	published       []string
+	failDelete      map[string]bool
+}
+
+func (s *filesRetentionServer) newPubNub() *PubNub {
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		s.Lock()
+		defer s.Unlock()
+
+		path := req.URL.Opaque
+		q := req.URL.Query()
+		switch {
+		case strings.HasPrefix(path, "//ps.pndsn.com/v1/files/") && req.Method == "DELETE":
+			parts := strings.Split(path, "/")
+			id := parts[len(parts)-2]
+			if s.failDelete[id] {
+				resp := stubResponse(req, `{"status":500}`)
+				resp.StatusCode = 500
+				return resp, nil
+			}
+			s.deletedFiles = append(s.deletedFiles, id)
+			return stubResponse(req, `{"status":200}`), nil
+		case strings.HasPrefix(path, "//ps.pndsn.com/v1/files/"):
+			ch := strings.Split(path, "/")[7]
+			body, _ := json.Marshal(map[string]interface{}{"status": 200, "data": s.files[ch], "next": ""})
+			return stubResponse(req, string(body)), nil
+		case strings.HasPrefix(path, "//ps.pndsn.com/v3/history/") && req.Method == "DELETE":
+			s.deletedMessages = append(s.deletedMessages, q.Get("start")+"-"+q.Get("end"))
+			return stubResponse(req, `{"status":200,"error":false,"error_message":""}`), nil
+		case strings.HasPrefix(path, "//ps.pndsn.com/v3/history/"):
+			ch := path[strings.LastIndex(path, "/")+1:]
+			start, _ := strconv.ParseInt(q.Get("start"), 10, 64)
+			end, _ := strconv.ParseInt(q.Get("end"), 10, 64)
+			items := []interface{}{}
+			for _, file := range s.files[ch] {
+				tt, ok := s.messages[file.ID]
+				if ok && tt < start && tt >= end {
+					items = append(items, map[string]interface{}{
+						"message":      map[string]interface{}{"message": map[string]interface{}{"text": "file"}, "file": map[string]interface{}{"id": file.ID, "name": file.Name}},
+						"timetoken":    strconv.FormatInt(tt, 10),
+						"message_type": 4,
+					})
+				}
+			}
+			body, _ := json.Marshal(map[string]interface{}{"status": 200, "channels": map[string]interface{}{ch: items}})
+			return stubResponse(req, string(body)), nil
+		default:
+			parts := strings.Split(path, "/")
+			s.published = append(s.published, parts[len(parts)-1])
+			return stubResponse(req, `[1,"Sent","16000000000000000"]`), nil
+		}
+	})})
+	return pn
+}
+
+func retentionTestFile(id, name string, age time.Duration) PNFileInfo {
+	return PNFileInfo{ID: id, Name: name, Created: time.Now().Add(-age).UTC().Format(time.RFC3339)}
+}
+
+func cleanedIDs(files []PNCleanedFile) []string {
+	ids := []string{}
+	for _, file := range files {
+		ids = append(ids, file.File.ID)
+	}
+	return ids
+}
+
+func TestCleanFilesByAgeAndPattern(t *testing.T) {
+	assert := assert.New(t)
+
+	day := 24 * time.Hour
+	server := &filesRetentionServer{files: map[string][]PNFileInfo{
+		"ch": {
+			retentionTestFile("new", "new.log", day),
+			retentionTestFile("old", "old.log", 10*day),
+			retentionTestFile("old-image", "old.png", 10*day),
+			{ID: "unknown", Name: "unknown.log", Created: "yesterday"},
+		},
+	}}
+	pn := server.newPubNub()
+	defer pn.Destroy()
+
+	var out bytes.Buffer
+	result, err := pn.CleanFiles(context.Background(), CleanFilesOptions{
+		Channels:    []string{"ch"},
+		MaxAge:      7 * day,
+		NamePattern: regexp.MustCompile(`\.log$`),
+		DryRun:      true,
+		Output:      &out,
+	})
+	assert.Nil(err)
+	assert.Equal(4, result.Listed)
+	assert.Equal([]string{"old"}, cleanedIDs(result.Files))
+	assert.Equal(FileExpiredByAge, result.Files[0].Reason)
+	assert.False(result.Files[0].Deleted)
+	assert.Empty(server.deletedFiles)
+	assert.Equal(fmt.Sprintf("ch\told\told.log\t%s\tage\tdry run\n", server.files["ch"][1].Created), out.String())
+
+	result, err = pn.CleanFiles(context.Background(), CleanFilesOptions{
+		Channels:    []string{"ch"},
+		MaxAge:      7 * day,
+		NamePattern: regexp.MustCompile(`\.log$`),
+	})
+	assert.Nil(err)
+	assert.True(result.Files[0].Deleted)
+	assert.Equal([]string{"old"}, server.deletedFiles)
+}
+
+func TestCleanFilesByCount(t *testing.T) {
+	assert := assert.New(t)
+
+	files := []PNFileInfo{}
+	for i := 0; i < 10; i++ {
+		files = append(files, retentionTestFile(fmt.Sprintf("f%d", i), "f.txt", time.Duration(i)*time.Hour))
+	}
+	server := &filesRetentionServer{files: map[string][]PNFileInfo{"a": files, "b": files[:2]}}
+	pn := server.newPubNub()
+	defer pn.Destroy()
+
+	result, err := pn.CleanFiles(context.Background(), CleanFilesOptions{
+		Channels:    []string{"a", "b"},
+		MaxCount:    3,
+		Concurrency: 3,
+		RateLimit:   100,
+	})
+	assert.Nil(err)
+	assert.Equal(12, result.Listed)
+	assert.Equal([]string{"f3", "f4", "f5", "f6", "f7", "f8", "f9"}, cleanedIDs(result.Files))
+	for _, file := range result.Files {
+		assert.Equal("a", file.Channel)
+		assert.Equal(FileExpiredByCount, file.Reason)
+		assert.True(file.Deleted)
+	}
+	sort.Strings(server.deletedFiles)
+	assert.Equal([]string{"f3", "f4", "f5", "f6", "f7", "f8", "f9"}, server.deletedFiles)
+}
+
+func TestCleanFilesDeletesMessagesAndPublishesTombstones(t *testing.T) {
+	assert := assert.New(t)
+
+	old := retentionTestFile("old", "old.txt", 48*time.Hour)
+	created, _ := time.Parse(time.RFC3339, old.Created)
+	messageTimetoken := TimetokenFromTime(created.Add(time.Second)).Int64()
+	server := &filesRetentionServer{
+		files:    map[string][]PNFileInfo{"ch": {old}},
+		messages: map[string]int64{"old": messageTimetoken},
+	}
+	pn := server.newPubNub()
+	defer pn.Destroy()
+
+	result, err := pn.CleanFiles(context.Background(), CleanFilesOptions{
+		Channels:      []string{"ch"},
+		MaxAge:        24 * time.Hour,
+		DeleteMessage: true,
+		Tombstone: func(channel string, file PNFileInfo) interface{} {
+			return map[string]string{"deleted": file.ID}
+		},
+	})
+	assert.Nil(err)
+	file := result.Files[0]
+	assert.True(file.Deleted)
+	assert.Equal(messageTimetoken, file.MessageTimetoken)
+	assert.True(file.MessageDeleted)
+	assert.Equal(int64(16000000000000000), file.TombstoneTimetoken)
+	assert.Equal([]string{fmt.Sprintf("%d-%d", messageTimetoken-1, messageTimetoken)}, server.deletedMessages)
+	assert.Equal([]string{"%7B%22deleted%22%3A%22old%22%7D"}, server.published)
+}
+
+func TestCleanFilesErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	server := &filesRetentionServer{
+		files: map[string][]PNFileInfo{"ch": {
+			retentionTestFile("a", "a.txt", 48*time.Hour),
+			retentionTestFile("b", "b.txt", 48*time.Hour),
+		}},
+		failDelete: map[string]bool{"a": true},
+	}
+	pn := server.newPubNub()
+	defer pn.Destroy()
+
+	_, err := pn.CleanFiles(context.Background(), CleanFilesOptions{Channels: []string{"ch"}})
+	assert.Contains(err.Error(), "Missing MaxAge or MaxCount")
+
+	result, err := pn.CleanFiles(context.Background(), CleanFilesOptions{Channels: []string{"ch"}, MaxAge: time.Hour})
+	assert.Nil(err)
+	for _, file := range result.Files {
+		if file.File.ID == "a" {
+			assert.NotNil(file.Error)
+			assert.False(file.Deleted)
+		} else {
+			assert.Nil(file.Error)
+			assert.True(file.Deleted)
+		}
+	}
+
+	server.failDelete["b"] = true
+	_, err = pn.CleanFiles(context.Background(), CleanFilesOptions{Channels: []string{"ch"}, MaxAge: time.Hour})
+	assert.NotNil(err)
+}
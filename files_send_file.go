@@ -1,14 +1,20 @@
 package pubnub
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 
+	"github.com/pubnub/go/v7/crypto"
 	"github.com/pubnub/go/v7/pnerr"
 )
 
@@ -60,6 +66,14 @@ func (b *sendFileBuilder) CipherKey(cipher string) *sendFileBuilder {
 	return b
 }
 
+// CryptoModule sets the cryptography module the file and its message are encrypted
+// with, overriding CipherKey, the Config one and the one resolved for the channel.
+func (b *sendFileBuilder) CryptoModule(cryptoModule crypto.CryptoModule) *sendFileBuilder {
+	b.opts.CryptoModule = cryptoModule
+
+	return b
+}
+
 func (b *sendFileBuilder) Channel(channel string) *sendFileBuilder {
 	b.opts.Channel = channel
 
@@ -78,12 +92,52 @@ func (b *sendFileBuilder) Message(message string) *sendFileBuilder {
 	return b
 }
 
+// ContentType sets the content type of the file. When empty it is found from the
+// extension of the name or sniffed from the first bytes of the file.
+func (b *sendFileBuilder) ContentType(contentType string) *sendFileBuilder {
+	b.opts.ContentType = contentType
+
+	return b
+}
+
+// FileMetadata sets custom metadata of the file, sent with the file in the file message.
+func (b *sendFileBuilder) FileMetadata(metadata map[string]interface{}) *sendFileBuilder {
+	b.opts.FileMetadata = metadata
+
+	return b
+}
+
 func (b *sendFileBuilder) File(f *os.File) *sendFileBuilder {
 	b.opts.File = f
 
 	return b
 }
 
+// Reader sets the content of the file to send, used when File is not set.
+func (b *sendFileBuilder) Reader(r io.Reader) *sendFileBuilder {
+	b.opts.Reader = r
+
+	return b
+}
+
+// Size sets the size of the content of Reader, 0 for an empty file. The upload
+// is sent chunked when the size is unknown, which some storages don't accept.
+func (b *sendFileBuilder) Size(size int64) *sendFileBuilder {
+	b.opts.Size = size
+	b.opts.setSize = true
+
+	return b
+}
+
+// OnProgress sets a callback called with the number of bytes of the file uploaded
+// and the size of the file, -1 when unknown. When the file is encrypted the
+// bytes and the size are the ones of the encrypted file.
+func (b *sendFileBuilder) OnProgress(onProgress func(sent, total int64)) *sendFileBuilder {
+	b.opts.OnProgress = onProgress
+
+	return b
+}
+
 // QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
 func (b *sendFileBuilder) QueryParam(queryParam map[string]string) *sendFileBuilder {
 	b.opts.QueryParam = queryParam
@@ -97,30 +151,147 @@ func (b *sendFileBuilder) Transport(tr http.RoundTripper) *sendFileBuilder {
 	return b
 }
 
-// Execute runs the sendFile request.
+// Execute runs the sendFile request. Each step is retried with backoff, when a
+// step still fails the error is a *SendFileError holding the state to resume from.
 func (b *sendFileBuilder) Execute() (*PNSendFileResponse, StatusResponse, error) {
-	rawJSON, status, err := executeRequest(b.opts)
+	o := b.opts
+	if err := o.validate(); err != nil {
+		o.config().Log.Println("PNUnknownCategory", err)
+		return emptySendFileResponse, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+	}
+
+	if o.pending != nil && o.pending.Step > SendFileStepGenerateUploadURL {
+		return o.resume(o.pending, StatusResponse{})
+	}
+
+	pending, status, err := o.generateUploadURL()
 	if err != nil {
 		return emptySendFileResponse, status, err
 	}
 
-	return newPNSendFileResponse(rawJSON, b.opts, status)
+	return o.resume(pending, status)
+}
+
+// generateUploadURL requests the file ID and the upload URL, with retries, and
+// returns the state of the send to resume from.
+func (o *sendFileOpts) generateUploadURL() (*PendingFileSend, StatusResponse, error) {
+	o.ContentType = o.detectContentType()
+
+	var rawJSON []byte
+	status, err := o.retryStep(o.config().FileSendRetryLimit, nil, func() (StatusResponse, error) {
+		var status StatusResponse
+		var err error
+		rawJSON, status, err = executeRequest(o)
+		return status, err
+	})
+	if err != nil {
+		return nil, status, &SendFileError{Step: SendFileStepGenerateUploadURL, Err: err}
+	}
+
+	return newPNSendFileResponse(rawJSON, o, status)
 }
 
 type sendFileOpts struct {
 	endpointOpts
 
-	Channel     string
-	Name        string
-	Message     string
-	File        *os.File
-	CipherKey   string
-	TTL         int
-	Meta        interface{}
-	ShouldStore bool
-	QueryParam  map[string]string
+	Channel      string
+	Name         string
+	Message      string
+	File         *os.File
+	Reader       io.Reader
+	Size         int64
+	OnProgress   func(sent, total int64)
+	ContentType  string
+	FileMetadata map[string]interface{}
+	CipherKey    string
+	CryptoModule crypto.CryptoModule
+	TTL          int
+	Meta         interface{}
+	ShouldStore  bool
+	QueryParam   map[string]string
 
 	Transport http.RoundTripper
+
+	// pending is the state of the resumed send
+	pending *PendingFileSend
+
+	// nil hacks
+	setSize bool
+}
+
+// fileCryptoModule returns the cryptography module of the file, nil when the
+// file is encrypted with the CipherKey.
+func (o *sendFileOpts) fileCryptoModule() crypto.CryptoModule {
+	if o.CryptoModule != nil {
+		return o.CryptoModule
+	}
+	if o.CipherKey != "" {
+		return nil
+	}
+	return o.pubnub.channelCryptoModule(o.Channel)
+}
+
+// detectContentType returns the ContentType, else the content type of the extension
+// of the name, else the one sniffed from the first bytes of the file. It is empty
+// when the file can't be peeked at, the upload sniffs it then.
+func (o *sendFileOpts) detectContentType() string {
+	if o.ContentType != "" {
+		return o.ContentType
+	}
+	if contentType := mime.TypeByExtension(filepath.Ext(o.Name)); contentType != "" {
+		return contentType
+	}
+	head, err := o.peekFile(512)
+	if err != nil {
+		o.config().Log.Println("send file: can't detect the content type:", err)
+		return ""
+	}
+	return http.DetectContentType(head)
+}
+
+// peekFile returns up to n first bytes of the file without consuming them, a
+// Reader which can't seek is wrapped in a buffered reader.
+func (o *sendFileOpts) peekFile(n int) ([]byte, error) {
+	head := make([]byte, n)
+	if o.File != nil {
+		offset, err := o.File.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		read, err := o.File.ReadAt(head, offset)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return head[:read], nil
+	}
+	if seeker, ok := o.Reader.(io.ReadSeeker); ok {
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		read, err := io.ReadFull(seeker, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return head[:read], nil
+	}
+	if o.Reader == nil {
+		return nil, errors.New(StrMissingFile)
+	}
+	if l, ok := o.Reader.(interface{ Len() int }); ok && !o.setSize {
+		o.Size = int64(l.Len())
+		o.setSize = true
+	}
+	buffered := bufio.NewReaderSize(o.Reader, n)
+	o.Reader = buffered
+	head, err := buffered.Peek(n)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return head, nil
 }
 
 func (o *sendFileOpts) validate() error {
@@ -135,6 +306,11 @@ func (o *sendFileOpts) validate() error {
 	if o.Name == "" {
 		return newValidationError(o, StrMissingFileName)
 	}
+
+	uploaded := o.pending != nil && o.pending.Step > SendFileStepUpload
+	if o.File == nil && o.Reader == nil && !uploaded {
+		return newValidationError(o, StrMissingFile)
+	}
 	return nil
 }
 
@@ -154,12 +330,14 @@ func (o *sendFileOpts) buildQuery() (*url.Values, error) {
 
 // PNSendFileBody is used to create the body of the request
 type PNSendFileBody struct {
-	Name string `json:"name"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type,omitempty"`
 }
 
 func (o *sendFileOpts) buildBody() ([]byte, error) {
 	b := &PNSendFileBody{
-		Name: o.Name,
+		Name:        o.Name,
+		ContentType: o.ContentType,
 	}
 	jsonEncBytes, errEnc := json.Marshal(b)
 
@@ -192,9 +370,8 @@ type PNSendFileResponse struct {
 	Data      PNFileData `json:"data"`
 }
 
-// TODO Add retry on publish failure
 func newPNSendFileResponse(jsonBytes []byte, o *sendFileOpts,
-	status StatusResponse) (*PNSendFileResponse, StatusResponse, error) {
+	status StatusResponse) (*PendingFileSend, StatusResponse, error) {
 
 	respForS3 := &PNSendFileResponseForS3{}
 
@@ -202,58 +379,8 @@ func newPNSendFileResponse(jsonBytes []byte, o *sendFileOpts,
 	if err != nil {
 		e := pnerr.NewResponseParsingError("Error unmarshalling response",
 			ioutil.NopCloser(bytes.NewBufferString(string(jsonBytes))), err)
-		return emptySendFileResponse, status, e
-	}
-	var s *sendFileToS3Builder
-	if o.context() != nil {
-		s = newSendFileToS3BuilderWithContext(o.pubnub, o.context())
-	} else {
-		s = newSendFileToS3Builder(o.pubnub)
-	}
-	_, s3ResponseStatus, errS3Response := s.File(o.File).CipherKey(o.CipherKey).FileUploadRequestData(respForS3.FileUploadRequest).Execute()
-	if s3ResponseStatus.StatusCode != 204 {
-		o.pubnub.Config.Log.Printf("s3ResponseStatus: %d", s3ResponseStatus.StatusCode)
-		return emptySendFileResponse, s3ResponseStatus, errS3Response
-	}
-
-	m := &PNPublishMessage{
-		Text: o.Message,
-	}
-
-	file := &PNFileInfoForPublish{
-		ID:   respForS3.Data.ID,
-		Name: o.Name,
-	}
-
-	message := PNPublishFileMessage{
-		PNFile:    file,
-		PNMessage: m,
-	}
-
-	sent := false
-	tryCount := 0
-	var timestamp int64
-	maxCount := o.config().FileMessagePublishRetryLimit
-	for !sent && tryCount < maxCount {
-		tryCount++
-		pubFileMessageResponse, pubFileResponseStatus, errPubFileResponse := o.pubnub.PublishFileMessage().TTL(o.TTL).Meta(o.Meta).ShouldStore(o.ShouldStore).Channel(o.Channel).Message(message).Execute()
-		if errPubFileResponse != nil {
-			if tryCount >= maxCount {
-				pubFileResponseStatus.AdditionalData = file
-				return emptySendFileResponse, pubFileResponseStatus, errPubFileResponse
-			}
-			continue
-		} else {
-			timestamp = pubFileMessageResponse.Timestamp
-			sent = true
-			break
-		}
+		return nil, status, e
 	}
-	resp := &PNSendFileResponse{}
-	d := PNFileData{}
-	d.ID = respForS3.Data.ID
-	resp.Data = d
-	resp.Timestamp = timestamp
 
-	return resp, status, nil
+	return o.newPendingFileSend(respForS3), status, nil
 }
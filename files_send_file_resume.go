@@ -0,0 +1,260 @@
+package pubnub
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pubnub/go/v7/pnerr"
+)
+
+// SendFileStep is a step of SendFile.
+type SendFileStep int
+
+const (
+	// SendFileStepGenerateUploadURL requests the file ID and the upload URL.
+	SendFileStepGenerateUploadURL SendFileStep = 1 + iota
+	// SendFileStepUpload uploads the file to the storage.
+	SendFileStepUpload
+	// SendFileStepPublish publishes the file message.
+	SendFileStepPublish
+)
+
+func (s SendFileStep) String() string {
+	switch s {
+	case SendFileStepGenerateUploadURL:
+		return "generate upload URL"
+	case SendFileStepUpload:
+		return "upload"
+	case SendFileStepPublish:
+		return "publish file message"
+	}
+	return fmt.Sprintf("step %d", int(s))
+}
+
+// PendingFileSend is the state of a SendFile which failed, it can be stored
+// (as JSON) and resumed later with ResumeSendFile. The cipher key is not kept.
+type PendingFileSend struct {
+	// Step is the step to resume from.
+	Step              SendFileStep           `json:"step"`
+	Channel           string                 `json:"channel"`
+	Name              string                 `json:"name"`
+	Message           string                 `json:"message"`
+	TTL               int                    `json:"ttl"`
+	Meta              interface{}            `json:"meta"`
+	ShouldStore       bool                   `json:"should_store"`
+	ContentType       string                 `json:"content_type"`
+	FileMetadata      map[string]interface{} `json:"file_metadata"`
+	FileID            string                 `json:"file_id"`
+	FileUploadRequest PNFileUploadRequest    `json:"file_upload_request"`
+}
+
+// SendFileError is the error returned when a step of SendFile failed after its retries.
+type SendFileError struct {
+	Step SendFileStep
+	// Pending is the state to pass to ResumeSendFile, nil when the upload URL
+	// couldn't be generated, the SendFile has to be run again.
+	Pending *PendingFileSend
+	Err     error
+}
+
+func (e *SendFileError) Error() string {
+	return fmt.Sprintf("send file: %s failed: %s", e.Step, e.Err)
+}
+
+// Unwrap returns the error of the failed step.
+func (e *SendFileError) Unwrap() error {
+	return e.Err
+}
+
+// newResumeSendFileBuilder returns a SendFile builder resuming the pending send.
+// File or Reader must be set again when the file is not uploaded yet.
+func newResumeSendFileBuilder(pubnub *PubNub, ctx Context, pending PendingFileSend) *sendFileBuilder {
+	b := newSendFileBuilderWithContext(pubnub, ctx)
+	b.Channel(pending.Channel).
+		Name(pending.Name).
+		Message(pending.Message).
+		TTL(pending.TTL).
+		Meta(pending.Meta).
+		ShouldStore(pending.ShouldStore).
+		ContentType(pending.ContentType).
+		FileMetadata(pending.FileMetadata)
+	b.opts.pending = &pending
+
+	return b
+}
+
+// newPendingFileSend returns the state of the send after the upload URL was generated.
+func (o *sendFileOpts) newPendingFileSend(respForS3 *PNSendFileResponseForS3) *PendingFileSend {
+	return &PendingFileSend{
+		Step:              SendFileStepUpload,
+		Channel:           o.Channel,
+		Name:              o.Name,
+		Message:           o.Message,
+		TTL:               o.TTL,
+		Meta:              o.Meta,
+		ShouldStore:       o.ShouldStore,
+		ContentType:       o.ContentType,
+		FileMetadata:      o.FileMetadata,
+		FileID:            respForS3.Data.ID,
+		FileUploadRequest: respForS3.FileUploadRequest,
+	}
+}
+
+// isRetryableFileSendError tells if a failed step may succeed when tried again.
+func isRetryableFileSendError(status StatusResponse, err error) bool {
+	if _, ok := err.(*pnerr.ValidationError); ok {
+		return false
+	}
+	code := status.StatusCode
+	return code < 400 || code >= 500 || code == 408 || code == 429
+}
+
+// retryStep runs the step up to tries times, waiting Config.FileSendRetryBackoff,
+// doubled after each try, between the tries. rewind is called before each retry,
+// the step isn't retried when it returns an error.
+func (o *sendFileOpts) retryStep(tries int, rewind func() error, step func() (StatusResponse, error)) (StatusResponse, error) {
+	if tries < 1 {
+		tries = 1
+	}
+	backoff := o.config().FileSendRetryBackoff
+	var status StatusResponse
+	var err error
+	for try := 1; ; try++ {
+		status, err = step()
+		if err == nil || try >= tries || !isRetryableFileSendError(status, err) {
+			return status, err
+		}
+		if rewind != nil {
+			if rewindErr := rewind(); rewindErr != nil {
+				o.config().Log.Println("send file: can't retry:", rewindErr)
+				return status, err
+			}
+		}
+		o.config().Log.Printf("send file: try %d of %d failed, retrying in %s: %s\n", try, tries, backoff, err)
+
+		var done <-chan struct{}
+		if o.context() != nil {
+			done = o.context().Done()
+		}
+		select {
+		case <-done:
+			return status, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// uploadRewinder returns a function seeking the file back to its current offset,
+// nil when the file can't be read again.
+func (o *sendFileOpts) uploadRewinder() func() error {
+	var seeker io.Seeker
+	if o.File != nil {
+		seeker = o.File
+	} else if s, ok := o.Reader.(io.Seeker); ok {
+		seeker = s
+	}
+	if seeker == nil {
+		return nil
+	}
+	offset, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil
+	}
+	return func() error {
+		_, err := seeker.Seek(offset, io.SeekStart)
+		return err
+	}
+}
+
+// upload uploads the file with the upload request of the pending send.
+func (o *sendFileOpts) upload(pending *PendingFileSend) (StatusResponse, error) {
+	rewind := o.uploadRewinder()
+	tries := o.config().FileSendRetryLimit
+	if rewind == nil {
+		tries = 1
+	}
+
+	return o.retryStep(tries, rewind, func() (StatusResponse, error) {
+		var s *sendFileToS3Builder
+		if o.context() != nil {
+			s = newSendFileToS3BuilderWithContext(o.pubnub, o.context())
+		} else {
+			s = newSendFileToS3Builder(o.pubnub)
+		}
+		s.File(o.File).Reader(o.Reader).Name(o.Name).ContentType(o.ContentType).OnProgress(o.OnProgress)
+		if o.setSize {
+			s.Size(o.Size)
+		}
+		_, status, err := s.CipherKey(o.CipherKey).CryptoModule(o.fileCryptoModule()).FileUploadRequestData(pending.FileUploadRequest).Execute()
+		if status.StatusCode != 204 {
+			o.pubnub.Config.Log.Printf("s3ResponseStatus: %d", status.StatusCode)
+			if err == nil {
+				err = fmt.Errorf("unexpected upload status %d", status.StatusCode)
+			}
+			return status, err
+		}
+		return status, nil
+	})
+}
+
+// publishFileMessage publishes the message of the uploaded file, it returns the publish timetoken.
+func (o *sendFileOpts) publishFileMessage(pending *PendingFileSend) (int64, StatusResponse, error) {
+	return o.publishMessage(PNPublishFileMessage{
+		PNFile: &PNFileInfoForPublish{
+			ID:          pending.FileID,
+			Name:        o.Name,
+			ContentType: pending.ContentType,
+			Metadata:    pending.FileMetadata,
+		},
+		PNMessage: &PNPublishMessage{
+			Text: o.Message,
+		},
+	})
+}
+
+// publishMessage publishes the file message with retries, it returns the publish timetoken.
+func (o *sendFileOpts) publishMessage(message PNPublishFileMessage) (int64, StatusResponse, error) {
+	var timestamp int64
+	status, err := o.retryStep(o.config().FileMessagePublishRetryLimit, nil, func() (StatusResponse, error) {
+		builder := o.pubnub.PublishFileMessage()
+		if o.context() != nil {
+			builder = o.pubnub.PublishFileMessageWithContext(o.context())
+		}
+		resp, status, err := builder.TTL(o.TTL).Meta(o.Meta).ShouldStore(o.ShouldStore).Channel(o.Channel).Message(message).CryptoModule(o.CryptoModule).Execute()
+		if err == nil {
+			timestamp = resp.Timestamp
+		}
+		return status, err
+	})
+	if err != nil {
+		status.AdditionalData = message.PNFile
+	}
+
+	return timestamp, status, err
+}
+
+// resume runs the steps of the pending send.
+func (o *sendFileOpts) resume(pending *PendingFileSend, status StatusResponse) (*PNSendFileResponse, StatusResponse, error) {
+	if pending.Step <= SendFileStepUpload {
+		pending.Step = SendFileStepUpload
+		uploadStatus, err := o.upload(pending)
+		if err != nil {
+			return emptySendFileResponse, uploadStatus, &SendFileError{Step: SendFileStepUpload, Pending: pending, Err: err}
+		}
+		pending.Step = SendFileStepPublish
+	}
+
+	timestamp, publishStatus, err := o.publishFileMessage(pending)
+	if err != nil {
+		return emptySendFileResponse, publishStatus, &SendFileError{Step: SendFileStepPublish, Pending: pending, Err: err}
+	}
+
+	resp := &PNSendFileResponse{
+		Timestamp: timestamp,
+		Data:      PNFileData{ID: pending.FileID},
+	}
+
+	return resp, status, nil
+}
@@ -0,0 +1,198 @@
+package pubnub
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sendFileSteps stubs the SendFile requests, the status codes of the uploads
+// and of the publishes are taken in order, 204 and 200 once they run out.
+type sendFileSteps struct {
+	sync.Mutex
+	uploadCodes  []int
+	publishCodes []int
+	uploads      [][]byte
+	publishes    int
+	generates    int
+}
+
+func (s *sendFileSteps) nextCode(codes *[]int, ok int) int {
+	if len(*codes) == 0 {
+		return ok
+	}
+	code := (*codes)[0]
+	*codes = (*codes)[1:]
+	return code
+}
+
+func (s *sendFileSteps) newPubNub(t *testing.T) *PubNub {
+	config := NewDemoConfig()
+	config.FileSendRetryBackoff = time.Millisecond
+	pn := NewPubNub(config)
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		s.Lock()
+		defer s.Unlock()
+		switch {
+		case strings.Contains(req.URL.Path, "generate-upload-url") || strings.Contains(req.URL.Opaque, "generate-upload-url"):
+			s.generates++
+			return stubResponse(req, `{"status":200,"data":{"id":"file-id","name":"name.txt"},"file_upload_request":{"url":"https://s3.example.com/upload","method":"POST","form_fields":[{"key":"key","value":"file-id/name.txt"}]}}`), nil
+		case req.URL.Host == "s3.example.com":
+			_, file, _ := readUpload(t, req)
+			s.uploads = append(s.uploads, file)
+			resp := stubResponse(req, "")
+			resp.StatusCode = s.nextCode(&s.uploadCodes, 204)
+			return resp, nil
+		default:
+			s.publishes++
+			resp := stubResponse(req, `[1,"Sent","15000000000000000"]`)
+			resp.StatusCode = s.nextCode(&s.publishCodes, 200)
+			return resp, nil
+		}
+	})})
+	return pn
+}
+
+func TestSendFileRetriesUpload(t *testing.T) {
+	assert := assert.New(t)
+
+	steps := &sendFileSteps{uploadCodes: []int{500, 503}}
+	pn := steps.newPubNub(t)
+	defer pn.Destroy()
+
+	resp, _, err := pn.SendFile().
+		Channel("ch").
+		Name("name.txt").
+		Reader(bytes.NewReader([]byte("content"))).
+		Execute()
+
+	assert.Nil(err)
+	assert.Equal("file-id", resp.Data.ID)
+	assert.Equal(int64(15000000000000000), resp.Timestamp)
+	assert.Equal(1, steps.generates)
+	assert.Equal([][]byte{[]byte("content"), []byte("content"), []byte("content")}, steps.uploads)
+	assert.Equal(1, steps.publishes)
+}
+
+func TestSendFileUploadFailed(t *testing.T) {
+	assert := assert.New(t)
+
+	steps := &sendFileSteps{uploadCodes: []int{500, 500, 500}}
+	pn := steps.newPubNub(t)
+	defer pn.Destroy()
+
+	_, _, err := pn.SendFile().
+		Channel("ch").
+		Name("name.txt").
+		Message("msg").
+		Reader(bytes.NewReader([]byte("content"))).
+		Execute()
+
+	sendErr, ok := err.(*SendFileError)
+	if !assert.True(ok, err) {
+		return
+	}
+	assert.Equal(SendFileStepUpload, sendErr.Step)
+	assert.Contains(sendErr.Error(), "send file: upload failed")
+	assert.Equal(3, len(steps.uploads))
+	assert.Equal(0, steps.publishes)
+	assert.Equal("file-id", sendErr.Pending.FileID)
+	assert.Equal("https://s3.example.com/upload", sendErr.Pending.FileUploadRequest.URL)
+
+	// the stored state resumes the send without generating a new upload URL
+	state, err := json.Marshal(sendErr.Pending)
+	assert.Nil(err)
+	var pending PendingFileSend
+	assert.Nil(json.Unmarshal(state, &pending))
+	assert.Equal(*sendErr.Pending, pending)
+
+	_, _, err = pn.ResumeSendFile(pending).Execute()
+	assert.Contains(err.Error(), StrMissingFile)
+
+	resp, _, err := pn.ResumeSendFile(pending).Reader(bytes.NewReader([]byte("content"))).Execute()
+	assert.Nil(err)
+	assert.Equal("file-id", resp.Data.ID)
+	assert.Equal(1, steps.generates)
+	assert.Equal(4, len(steps.uploads))
+	assert.Equal(1, steps.publishes)
+}
+
+func TestSendFileUploadNotRetriedWithoutSeek(t *testing.T) {
+	assert := assert.New(t)
+
+	steps := &sendFileSteps{uploadCodes: []int{500}}
+	pn := steps.newPubNub(t)
+	defer pn.Destroy()
+
+	_, _, err := pn.SendFile().
+		Channel("ch").
+		Name("name.txt").
+		Reader(strings.NewReader("content")).
+		Execute()
+	assert.Nil(err)
+	assert.Equal(2, len(steps.uploads))
+
+	steps.uploadCodes = []int{500}
+	_, _, err = pn.SendFile().
+		Channel("ch").
+		Name("name.txt").
+		Reader(struct{ *bytes.Buffer }{bytes.NewBufferString("content")}).
+		Execute()
+	sendErr, ok := err.(*SendFileError)
+	assert.True(ok, err)
+	assert.Equal(SendFileStepUpload, sendErr.Step)
+	assert.Equal(3, len(steps.uploads))
+}
+
+func TestSendFilePublishFailed(t *testing.T) {
+	assert := assert.New(t)
+
+	steps := &sendFileSteps{publishCodes: []int{500, 500, 500, 500, 500}}
+	pn := steps.newPubNub(t)
+	defer pn.Destroy()
+
+	_, status, err := pn.SendFile().
+		Channel("ch").
+		Name("name.txt").
+		Reader(bytes.NewReader([]byte("content"))).
+		Execute()
+
+	sendErr, ok := err.(*SendFileError)
+	if !assert.True(ok, err) {
+		return
+	}
+	assert.Equal(SendFileStepPublish, sendErr.Step)
+	assert.Equal(5, steps.publishes)
+	assert.Equal("file-id", status.AdditionalData.(*PNFileInfoForPublish).ID)
+
+	resp, _, err := pn.ResumeSendFile(*sendErr.Pending).Execute()
+	assert.Nil(err)
+	assert.Equal("file-id", resp.Data.ID)
+	assert.Equal(1, len(steps.uploads))
+	assert.Equal(6, steps.publishes)
+}
+
+func TestSendFileClientErrorNotRetried(t *testing.T) {
+	assert := assert.New(t)
+
+	steps := &sendFileSteps{uploadCodes: []int{403}}
+	pn := steps.newPubNub(t)
+	defer pn.Destroy()
+
+	_, _, err := pn.SendFile().
+		Channel("ch").
+		Name("name.txt").
+		Reader(bytes.NewReader([]byte("content"))).
+		Execute()
+
+	sendErr, ok := err.(*SendFileError)
+	assert.True(ok, err)
+	assert.Equal(SendFileStepUpload, sendErr.Step)
+	assert.Equal(1, len(steps.uploads))
+}
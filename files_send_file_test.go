@@ -1,9 +1,19 @@
 package pubnub
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
 	"testing"
 
+	"github.com/pubnub/go/v7/crypto"
 	h "github.com/pubnub/go/v7/tests/helpers"
 	"github.com/stretchr/testify/assert"
 )
@@ -66,3 +76,234 @@ func TestSendFileResponseValueError(t *testing.T) {
 	_, _, err := newPNSendFileResponse(jsonBytes, opts, StatusResponse{})
 	assert.Equal("pubnub/parsing: Error unmarshalling response: {s}", err.Error())
 }
+
+// newSendFileTestPubNub stubs the upload URL, S3 and the file message publish,
+// the S3 requests are passed to onUpload.
+func newSendFileTestPubNub(onUpload func(req *http.Request)) *PubNub {
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.Contains(req.URL.Path, "generate-upload-url") || strings.Contains(req.URL.Opaque, "generate-upload-url"):
+			return stubResponse(req, `{"status":200,"data":{"id":"file-id","name":"name.txt"},"file_upload_request":{"url":"https://s3.example.com/upload","method":"POST","form_fields":[{"key":"key","value":"file-id/name.txt"},{"key":"Content-Type","value":""}]}}`), nil
+		case req.URL.Host == "s3.example.com":
+			onUpload(req)
+			resp := stubResponse(req, "")
+			resp.StatusCode = 204
+			return resp, nil
+		default:
+			return stubResponse(req, `[1,"Sent","15000000000000000"]`), nil
+		}
+	})})
+	return pn
+}
+
+// readUpload reads the multipart upload and returns its fields and file content.
+func readUpload(t *testing.T, req *http.Request) (map[string]string, []byte, int64) {
+	_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	assert.Nil(t, err)
+	body, err := ioutil.ReadAll(req.Body)
+	assert.Nil(t, err)
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	fields := map[string]string{}
+	var file []byte
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		content, _ := ioutil.ReadAll(part)
+		if part.FormName() == "file" {
+			file = content
+			fields["filename"] = part.FileName()
+		} else {
+			fields[part.FormName()] = string(content)
+		}
+	}
+	return fields, file, int64(len(body))
+}
+
+func TestSendFileFromReader(t *testing.T) {
+	assert := assert.New(t)
+
+	content := bytes.Repeat([]byte("hello "), 10000)
+	var fields map[string]string
+	var uploaded []byte
+	var contentLength, bodyLength int64
+	pn := newSendFileTestPubNub(func(req *http.Request) {
+		contentLength = req.ContentLength
+		fields, uploaded, bodyLength = readUpload(t, req)
+	})
+	defer pn.Destroy()
+
+	progress := []int64{}
+	resp, _, err := pn.SendFile().
+		Channel("ch").
+		Name("name.txt").
+		Reader(bytes.NewReader(content)).
+		OnProgress(func(sent, total int64) {
+			assert.Equal(int64(len(content)), total)
+			progress = append(progress, sent)
+		}).
+		Execute()
+	assert.Nil(err)
+	assert.Equal("file-id", resp.Data.ID)
+	assert.Equal(content, uploaded)
+	assert.Equal("name.txt", fields["filename"])
+	assert.Equal("file-id/name.txt", fields["key"])
+	assert.Equal("text/plain; charset=utf-8", fields["Content-Type"])
+	assert.Equal(bodyLength, contentLength)
+	assert.Equal(int64(len(content)), progress[len(progress)-1])
+}
+
+func TestSendFileFromReaderEncrypted(t *testing.T) {
+	assert := assert.New(t)
+
+	content := bytes.Repeat([]byte{1, 2, 3}, 5000)
+	var uploaded []byte
+	var contentLength, bodyLength int64
+	pn := newSendFileTestPubNub(func(req *http.Request) {
+		contentLength = req.ContentLength
+		_, uploaded, bodyLength = readUpload(t, req)
+	})
+	defer pn.Destroy()
+
+	// the size of a reader without Len is unknown unless set
+	var sent, total int64
+	_, _, err := pn.SendFile().
+		Channel("ch").
+		Name("data.bin").
+		CipherKey("enigma").
+		Reader(io.MultiReader(bytes.NewReader(content))).
+		Size(int64(len(content))).
+		OnProgress(func(s, t int64) {
+			sent, total = s, t
+		}).
+		Execute()
+	assert.Nil(err)
+	assert.Equal(bodyLength, contentLength)
+	// the progress counts the bytes of the encrypted file
+	assert.Equal(int64(len(uploaded)), total)
+	assert.Equal(total, sent)
+
+	module, _ := crypto.NewLegacyCryptoModule("enigma", true)
+	decrypted, err := module.Decrypt(uploaded)
+	assert.Nil(err)
+	assert.Equal(content, decrypted)
+}
+
+func TestSendFileEmptyFile(t *testing.T) {
+	assert := assert.New(t)
+
+	var uploaded []byte
+	var contentLength, bodyLength int64
+	pn := newSendFileTestPubNub(func(req *http.Request) {
+		contentLength = req.ContentLength
+		_, uploaded, bodyLength = readUpload(t, req)
+	})
+	defer pn.Destroy()
+
+	_, _, err := pn.SendFile().
+		Channel("ch").
+		Name("empty.txt").
+		Reader(io.MultiReader(strings.NewReader(""))).
+		Size(0).
+		Execute()
+	assert.Nil(err)
+	assert.Empty(uploaded)
+	assert.Equal(bodyLength, contentLength)
+}
+
+func TestSendFileFromReaderUnknownSize(t *testing.T) {
+	assert := assert.New(t)
+
+	var uploaded []byte
+	var contentLength int64
+	pn := newSendFileTestPubNub(func(req *http.Request) {
+		contentLength = req.ContentLength
+		_, uploaded, _ = readUpload(t, req)
+	})
+	defer pn.Destroy()
+
+	total := int64(0)
+	_, _, err := pn.SendFile().
+		Channel("ch").
+		Name("name.txt").
+		Reader(io.MultiReader(strings.NewReader("streamed"))).
+		OnProgress(func(sent, size int64) {
+			total = size
+		}).
+		Execute()
+	assert.Nil(err)
+	assert.Equal([]byte("streamed"), uploaded)
+	assert.Equal(int64(-1), total)
+	assert.True(contentLength <= 0)
+
+	_, _, err = pn.SendFile().Channel("ch").Name("name.txt").Execute()
+	assert.Contains(err.Error(), StrMissingFile)
+}
+
+func TestSendFileContentTypeAndMetadata(t *testing.T) {
+	assert := assert.New(t)
+
+	pdf := "%PDF-1.4\n" + strings.Repeat("pdf content ", 100)
+	cases := []struct {
+		name        string
+		contentType string
+		content     string
+		// streamed sends the content from a reader which can't seek
+		streamed bool
+		expected string
+	}{
+		{"image.bin", "image/webp", "data", false, "image/webp"},
+		{"photo.png", "", "not really a png", false, "image/png"},
+		{"document", "", pdf, false, "application/pdf"},
+		{"streamed", "", pdf, true, "application/pdf"},
+	}
+
+	for _, c := range cases {
+		var generateBody PNSendFileBody
+		var fields map[string]string
+		var uploaded []byte
+		var published string
+		pn := NewPubNub(NewDemoConfig())
+		pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			switch {
+			case strings.Contains(req.URL.Opaque, "generate-upload-url"):
+				body, _ := ioutil.ReadAll(req.Body)
+				assert.Nil(json.Unmarshal(body, &generateBody))
+				return stubResponse(req, `{"status":200,"data":{"id":"file-id","name":"name"},"file_upload_request":{"url":"https://s3.example.com/upload","method":"POST","form_fields":[{"key":"key","value":"file-id/name"},{"key":"Content-Type","value":""}]}}`), nil
+			case req.URL.Host == "s3.example.com":
+				fields, uploaded, _ = readUpload(t, req)
+				resp := stubResponse(req, "")
+				resp.StatusCode = 204
+				return resp, nil
+			default:
+				parts := strings.Split(req.URL.Opaque, "/")
+				published, _ = url.PathUnescape(parts[len(parts)-1])
+				return stubResponse(req, `[1,"Sent","15000000000000000"]`), nil
+			}
+		})})
+
+		var reader io.Reader = strings.NewReader(c.content)
+		if c.streamed {
+			reader = io.MultiReader(reader)
+		}
+		_, _, err := pn.SendFile().
+			Channel("ch").
+			Name(c.name).
+			Reader(reader).
+			ContentType(c.contentType).
+			FileMetadata(map[string]interface{}{"width": 640}).
+			Execute()
+		assert.Nil(err, c.name)
+		assert.Equal(c.expected, generateBody.ContentType, c.name)
+		assert.Equal(c.expected, fields["Content-Type"], c.name)
+		assert.Equal(c.content, string(uploaded), c.name)
+
+		var message map[string]interface{}
+		assert.Nil(json.Unmarshal([]byte(published), &message), c.name)
+		file, _ := ParseFileInfo(message)
+		assert.Equal(c.expected, file.ContentType, c.name)
+		assert.Equal(map[string]interface{}{"width": float64(640)}, file.Metadata, c.name)
+	}
+}
@@ -1,9 +1,10 @@
 package pubnub
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
-	"github.com/pubnub/go/v7/crypto"
+	"errors"
 	"io"
 	"io/ioutil"
 	"mime/multipart"
@@ -11,6 +12,7 @@ import (
 	"net/url"
 	"os"
 
+	"github.com/pubnub/go/v7/crypto"
 	"github.com/pubnub/go/v7/pnerr"
 )
 
@@ -40,6 +42,20 @@ func (b *sendFileToS3Builder) CipherKey(cipherKey string) *sendFileToS3Builder {
 	return b
 }
 
+// ContentType sets the content type of the file, sniffed from its first bytes when empty.
+func (b *sendFileToS3Builder) ContentType(contentType string) *sendFileToS3Builder {
+	b.opts.ContentType = contentType
+
+	return b
+}
+
+// CryptoModule sets the cryptography module the file is encrypted with, used instead of CipherKey.
+func (b *sendFileToS3Builder) CryptoModule(cryptoModule crypto.CryptoModule) *sendFileToS3Builder {
+	b.opts.CryptoModule = cryptoModule
+
+	return b
+}
+
 func (b *sendFileToS3Builder) FileUploadRequestData(fileUploadRequestData PNFileUploadRequest) *sendFileToS3Builder {
 	b.opts.FileUploadRequestData = fileUploadRequestData
 
@@ -52,6 +68,38 @@ func (b *sendFileToS3Builder) File(f *os.File) *sendFileToS3Builder {
 	return b
 }
 
+// Reader sets the content of the file to upload, used when File is not set.
+func (b *sendFileToS3Builder) Reader(r io.Reader) *sendFileToS3Builder {
+	b.opts.Reader = r
+
+	return b
+}
+
+// Size sets the size of the content of Reader, 0 for an empty file. The upload
+// is sent chunked when the size is unknown, which some storages don't accept.
+func (b *sendFileToS3Builder) Size(size int64) *sendFileToS3Builder {
+	b.opts.Size = size
+	b.opts.setSize = true
+
+	return b
+}
+
+// Name sets the name of the uploaded file, the name of File by default.
+func (b *sendFileToS3Builder) Name(name string) *sendFileToS3Builder {
+	b.opts.Name = name
+
+	return b
+}
+
+// OnProgress sets a callback called with the number of bytes of the file sent
+// and the size of the file, -1 when unknown. When the file is encrypted the
+// bytes and the size are the ones of the encrypted file.
+func (b *sendFileToS3Builder) OnProgress(onProgress func(sent, total int64)) *sendFileToS3Builder {
+	b.opts.OnProgress = onProgress
+
+	return b
+}
+
 // QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
 func (b *sendFileToS3Builder) QueryParam(queryParam map[string]string) *sendFileToS3Builder {
 	b.opts.QueryParam = queryParam
@@ -79,10 +127,19 @@ type sendFileToS3Opts struct {
 	endpointOpts
 
 	File                  *os.File
+	Reader                io.Reader
+	Size                  int64
+	Name                  string
+	ContentType           string
+	OnProgress            func(sent, total int64)
 	FileUploadRequestData PNFileUploadRequest
 	QueryParam            map[string]string
 	CipherKey             string
+	CryptoModule          crypto.CryptoModule
 	Transport             http.RoundTripper
+
+	// nil hacks
+	setSize bool
 }
 
 func (o *sendFileToS3Opts) validate() error {
@@ -101,70 +158,143 @@ func (o *sendFileToS3Opts) buildQuery() (*url.Values, error) {
 	return &url.Values{}, nil
 }
 
-func (o *sendFileToS3Opts) buildBodyMultipartFileUpload() (bytes.Buffer, *multipart.Writer, int64, error) {
-
-	fileInfo, _ := o.File.Stat()
-	s := fileInfo.Size()
-	buffer := make([]byte, 512)
-	_, err := o.File.Read(buffer)
-	if err != nil {
-		return bytes.Buffer{}, nil, s, err
+// uploadSize returns the size of the file, -1 when unknown.
+func (o *sendFileToS3Opts) uploadSize() int64 {
+	if o.setSize && o.Size >= 0 {
+		return o.Size
+	}
+	if o.File != nil {
+		if fileInfo, err := o.File.Stat(); err == nil {
+			return fileInfo.Size()
+		}
 	}
-	o.File.Seek(0, 0)
-	contentType := http.DetectContentType(buffer)
+	if l, ok := o.Reader.(interface{ Len() int }); ok {
+		return int64(l.Len())
+	}
+	return -1
+}
 
-	var fileBody bytes.Buffer
-	writer := multipart.NewWriter(&fileBody)
+// progressWriter reports the bytes of the file written to the request body to
+// the progress callback. The body is a pipe, a write returns once the request
+// has read the bytes.
+type progressWriter struct {
+	writer     io.Writer
+	sent       int64
+	total      int64
+	onProgress func(sent, total int64)
+}
 
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	if n > 0 {
+		w.sent += int64(n)
+		w.onProgress(w.sent, w.total)
+	}
+	return n, err
+}
+
+// writeFormFields writes the form fields and the header of the file part.
+func (o *sendFileToS3Opts) writeFormFields(writer *multipart.Writer, contentType string) (io.Writer, error) {
 	for _, v := range o.FileUploadRequestData.FormFields {
-		o.pubnub.Config.Log.Printf("FormFields: Key: %s Value: %s\n", v.Key, v.Value)
 		if v.Key == "Content-Type" {
 			v.Value = contentType
 		}
-		_ = writer.WriteField(v.Key, v.Value)
+		if err := writer.WriteField(v.Key, v.Value); err != nil {
+			return nil, err
+		}
 	}
+	return writer.CreateFormFile("file", o.fileName())
+}
 
-	filePart, errFilePart := writer.CreateFormFile("file", fileInfo.Name())
+func (o *sendFileToS3Opts) fileName() string {
+	if o.Name != "" {
+		return o.Name
+	}
+	if o.File != nil {
+		if fileInfo, err := o.File.Stat(); err == nil {
+			return fileInfo.Name()
+		}
+	}
+	return "file"
+}
 
-	if errFilePart != nil {
-		o.pubnub.Config.Log.Printf("ERROR: writer CreateFormFile: %s\n", errFilePart.Error())
-		return bytes.Buffer{}, writer, s, errFilePart
+// buildBodyMultipartFileUpload returns the multipart form streamed through a
+// pipe, the file is read and encrypted while the request is sent. The length
+// of the body is -1 when the size of the file is unknown.
+func (o *sendFileToS3Opts) buildBodyMultipartFileUpload() (io.ReadCloser, string, int64, error) {
+	var source io.Reader = o.File
+	if o.File == nil {
+		source = o.Reader
+	}
+	if source == nil {
+		return nil, "", -1, errors.New(StrMissingFile)
 	}
+	size := o.uploadSize()
 
-	if o.CipherKey == "" && o.pubnub.getCryptoModule() == nil {
-		_, errIOCopy := io.Copy(filePart, o.File)
+	bufferedSource := bufio.NewReaderSize(source, 512)
+	head, err := bufferedSource.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, "", -1, err
+	}
+	contentType := o.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(head)
+	}
 
-		if errIOCopy != nil {
-			o.pubnub.Config.Log.Printf("ERROR: io Copy error: %s\n", errIOCopy.Error())
-			return bytes.Buffer{}, writer, s, errIOCopy
-		}
-	} else {
-		var e error
-		cryptoModule := o.pubnub.getCryptoModule()
-
-		if o.CipherKey != "" {
-			cryptoModule, e = crypto.NewLegacyCryptoModule(o.CipherKey, true)
-			if e != nil {
-				o.pubnub.Config.Log.Printf("ERROR: %s\n", e.Error())
-				return bytes.Buffer{}, writer, s, e
-			}
+	cryptoModule := o.pubnub.getCryptoModule()
+	if o.CryptoModule != nil {
+		cryptoModule = o.CryptoModule
+	} else if o.CipherKey != "" {
+		cryptoModule, err = crypto.NewLegacyCryptoModule(o.CipherKey, true)
+		if err != nil {
+			o.pubnub.Config.Log.Printf("ERROR: %s\n", err.Error())
+			return nil, "", -1, err
 		}
+	}
 
-		e = encryptStreamAndCopyTo(cryptoModule, o.File, filePart)
-		if e != nil {
-			o.pubnub.Config.Log.Printf("ERROR: %s\n", e.Error())
-			return bytes.Buffer{}, writer, s, e
+	var file io.Reader = bufferedSource
+	payloadSize := size
+	if cryptoModule != nil {
+		if file, payloadSize, err = crypto.EncryptStreamWithSize(cryptoModule, bufferedSource, size); err != nil {
+			o.pubnub.Config.Log.Printf("ERROR: %s\n", err.Error())
+			return nil, "", -1, err
 		}
 	}
 
-	errWriterClose := writer.Close()
-	if errWriterClose != nil {
-		o.pubnub.Config.Log.Printf("ERROR: Writer close: %s\n", errWriterClose.Error())
-		return bytes.Buffer{}, writer, s, errWriterClose
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	contentLength := int64(-1)
+	if payloadSize >= 0 {
+		// the form around the file is written to a buffer with the same boundary to get its length
+		var form bytes.Buffer
+		formWriter := multipart.NewWriter(&form)
+		formWriter.SetBoundary(writer.Boundary())
+		if _, err := o.writeFormFields(formWriter, contentType); err != nil {
+			return nil, "", -1, err
+		}
+		formWriter.Close()
+		contentLength = int64(form.Len()) + payloadSize
 	}
 
-	return fileBody, writer, s, nil
+	go func() {
+		filePart, err := o.writeFormFields(writer, contentType)
+		if err == nil && o.OnProgress != nil {
+			filePart = &progressWriter{writer: filePart, total: payloadSize, onProgress: o.OnProgress}
+		}
+		if err == nil {
+			_, err = io.Copy(filePart, file)
+		}
+		if err == nil {
+			err = writer.Close()
+		}
+		if err != nil {
+			o.pubnub.Config.Log.Printf("ERROR: file upload: %s\n", err.Error())
+		}
+		pw.CloseWithError(err)
+	}()
 
+	return pr, writer.FormDataContentType(), contentLength, nil
 }
 
 func (o *sendFileToS3Opts) httpMethod() string {
@@ -0,0 +1,421 @@
+package pubnub
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/pubnub/go/v7/crypto"
+	"github.com/pubnub/go/v7/pnerr"
+)
+
+const defaultSendFilesConcurrency = 3
+
+// maxPublishMessageSize is the max size of a published message, escaped in the
+// URL of the publish with the keys and the channel.
+const maxPublishMessageSize = 32 * 1024
+
+// fileIDPlaceholder stands for the IDs of the files, not known before their upload URL is generated.
+const fileIDPlaceholder = "00000000-0000-0000-0000-000000000000"
+
+// PNFileToSend is a file sent by SendFiles, File or Reader holds its content.
+type PNFileToSend struct {
+	Name   string
+	File   *os.File
+	Reader io.Reader
+	// Size is the size of the content of Reader, 0 when unknown. The upload is sent chunked when unknown.
+	Size int64
+	// ContentType is the content type of the file, found from its name or its content when empty.
+	ContentType string
+	// Metadata is the custom metadata of the file, sent in the file message.
+	Metadata map[string]interface{}
+	// Message is the text of the message of the file, the SendFiles Message when empty.
+	// It is not used when the files share a combined message.
+	Message string
+}
+
+type sendFilesBuilder struct {
+	opts *sendFilesOpts
+}
+
+func newSendFilesBuilder(pubnub *PubNub) *sendFilesBuilder {
+	return newSendFilesBuilderWithContext(pubnub, pubnub.ctx)
+}
+
+func newSendFilesBuilderWithContext(pubnub *PubNub,
+	context Context) *sendFilesBuilder {
+	builder := sendFilesBuilder{
+		opts: &sendFilesOpts{endpointOpts: endpointOpts{pubnub: pubnub, ctx: context}}}
+	return &builder
+}
+
+// Channel sets the channel the files are sent to.
+func (b *sendFilesBuilder) Channel(channel string) *sendFilesBuilder {
+	b.opts.Channel = channel
+
+	return b
+}
+
+// Files sets the files to send.
+func (b *sendFilesBuilder) Files(files []PNFileToSend) *sendFilesBuilder {
+	b.opts.Files = files
+
+	return b
+}
+
+// Message sets the text of the combined message, and of the message of the files without a Message.
+func (b *sendFilesBuilder) Message(message string) *sendFilesBuilder {
+	b.opts.Message = message
+
+	return b
+}
+
+// CombinedMessage when true publishes a single message referencing all the
+// uploaded files, once they are uploaded, instead of a message per file.
+func (b *sendFilesBuilder) CombinedMessage(combined bool) *sendFilesBuilder {
+	b.opts.CombinedMessage = combined
+
+	return b
+}
+
+// TTL sets the TTL (hours) of the file messages.
+func (b *sendFilesBuilder) TTL(ttl int) *sendFilesBuilder {
+	b.opts.TTL = ttl
+
+	return b
+}
+
+// Meta sets the Meta Payload of the file messages.
+func (b *sendFilesBuilder) Meta(meta interface{}) *sendFilesBuilder {
+	b.opts.Meta = meta
+
+	return b
+}
+
+// ShouldStore if true the file messages are stored in History.
+func (b *sendFilesBuilder) ShouldStore(store bool) *sendFilesBuilder {
+	b.opts.ShouldStore = store
+
+	return b
+}
+
+// CipherKey sets the key the files are encrypted with.
+func (b *sendFilesBuilder) CipherKey(cipher string) *sendFilesBuilder {
+	b.opts.CipherKey = cipher
+
+	return b
+}
+
+// CryptoModule sets the cryptography module the files and their messages are encrypted
+// with, overriding CipherKey, the Config one and the one resolved for the channel.
+func (b *sendFilesBuilder) CryptoModule(cryptoModule crypto.CryptoModule) *sendFilesBuilder {
+	b.opts.CryptoModule = cryptoModule
+
+	return b
+}
+
+// Concurrency sets the max number of files sent concurrently, 3 by default.
+func (b *sendFilesBuilder) Concurrency(concurrency int) *sendFilesBuilder {
+	b.opts.Concurrency = concurrency
+
+	return b
+}
+
+// OnProgress sets a callback called with the name of the file, the number of bytes
+// of the file uploaded and the size of the file, -1 when unknown.
+func (b *sendFilesBuilder) OnProgress(onProgress func(name string, sent, total int64)) *sendFilesBuilder {
+	b.opts.OnProgress = onProgress
+
+	return b
+}
+
+// QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URLs called by the API.
+func (b *sendFilesBuilder) QueryParam(queryParam map[string]string) *sendFilesBuilder {
+	b.opts.QueryParam = queryParam
+
+	return b
+}
+
+// Transport sets the Transport for the SendFiles requests.
+func (b *sendFilesBuilder) Transport(tr http.RoundTripper) *sendFilesBuilder {
+	b.opts.Transport = tr
+
+	return b
+}
+
+// Execute sends the files concurrently, each one as SendFile does. The files
+// whose send failed report their error, a *SendFileError when the send can be
+// resumed, the error is returned only when the request is invalid or every send failed.
+// The status is the one of the last file sent, or of the last failed one when they all failed.
+func (b *sendFilesBuilder) Execute() (*PNSendFilesResponse, StatusResponse, error) {
+	o := b.opts
+	if err := o.validate(); err != nil {
+		return emptySendFilesResponse, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+	}
+
+	fileOpts := make([]*sendFileOpts, len(o.Files))
+	for i, file := range o.Files {
+		fileOpts[i] = o.fileOpts(file)
+	}
+	if o.CombinedMessage {
+		// the files are uploaded only if their message can be published
+		if err := o.validateCombinedMessage(fileOpts); err != nil {
+			return emptySendFilesResponse, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+		}
+	}
+
+	concurrency := o.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultSendFilesConcurrency
+	}
+
+	resp := &PNSendFilesResponse{Files: make([]PNSentFile, len(o.Files))}
+	pendings := make([]*PendingFileSend, len(o.Files))
+	statuses := make([]StatusResponse, len(o.Files))
+
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	for i := 0; i < concurrency && i < len(o.Files); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				pendings[j], statuses[j] = o.sendFile(fileOpts[j], &resp.Files[j])
+				if resp.Files[j].Error != nil {
+					o.config().Log.Println("send files: send failed", resp.Files[j].Name, resp.Files[j].Error)
+				}
+			}
+		}()
+	}
+	for i := range o.Files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if o.CombinedMessage {
+		o.publishCombinedMessage(resp, pendings, statuses)
+	}
+
+	var status, errStatus StatusResponse
+	sent := false
+	var lastErr error
+	for i, file := range resp.Files {
+		if file.Error != nil {
+			errStatus = statuses[i]
+			lastErr = file.Error
+		} else {
+			status = statuses[i]
+			sent = true
+		}
+	}
+	if !sent {
+		return resp, errStatus, lastErr
+	}
+
+	return resp, status, nil
+}
+
+type sendFilesOpts struct {
+	endpointOpts
+
+	Channel         string
+	Files           []PNFileToSend
+	Message         string
+	CombinedMessage bool
+	TTL             int
+	Meta            interface{}
+	ShouldStore     bool
+	CipherKey       string
+	CryptoModule    crypto.CryptoModule
+	Concurrency     int
+	OnProgress      func(name string, sent, total int64)
+	QueryParam      map[string]string
+	Transport       http.RoundTripper
+}
+
+func (o *sendFilesOpts) validate() error {
+	if o.config().SubscribeKey == "" {
+		return pnerr.NewValidationError("SendFiles", StrMissingSubKey)
+	}
+	if o.Channel == "" {
+		return pnerr.NewValidationError("SendFiles", StrMissingChannel)
+	}
+	if len(o.Files) == 0 {
+		return pnerr.NewValidationError("SendFiles", StrMissingFile)
+	}
+	return nil
+}
+
+// validateCombinedMessage checks that the combined message of the files is not
+// larger than the publish limit, with an ID as long as the ones of the files.
+// It sets the content type of the files, read from their content when needed.
+func (o *sendFilesOpts) validateCombinedMessage(fileOpts []*sendFileOpts) error {
+	message := PNPublishFileMessage{
+		PNMessage: &PNPublishMessage{Text: o.Message},
+		PNFiles:   []*PNFileInfoForPublish{},
+	}
+	for _, fo := range fileOpts {
+		if fo.File == nil && fo.Reader == nil {
+			continue
+		}
+		fo.ContentType = fo.detectContentType()
+		message.PNFiles = append(message.PNFiles, &PNFileInfoForPublish{
+			ID:          fileIDPlaceholder,
+			Name:        fo.Name,
+			ContentType: fo.ContentType,
+			Metadata:    fo.FileMetadata,
+		})
+	}
+	if len(message.PNFiles) == 0 {
+		return nil
+	}
+	message.PNFile = message.PNFiles[0]
+
+	po := newPublishFileMessageOpts(o.pubnub, o.ctx)
+	po.Channel = o.Channel
+	po.Message = message
+	po.CryptoModule = o.CryptoModule
+	path, err := po.buildPath()
+	if err != nil {
+		return err
+	}
+	if len(path) > maxPublishMessageSize {
+		return pnerr.NewValidationError("SendFiles", fmt.Sprintf("%s: %d bytes", StrMessageTooLarge, len(path)))
+	}
+	return nil
+}
+
+// fileOpts returns the SendFile opts of the file.
+func (o *sendFilesOpts) fileOpts(file PNFileToSend) *sendFileOpts {
+	fo := newSendFileOpts(o.pubnub, o.ctx)
+	fo.Channel = o.Channel
+	fo.Name = file.Name
+	fo.File = file.File
+	fo.Reader = file.Reader
+	if file.Size > 0 {
+		fo.Size = file.Size
+		fo.setSize = true
+	}
+	fo.ContentType = file.ContentType
+	fo.FileMetadata = file.Metadata
+	fo.Message = file.Message
+	if fo.Message == "" || o.CombinedMessage {
+		fo.Message = o.Message
+	}
+	fo.TTL = o.TTL
+	fo.Meta = o.Meta
+	fo.ShouldStore = o.ShouldStore
+	fo.CipherKey = o.CipherKey
+	fo.CryptoModule = o.CryptoModule
+	fo.QueryParam = o.QueryParam
+	fo.Transport = o.Transport
+	if o.OnProgress != nil {
+		name := file.Name
+		fo.OnProgress = func(sent, total int64) {
+			o.OnProgress(name, sent, total)
+		}
+	}
+
+	return fo
+}
+
+// sendFile uploads the file, and publishes its message unless the message is
+// combined. It returns the state of the send, nil when the upload URL couldn't
+// be generated, and the status of its last request.
+func (o *sendFilesOpts) sendFile(fo *sendFileOpts, sent *PNSentFile) (*PendingFileSend, StatusResponse) {
+	sent.Name = fo.Name
+	if sent.Error = fo.validate(); sent.Error != nil {
+		return nil, createStatus(PNUnknownCategory, "", ResponseInfo{}, sent.Error)
+	}
+
+	pending, status, err := fo.generateUploadURL()
+	if err != nil {
+		sent.Error = err
+		return nil, status
+	}
+	sent.ID = pending.FileID
+
+	if o.CombinedMessage {
+		uploadStatus, err := fo.upload(pending)
+		if err != nil {
+			sent.Error = &SendFileError{Step: SendFileStepUpload, Pending: pending, Err: err}
+			return pending, uploadStatus
+		}
+		pending.Step = SendFileStepPublish
+		return pending, uploadStatus
+	}
+
+	resp, status, err := fo.resume(pending, status)
+	if err != nil {
+		sent.Error = err
+		return pending, status
+	}
+	sent.Timestamp = resp.Timestamp
+
+	return pending, status
+}
+
+// publishCombinedMessage publishes the message of all the uploaded files, the
+// first one being its file, and sets its timetoken or its error, and its status, on the files.
+func (o *sendFilesOpts) publishCombinedMessage(resp *PNSendFilesResponse, pendings []*PendingFileSend, statuses []StatusResponse) {
+	message := PNPublishFileMessage{
+		PNMessage: &PNPublishMessage{Text: o.Message},
+		PNFiles:   []*PNFileInfoForPublish{},
+	}
+	for i, file := range resp.Files {
+		if file.Error == nil {
+			message.PNFiles = append(message.PNFiles, &PNFileInfoForPublish{
+				ID:          pendings[i].FileID,
+				Name:        file.Name,
+				ContentType: pendings[i].ContentType,
+				Metadata:    pendings[i].FileMetadata,
+			})
+		}
+	}
+	if len(message.PNFiles) == 0 {
+		return
+	}
+	message.PNFile = message.PNFiles[0]
+
+	timestamp, status, err := o.fileOpts(PNFileToSend{}).publishMessage(message)
+	if err == nil {
+		resp.Timestamp = timestamp
+	}
+	for i := range resp.Files {
+		file := &resp.Files[i]
+		if file.Error != nil {
+			continue
+		}
+		statuses[i] = status
+		if err != nil {
+			// each file can be resumed alone, with a message of its own
+			file.Error = &SendFileError{Step: SendFileStepPublish, Pending: pendings[i], Err: err}
+		} else {
+			file.Timestamp = timestamp
+		}
+	}
+}
+
+var emptySendFilesResponse *PNSendFilesResponse
+
+// PNSentFile is the outcome of the send of a file by SendFiles.
+type PNSentFile struct {
+	Name string
+	// ID is the ID of the file, empty when the upload URL couldn't be generated.
+	ID string
+	// Timestamp is the timetoken of the message of the file, 0 when not published.
+	Timestamp int64
+	// Error is the error which stopped the send of the file.
+	Error error
+}
+
+// PNSendFilesResponse is the response of the SendFiles request.
+type PNSendFilesResponse struct {
+	// Files holds the outcome of each file, in the order of the files sent.
+	Files []PNSentFile
+	// Timestamp is the timetoken of the combined message, 0 when not published.
+	Timestamp int64
+}
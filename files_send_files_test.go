@@ -0,0 +1,241 @@
+package pubnub
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sendFilesServer stubs the SendFile requests, the ID of a file is "id-" and its name,
+// the uploads of the files named in failUpload fail.
+type sendFilesServer struct {
+	sync.Mutex
+	failUpload  map[string]bool
+	failPublish bool
+	uploads     map[string]string
+	published   []PNPublishFileMessage
+	inFlight    int
+	maxInFlight int
+}
+
+func (s *sendFilesServer) newPubNub(t *testing.T) *PubNub {
+	config := NewDemoConfig()
+	config.FileSendRetryLimit = 1
+	pn := NewPubNub(config)
+	s.uploads = map[string]string{}
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.Contains(req.URL.Opaque, "generate-upload-url"):
+			body, _ := ioutil.ReadAll(req.Body)
+			var file PNSendFileBody
+			assert.Nil(t, json.Unmarshal(body, &file))
+			return stubResponse(req, `{"status":200,"data":{"id":"id-`+file.Name+`","name":"`+file.Name+`"},"file_upload_request":{"url":"https://s3.example.com/upload","method":"POST","form_fields":[{"key":"key","value":"`+file.Name+`"}]}}`), nil
+		case req.URL.Host == "s3.example.com":
+			fields, content, _ := readUpload(t, req)
+			s.Lock()
+			s.inFlight++
+			if s.inFlight > s.maxInFlight {
+				s.maxInFlight = s.inFlight
+			}
+			s.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			s.Lock()
+			defer s.Unlock()
+			s.inFlight--
+			resp := stubResponse(req, "")
+			resp.StatusCode = 204
+			if s.failUpload[fields["key"]] {
+				resp.StatusCode = 400
+				return resp, nil
+			}
+			s.uploads[fields["key"]] = string(content)
+			return resp, nil
+		default:
+			s.Lock()
+			defer s.Unlock()
+			if s.failPublish {
+				resp := stubResponse(req, `{"status":400,"error":true}`)
+				resp.StatusCode = 400
+				return resp, nil
+			}
+			parts := strings.Split(req.URL.Opaque, "/")
+			payload, _ := url.PathUnescape(parts[len(parts)-1])
+			var message PNPublishFileMessage
+			assert.Nil(t, json.Unmarshal([]byte(payload), &message))
+			s.published = append(s.published, message)
+			return stubResponse(req, `[1,"Sent","1600000000000000`+string(rune('0'+len(s.published)))+`"]`), nil
+		}
+	})})
+	return pn
+}
+
+func filesToSend(names ...string) []PNFileToSend {
+	files := []PNFileToSend{}
+	for _, name := range names {
+		files = append(files, PNFileToSend{Name: name, Reader: strings.NewReader("content of " + name)})
+	}
+	return files
+}
+
+func TestSendFilesMessagePerFile(t *testing.T) {
+	assert := assert.New(t)
+
+	server := &sendFilesServer{}
+	pn := server.newPubNub(t)
+	defer pn.Destroy()
+
+	files := filesToSend("a.txt", "b.txt", "c.txt", "d.txt")
+	files[1].Message = "own message"
+	var progressMu sync.Mutex
+	progress := map[string]int64{}
+	resp, status, err := pn.SendFiles().
+		Channel("ch").
+		Files(files).
+		Message("shared message").
+		Concurrency(2).
+		OnProgress(func(name string, sent, total int64) {
+			progressMu.Lock()
+			progress[name] = sent
+			progressMu.Unlock()
+		}).
+		Execute()
+	assert.Nil(err)
+	assert.Equal(200, status.StatusCode)
+	assert.Equal(int64(0), resp.Timestamp)
+	assert.Len(resp.Files, 4)
+	for i, file := range resp.Files {
+		assert.Nil(file.Error)
+		assert.Equal(files[i].Name, file.Name)
+		assert.Equal("id-"+files[i].Name, file.ID)
+		assert.NotZero(file.Timestamp)
+		assert.Equal("content of "+file.Name, server.uploads[file.Name])
+		assert.Equal(int64(len("content of "+file.Name)), progress[file.Name])
+	}
+	assert.Equal(2, server.maxInFlight)
+
+	assert.Len(server.published, 4)
+	texts := map[string]string{}
+	for _, message := range server.published {
+		assert.Empty(message.PNFiles)
+		texts[message.PNFile.Name] = message.PNMessage.Text
+	}
+	assert.Equal(map[string]string{"a.txt": "shared message", "b.txt": "own message", "c.txt": "shared message", "d.txt": "shared message"}, texts)
+}
+
+func TestSendFilesCombinedMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	server := &sendFilesServer{failUpload: map[string]bool{"b.txt": true}}
+	pn := server.newPubNub(t)
+	defer pn.Destroy()
+
+	resp, status, err := pn.SendFiles().
+		Channel("ch").
+		Files(filesToSend("a.txt", "b.txt", "c.txt")).
+		Message("album").
+		CombinedMessage(true).
+		Execute()
+	assert.Nil(err)
+	assert.Equal(200, status.StatusCode)
+	assert.Equal(int64(16000000000000001), resp.Timestamp)
+
+	assert.Nil(resp.Files[0].Error)
+	assert.Equal(resp.Timestamp, resp.Files[0].Timestamp)
+	assert.Nil(resp.Files[2].Error)
+	assert.Equal(resp.Timestamp, resp.Files[2].Timestamp)
+
+	sendErr, ok := resp.Files[1].Error.(*SendFileError)
+	assert.True(ok)
+	assert.Equal(SendFileStepUpload, sendErr.Step)
+	assert.Equal("id-b.txt", sendErr.Pending.FileID)
+	assert.Zero(resp.Files[1].Timestamp)
+
+	assert.Len(server.published, 1)
+	message := server.published[0]
+	assert.Equal("album", message.PNMessage.Text)
+	assert.Equal(&PNFileInfoForPublish{ID: "id-a.txt", Name: "a.txt", ContentType: "text/plain; charset=utf-8"}, message.PNFile)
+	assert.Equal([]*PNFileInfoForPublish{
+		{ID: "id-a.txt", Name: "a.txt", ContentType: "text/plain; charset=utf-8"},
+		{ID: "id-c.txt", Name: "c.txt", ContentType: "text/plain; charset=utf-8"},
+	}, message.PNFiles)
+}
+
+func TestSendFilesCombinedMessagePublishFailed(t *testing.T) {
+	assert := assert.New(t)
+
+	server := &sendFilesServer{failPublish: true}
+	pn := server.newPubNub(t)
+	pn.Config.FileMessagePublishRetryLimit = 1
+	defer pn.Destroy()
+
+	resp, status, err := pn.SendFiles().
+		Channel("ch").
+		Files(filesToSend("a.txt", "b.txt")).
+		CombinedMessage(true).
+		Execute()
+	assert.NotNil(err)
+	assert.Equal(400, status.StatusCode)
+	for _, file := range resp.Files {
+		sendErr, ok := file.Error.(*SendFileError)
+		assert.True(ok)
+		assert.Equal(SendFileStepPublish, sendErr.Step)
+		assert.Equal(SendFileStepPublish, sendErr.Pending.Step)
+		assert.Equal("id-"+file.Name, sendErr.Pending.FileID)
+	}
+}
+
+func TestSendFilesValidation(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+
+	_, status, err := pn.SendFiles().Files(filesToSend("a.txt")).Execute()
+	assert.Contains(err.Error(), StrMissingChannel)
+	assert.Equal(err, status.Error)
+
+	_, _, err = pn.SendFiles().Channel("ch").Execute()
+	assert.Contains(err.Error(), StrMissingFile)
+
+	resp, status, err := pn.SendFiles().Channel("ch").Files([]PNFileToSend{{Name: "a.txt"}}).Execute()
+	assert.Contains(err.Error(), StrMissingFile)
+	assert.Contains(resp.Files[0].Error.Error(), StrMissingFile)
+	assert.Equal(resp.Files[0].Error, status.Error)
+}
+
+func TestSendFilesCombinedMessageTooLarge(t *testing.T) {
+	assert := assert.New(t)
+
+	server := &sendFilesServer{}
+	pn := server.newPubNub(t)
+	defer pn.Destroy()
+
+	files := filesToSend("a.txt", "b.txt")
+	files[1].Metadata = map[string]interface{}{"description": strings.Repeat("d", maxPublishMessageSize)}
+	resp, status, err := pn.SendFiles().
+		Channel("ch").
+		Files(files).
+		CombinedMessage(true).
+		Execute()
+	assert.Nil(resp)
+	assert.Contains(err.Error(), StrMessageTooLarge)
+	assert.Equal(err, status.Error)
+	assert.Empty(server.uploads)
+	assert.Empty(server.published)
+}
+
+func TestParseFileList(t *testing.T) {
+	assert := assert.New(t)
+
+	var payload map[string]interface{}
+	assert.Nil(json.Unmarshal([]byte(`{"message":{"text":"album"},"file":{"id":"a","name":"a.txt"},"files":[{"id":"a","name":"a.txt"},{"id":"b","name":"b.txt"}]}`), &payload))
+	assert.Equal([]PNFileDetails{{ID: "a", Name: "a.txt"}, {ID: "b", Name: "b.txt"}}, ParseFileList(payload))
+
+	delete(payload, "files")
+	assert.Nil(ParseFileList(payload))
+}
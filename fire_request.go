@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/pubnub/go/v7/crypto"
 	"github.com/pubnub/go/v7/pnerr"
 	"github.com/pubnub/go/v7/utils"
 
@@ -34,6 +35,10 @@ type fireOpts struct {
 	DoNotReplicate bool
 	Transport      http.RoundTripper
 	QueryParam     map[string]string
+	Codec          Codec
+	CryptoModule   crypto.CryptoModule
+	// the message is already encoded with the codec and validated
+	isEncoded bool
 	// nil hacks
 	setTTL         bool
 	setShouldStore bool
@@ -99,6 +104,21 @@ func (b *fireBuilder) Serialize(serialize bool) *fireBuilder {
 	return b
 }
 
+// Codec sets the codec the message is encoded with before encryption, overriding the Config Codec.
+func (b *fireBuilder) Codec(codec Codec) *fireBuilder {
+	b.opts.Codec = codec
+
+	return b
+}
+
+// CryptoModule sets the cryptography module the message is encrypted with,
+// overriding the Config one and the one resolved for the channel.
+func (b *fireBuilder) CryptoModule(cryptoModule crypto.CryptoModule) *fireBuilder {
+	b.opts.CryptoModule = cryptoModule
+
+	return b
+}
+
 // Transport sets the Transport for the Fire request.
 func (b *fireBuilder) Transport(tr http.RoundTripper) *fireBuilder {
 	b.opts.Transport = tr
@@ -117,7 +137,11 @@ func (b *fireBuilder) QueryParam(queryParam map[string]string) *fireBuilder {
 func (b *fireBuilder) Execute() (*PublishResponse, StatusResponse, error) {
 	b.opts.ShouldStore = false
 	b.opts.DoNotReplicate = true
-	rawJSON, status, err := executeRequest(b.opts)
+	opts, err := b.opts.encodeWithCodec()
+	if err != nil {
+		return emptyPublishResponse, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+	}
+	rawJSON, status, err := executeRequest(opts)
 	if err != nil {
 		return emptyPublishResponse, status, err
 	}
@@ -142,9 +166,52 @@ func (o *fireOpts) validate() error {
 		return newValidationError(o, StrMissingMessage)
 	}
 
+	if !o.isEncoded {
+		if err := o.pubnub.schemaRegistry.validateOutgoing(o.Channel, o.Message, !o.Serialize); err != nil {
+			return newValidationError(o, err.Error())
+		}
+	}
+
 	return nil
 }
 
+// encodeWithCodec returns a copy of the opts with the message encoded by the codec
+// and the codec name in the meta. The opts are returned as is for JSON messages.
+func (o *fireOpts) encodeWithCodec() (*fireOpts, error) {
+	codec := o.pubnub.outgoingCodec(o.Codec)
+	if codec == nil {
+		return o, nil
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	meta, ok := copyMeta(o.Meta)
+	if !ok {
+		return nil, newValidationError(o, StrInvalidCodecMeta)
+	}
+	meta[codecMetaKey] = codec.Name()
+
+	payload, err := encodeWithCodec(codec, o.Message, !o.Serialize)
+	if err != nil {
+		return nil, pnerr.NewBuildRequestError(fmt.Sprintf("Codec %s: %s", codec.Name(), err.Error()))
+	}
+
+	encoded := *o
+	encoded.Message = payload
+	encoded.Meta = meta
+	encoded.Serialize = true
+	encoded.isEncoded = true
+
+	return &encoded, nil
+}
+
+func (o *fireOpts) cryptoModule() crypto.CryptoModule {
+	if o.CryptoModule != nil {
+		return o.CryptoModule
+	}
+	return o.pubnub.channelCryptoModule(o.Channel)
+}
+
 func (o *fireOpts) buildPath() (string, error) {
 	if o.UsePost == true {
 		return fmt.Sprintf(publishPostPath,
@@ -157,9 +224,9 @@ func (o *fireOpts) buildPath() (string, error) {
 	var message []byte
 	var err error
 
-	if o.pubnub.getCryptoModule() != nil {
+	if cryptoModule := o.cryptoModule(); cryptoModule != nil {
 		var msg string
-		if msg, err = serializeEncryptAndSerialize(o.pubnub.getCryptoModule(), o.Message, o.Serialize); err != nil {
+		if msg, err = serializeEncryptAndSerialize(cryptoModule, o.Message, o.Serialize); err != nil {
 			o.pubnub.Config.Log.Printf("error in serializing: %v\n", err)
 			return "", err
 		}
@@ -225,8 +292,8 @@ func (o *fireOpts) buildBody() ([]byte, error) {
 			}
 		}
 
-		if o.pubnub.getCryptoModule() != nil {
-			enc, err := encryptString(o.pubnub.getCryptoModule(), string(msg))
+		if cryptoModule := o.cryptoModule(); cryptoModule != nil {
+			enc, err := encryptString(cryptoModule, string(msg))
 			if err != nil {
 				return []byte{}, err
 			}
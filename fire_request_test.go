@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"testing"
 
+	"github.com/pubnub/go/v7/crypto"
 	h "github.com/pubnub/go/v7/tests/helpers"
 	"github.com/stretchr/testify/assert"
 )
@@ -320,3 +321,21 @@ func TestFirePathPost(t *testing.T) {
 
 	AssertSuccessFirePost(t, "[1,2,3]", []int{1, 2, 3})
 }
+
+func TestFireCryptoModule(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.UseRandomInitializationVector = false
+	pn.Config.CipherKey = "enigma"
+	expected, err := newFireBuilder(pn).Channel("ch").Message("yay!").opts.buildPath()
+	assert.Nil(err)
+	pn.Config.CipherKey = ""
+
+	module, err := crypto.NewLegacyCryptoModule("enigma", false)
+	assert.Nil(err)
+
+	path, err := newFireBuilder(pn).Channel("ch").Message("yay!").CryptoModule(module).opts.buildPath()
+	assert.Nil(err)
+	assert.Equal(expected, path)
+}
@@ -0,0 +1,113 @@
+package pubnub
+
+import (
+	"sort"
+)
+
+type hereNowIteratorEntry struct {
+	channel  string
+	occupant HereNowOccupantsData
+}
+
+// HereNowIterator pages through the occupants of the channels of a HereNow
+// request with Limit and Offset, so that channels with thousands of occupants
+// are fetched in several requests.
+//
+//	it := pn.HereNow().Channels([]string{"ch"}).Iterate(ctx)
+//	for it.Next() {
+//		fmt.Println(it.Channel(), it.Occupant().UUID)
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type HereNowIterator struct {
+	pageIterator
+	opts    hereNowOpts
+	started bool
+	// channels are the channels with more occupants to fetch after the first page.
+	channels []string
+	buffer   []hereNowIteratorEntry
+	current  hereNowIteratorEntry
+}
+
+// Iterate returns an iterator paging through the occupants of the channels and
+// channel groups, or of all the channels when none is set. The pages hold Limit
+// occupants per channel, 1000 when not set, starting at Offset. The iteration
+// stops when the context is done.
+func (b *hereNowBuilder) Iterate(ctx Context) *HereNowIterator {
+	it := &HereNowIterator{
+		pageIterator: newPageIterator(ctx, b.opts.endpointOpts),
+		opts:         *b.opts,
+	}
+	it.opts.ctx = it.ctx
+	it.opts.IncludeUUIDs, it.opts.SetIncludeUUIDs = true, true
+	if it.opts.Limit == 0 {
+		it.opts.Limit = maxHereNowLimit
+	}
+
+	if err := b.opts.validate(); err != nil {
+		it.err = err
+	}
+
+	return it
+}
+
+// Next advances the iterator to the next occupant, fetching the next page when needed.
+// It returns false when all the occupants were returned, on error or when the context is done.
+func (it *HereNowIterator) Next() bool {
+	if !it.next(func() int { return len(it.buffer) }, func() bool { return it.started && len(it.channels) == 0 }, it.fetchPage) {
+		return false
+	}
+	it.current = it.buffer[0]
+	it.buffer = it.buffer[1:]
+
+	return true
+}
+
+// Channel returns the channel of the current occupant.
+func (it *HereNowIterator) Channel() string {
+	return it.current.channel
+}
+
+// Occupant returns the current occupant.
+func (it *HereNowIterator) Occupant() HereNowOccupantsData {
+	return it.current.occupant
+}
+
+// Err returns the error which stopped the iteration, nil when all the occupants were returned.
+func (it *HereNowIterator) Err() error {
+	return it.err
+}
+
+func (it *HereNowIterator) fetchPage() {
+	opts := it.opts
+	if it.started {
+		// the next pages are requested by channel name, the channel groups
+		// were resolved by the first page
+		opts.Channels = it.channels
+		opts.ChannelGroups = nil
+	}
+
+	resp, _, err := (&hereNowBuilder{opts: &opts}).Execute()
+	if err != nil {
+		it.fail(err)
+		return
+	}
+	it.started = true
+
+	channels := append([]HereNowChannelData{}, resp.Channels...)
+	sort.SliceStable(channels, func(i, j int) bool {
+		return channels[i].ChannelName < channels[j].ChannelName
+	})
+	it.channels = []string{}
+	for _, ch := range channels {
+		for _, occupant := range ch.Occupants {
+			it.buffer = append(it.buffer, hereNowIteratorEntry{channel: ch.ChannelName, occupant: occupant})
+		}
+		fetched := opts.Offset + len(ch.Occupants)
+		if len(ch.Occupants) == opts.Limit && fetched < ch.Occupancy {
+			it.channels = append(it.channels, ch.ChannelName)
+		}
+	}
+	it.opts.Offset += it.opts.Limit
+}
@@ -0,0 +1,92 @@
+package pubnub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newHereNowIteratorTestPubNub serves the occupants of the channels, "u0" to "u<n-1>",
+// and records the channels, limit and offset of each request.
+func newHereNowIteratorTestPubNub(occupancy map[string]int, requests *[]string) *PubNub {
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		path := req.URL.Opaque
+		rawChannels, _ := url.PathUnescape(path[strings.LastIndex(path, "/")+1:])
+		channels := strings.Split(rawChannels, ",")
+		q := req.URL.Query()
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		offset, _ := strconv.Atoi(q.Get("offset"))
+		*requests = append(*requests, fmt.Sprintf("%s limit=%d offset=%d", rawChannels, limit, offset))
+
+		data := map[string]interface{}{}
+		for _, ch := range channels {
+			uuids := []interface{}{}
+			for i := offset; i < occupancy[ch] && i < offset+limit; i++ {
+				uuids = append(uuids, map[string]interface{}{"uuid": ch + "-u" + strconv.Itoa(i)})
+			}
+			data[ch] = map[string]interface{}{"occupancy": occupancy[ch], "uuids": uuids}
+		}
+
+		var body []byte
+		if len(channels) == 1 {
+			single := data[channels[0]].(map[string]interface{})
+			single["status"] = 200
+			body, _ = json.Marshal(single)
+		} else {
+			body, _ = json.Marshal(map[string]interface{}{
+				"status":  200,
+				"payload": map[string]interface{}{"channels": data, "total_channels": len(channels)},
+			})
+		}
+		return stubResponse(req, string(body)), nil
+	})})
+	return pn
+}
+
+func TestHereNowIterator(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := []string{}
+	pn := newHereNowIteratorTestPubNub(map[string]int{"a": 5, "b": 2, "c": 4}, &requests)
+	defer pn.Destroy()
+
+	it := pn.HereNow().Channels([]string{"a", "b", "c"}).Limit(2).Iterate(nil)
+	occupants := map[string][]string{}
+	for it.Next() {
+		occupants[it.Channel()] = append(occupants[it.Channel()], it.Occupant().UUID)
+	}
+	assert.Nil(it.Err())
+	assert.Equal([]string{"a-u0", "a-u1", "a-u2", "a-u3", "a-u4"}, occupants["a"])
+	assert.Equal([]string{"b-u0", "b-u1"}, occupants["b"])
+	assert.Equal([]string{"c-u0", "c-u1", "c-u2", "c-u3"}, occupants["c"])
+	assert.Equal([]string{
+		"a,b,c limit=2 offset=0",
+		"a,c limit=2 offset=2",
+		"a limit=2 offset=4",
+	}, requests)
+}
+
+func TestHereNowIteratorError(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := []string{}
+	pn := newHereNowIteratorTestPubNub(map[string]int{}, &requests)
+	defer pn.Destroy()
+
+	it := pn.HereNow().Channels([]string{"a"}).Limit(5000).Iterate(nil)
+	assert.False(it.Next())
+	assert.Contains(it.Err().Error(), StrInvalidHereNowLimit)
+	assert.Equal(0, len(requests))
+
+	it = pn.HereNow().Channels([]string{"a"}).Iterate(nil)
+	assert.False(it.Next())
+	assert.Nil(it.Err())
+	assert.Equal([]string{"a limit=1000 offset=0"}, requests)
+}
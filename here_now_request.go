@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/pubnub/go/v7/pnerr"
@@ -17,6 +18,15 @@ var globalHereNowPath = "/v2/presence/sub_key/%s"
 
 var emptyHereNowResponse *HereNowResponse
 
+// maxHereNowLimit is the max number of occupants per channel of a HereNow response.
+const maxHereNowLimit = 1000
+
+// StrInvalidHereNowLimit shows `Limit must be between 0 and 1000` message
+const StrInvalidHereNowLimit = "Limit must be between 0 and 1000"
+
+// StrInvalidHereNowOffset shows `Offset must not be negative` message
+const StrInvalidHereNowOffset = "Offset must not be negative"
+
 type hereNowBuilder struct {
 	opts *hereNowOpts
 }
@@ -66,6 +76,20 @@ func (b *hereNowBuilder) IncludeUUIDs(uuid bool) *hereNowBuilder {
 	return b
 }
 
+// Limit sets the max number of occupants returned per channel, up to 1000.
+func (b *hereNowBuilder) Limit(limit int) *hereNowBuilder {
+	b.opts.Limit = limit
+
+	return b
+}
+
+// Offset sets the number of occupants to skip in each channel, used with Limit to page through the occupants.
+func (b *hereNowBuilder) Offset(offset int) *hereNowBuilder {
+	b.opts.Offset = offset
+
+	return b
+}
+
 // QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
 func (b *hereNowBuilder) QueryParam(queryParam map[string]string) *hereNowBuilder {
 	b.opts.QueryParam = queryParam
@@ -73,6 +97,13 @@ func (b *hereNowBuilder) QueryParam(queryParam map[string]string) *hereNowBuilde
 	return b
 }
 
+// Transport sets the Transport for the HereNow request.
+func (b *hereNowBuilder) Transport(tr http.RoundTripper) *hereNowBuilder {
+	b.opts.Transport = tr
+
+	return b
+}
+
 // Execute runs the HereNow request.
 func (b *hereNowBuilder) Execute() (*HereNowResponse, StatusResponse, error) {
 	rawJSON, status, err := executeRequest(b.opts)
@@ -101,6 +132,8 @@ type hereNowOpts struct {
 	IncludeState    bool
 	SetIncludeState bool
 	SetIncludeUUIDs bool
+	Limit           int
+	Offset          int
 	QueryParam      map[string]string
 
 	Transport http.RoundTripper
@@ -111,6 +144,14 @@ func (o *hereNowOpts) validate() error {
 		return newValidationError(o, StrMissingSubKey)
 	}
 
+	if o.Limit < 0 || o.Limit > maxHereNowLimit {
+		return newValidationError(o, StrInvalidHereNowLimit)
+	}
+
+	if o.Offset < 0 {
+		return newValidationError(o, StrInvalidHereNowOffset)
+	}
+
 	return nil
 }
 
@@ -150,6 +191,14 @@ func (o *hereNowOpts) buildQuery() (*url.Values, error) {
 		q.Set("disable-uuids", "0")
 	}
 
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+
+	if o.Offset > 0 {
+		q.Set("offset", strconv.Itoa(o.Offset))
+	}
+
 	SetQueryParam(q, o.QueryParam)
 
 	return q, nil
@@ -208,6 +208,35 @@ func TestHereNowBuildQuery(t *testing.T) {
 
 }
 
+func TestHereNowBuildQueryLimitOffset(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := newHereNowOpts(pubnub, pubnub.ctx)
+	opts.Channels = []string{"ch1"}
+	opts.Limit = 100
+	opts.Offset = 200
+
+	query, err := opts.buildQuery()
+	assert.Nil(err)
+	expected := &url.Values{}
+	expected.Set("limit", "100")
+	expected.Set("offset", "200")
+	h.AssertQueriesEqual(t, expected, query, []string{"pnsdk", "uuid"}, []string{})
+}
+
+func TestHereNowValidateLimitOffset(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+
+	opts := newHereNowOpts(pn, pn.ctx)
+	opts.Limit = 1001
+	assert.Equal("pubnub/validation: pubnub: Here Now: Limit must be between 0 and 1000", opts.validate().Error())
+
+	opts.Limit = 1000
+	opts.Offset = -1
+	assert.Equal("pubnub/validation: pubnub: Here Now: Offset must not be negative", opts.validate().Error())
+}
+
 func TestNewHereNowResponseErrorUnmarshalling(t *testing.T) {
 	assert := assert.New(t)
 	jsonBytes := []byte(`s`)
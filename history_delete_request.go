@@ -50,6 +50,16 @@ func (b *historyDeleteBuilder) End(end int64) *historyDeleteBuilder {
 	return b
 }
 
+// WithStart sets the Start Timetoken for the DeleteMessages request.
+func (b *historyDeleteBuilder) WithStart(start Timetoken) *historyDeleteBuilder {
+	return b.Start(start.Int64())
+}
+
+// WithEnd sets the End Timetoken for the DeleteMessages request.
+func (b *historyDeleteBuilder) WithEnd(end Timetoken) *historyDeleteBuilder {
+	return b.End(end.Int64())
+}
+
 // QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
 func (b *historyDeleteBuilder) QueryParam(queryParam map[string]string) *historyDeleteBuilder {
 	b.opts.QueryParam = queryParam
@@ -0,0 +1,214 @@
+package pubnub
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/pubnub/go/v7/pnerr"
+)
+
+// HistoryExportFormat is the output format of ExportHistory.
+type HistoryExportFormat int
+
+const (
+	// HistoryExportJSONL writes one JSON object per line.
+	HistoryExportJSONL HistoryExportFormat = iota
+	// HistoryExportCSV writes a header line and one line per message.
+	HistoryExportCSV
+)
+
+// HistoryExportColumns are the columns of an exported message, and the keys of the JSONL objects.
+var HistoryExportColumns = []string{"channel", "timetoken", "uuid", "message_type", "meta", "message", "file_id", "file_name", "file_url", "actions", "error"}
+
+// HistoryExportOptions configures ExportHistory.
+type HistoryExportOptions struct {
+	Channels []string
+	// Start is the timetoken the export stops at (exclusive), 0 to export up to the latest message.
+	Start int64
+	// End is the timetoken the export starts from (inclusive), 0 to export from the oldest message.
+	End    int64
+	Format HistoryExportFormat
+	// Columns sets the CSV columns among HistoryExportColumns, all when empty.
+	Columns []string
+	// IncludeMessageActions exports the message actions of each message.
+	IncludeMessageActions bool
+	// ResumeFrom holds the last exported timetoken per channel, as returned in
+	// HistoryExportResult.Cursors. The export of these channels continues after it.
+	ResumeFrom map[string]int64
+	// SkipHeader omits the CSV header, used when appending to a resumed export.
+	SkipHeader bool
+}
+
+// HistoryExportResult is the outcome of ExportHistory.
+type HistoryExportResult struct {
+	// Count is the number of exported messages.
+	Count int
+	// Cursors holds the last exported timetoken per channel, pass it as ResumeFrom to resume the export.
+	Cursors map[string]int64
+}
+
+// ExportHistory writes the messages of the channels between End and Start to w,
+// from the oldest to the newest, one channel after the other. The messages are
+// decrypted with the configured CryptoModule. When the export fails the result
+// holds the cursors of the messages written so far.
+func (pn *PubNub) ExportHistory(ctx Context, w io.Writer, opts HistoryExportOptions) (HistoryExportResult, error) {
+	result := HistoryExportResult{Cursors: make(map[string]int64)}
+	for ch, tt := range opts.ResumeFrom {
+		result.Cursors[ch] = tt
+	}
+
+	if len(opts.Channels) == 0 {
+		return result, pnerr.NewValidationError("ExportHistory", StrMissingChannel)
+	}
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = HistoryExportColumns
+	}
+	for _, c := range columns {
+		if !isHistoryExportColumn(c) {
+			return result, pnerr.NewValidationError("ExportHistory", fmt.Sprintf("Unknown column %s", c))
+		}
+	}
+	if ctx == nil {
+		ctx = pn.ctx
+	}
+
+	var csvWriter *csv.Writer
+	if opts.Format == HistoryExportCSV {
+		csvWriter = csv.NewWriter(w)
+		if !opts.SkipHeader {
+			if err := csvWriter.Write(columns); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	for _, ch := range opts.Channels {
+		builder := pn.FetchWithContext(ctx).
+			Channels([]string{ch}).
+			Reverse(true).
+			IncludeMeta(true).
+			IncludeMessageActions(opts.IncludeMessageActions)
+		if opts.Start > 0 {
+			builder.Start(opts.Start)
+		}
+		end := opts.End
+		if last, ok := result.Cursors[ch]; ok && last+1 > end {
+			// end is inclusive
+			end = last + 1
+		}
+		if end > 0 {
+			builder.End(end)
+		}
+
+		it := builder.Iterate(ctx)
+		for it.Next() {
+			record := newHistoryExportRecord(ch, it.Item())
+			var err error
+			if csvWriter != nil {
+				err = csvWriter.Write(record.csv(columns))
+				if err == nil {
+					csvWriter.Flush()
+					err = csvWriter.Error()
+				}
+			} else {
+				err = record.writeJSONL(w)
+			}
+			if err != nil {
+				return result, err
+			}
+			result.Count++
+			if tt, err := strconv.ParseInt(it.Item().Timetoken, 10, 64); err == nil {
+				result.Cursors[ch] = tt
+			}
+		}
+		if err := it.Err(); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+func isHistoryExportColumn(column string) bool {
+	for _, c := range HistoryExportColumns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+type historyExportRecord map[string]interface{}
+
+func newHistoryExportRecord(channel string, item FetchResponseItem) historyExportRecord {
+	record := historyExportRecord{
+		"channel":      channel,
+		"timetoken":    item.Timetoken,
+		"uuid":         item.UUID,
+		"message_type": item.MessageType,
+		"meta":         item.Meta,
+		"message":      item.Message,
+		"file_id":      item.File.ID,
+		"file_name":    item.File.Name,
+		"file_url":     item.File.URL,
+		"actions":      historyExportActions(item.MessageActions),
+		"error":        "",
+	}
+	if item.Error != nil {
+		record["error"] = item.Error.Error()
+	}
+	return record
+}
+
+// historyExportActions flattens the message actions to a list sorted by action timetoken.
+func historyExportActions(actions map[string]PNHistoryMessageActionsTypeMap) []PNMessageActionsResponse {
+	flat := []PNMessageActionsResponse{}
+	for actionType, values := range actions {
+		for value, list := range values.ActionsTypeValues {
+			for _, a := range list {
+				flat = append(flat, PNMessageActionsResponse{
+					ActionType:      actionType,
+					ActionValue:     value,
+					ActionTimetoken: a.ActionTimetoken,
+					UUID:            a.UUID,
+				})
+			}
+		}
+	}
+	sort.Slice(flat, func(i, j int) bool {
+		return flat[i].ActionTimetoken < flat[j].ActionTimetoken
+	})
+	return flat
+}
+
+func (r historyExportRecord) writeJSONL(w io.Writer) error {
+	line, err := json.Marshal(map[string]interface{}(r))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+func (r historyExportRecord) csv(columns []string) []string {
+	row := make([]string, len(columns))
+	for i, c := range columns {
+		switch v := r[c].(type) {
+		case string:
+			row[i] = v
+		case int:
+			row[i] = strconv.Itoa(v)
+		case nil:
+			row[i] = ""
+		default:
+			b, _ := json.Marshal(v)
+			row[i] = string(b)
+		}
+	}
+	return row
+}
@@ -0,0 +1,122 @@
+package pubnub
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportHistoryJSONLResume(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := 0
+	pn := newFetchIteratorTestPubNub(map[string][]int64{
+		"a": timetokens(1, 5),
+		"b": timetokens(10, 12),
+	}, &requests)
+	defer pn.Destroy()
+
+	var out bytes.Buffer
+	res, err := pn.ExportHistory(nil, &out, HistoryExportOptions{
+		Channels:   []string{"a", "b"},
+		End:        2,
+		ResumeFrom: map[string]int64{"b": 10},
+	})
+	assert.Nil(err)
+	assert.Equal(6, res.Count)
+	assert.Equal(map[string]int64{"a": 5, "b": 12}, res.Cursors)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(6, len(lines))
+	var first map[string]interface{}
+	assert.Nil(json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal("a", first["channel"])
+	assert.Equal("2", first["timetoken"])
+	assert.Equal("a-2", first["message"])
+	assert.Contains(lines[4], `"timetoken":"11"`)
+
+	// nothing left to export after the cursors
+	out.Reset()
+	res, err = pn.ExportHistory(nil, &out, HistoryExportOptions{Channels: []string{"a", "b"}, ResumeFrom: res.Cursors})
+	assert.Nil(err)
+	assert.Equal(0, res.Count)
+	assert.Equal("", out.String())
+}
+
+func TestExportHistoryCSV(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := 0
+	pn := newFetchIteratorTestPubNub(map[string][]int64{"a": timetokens(1, 2)}, &requests)
+	defer pn.Destroy()
+
+	var out bytes.Buffer
+	res, err := pn.ExportHistory(nil, &out, HistoryExportOptions{
+		Channels: []string{"a"},
+		Format:   HistoryExportCSV,
+		Columns:  []string{"timetoken", "message", "actions"},
+	})
+	assert.Nil(err)
+	assert.Equal(2, res.Count)
+	assert.Equal("timetoken,message,actions\n1,a-1,[]\n2,a-2,[]\n", out.String())
+
+	_, err = pn.ExportHistory(nil, &out, HistoryExportOptions{Channels: []string{"a"}, Columns: []string{"bogus"}})
+	assert.Contains(err.Error(), "Unknown column bogus")
+	_, err = pn.ExportHistory(nil, &out, HistoryExportOptions{})
+	assert.Contains(err.Error(), StrMissingChannel)
+}
+
+func TestHistoryExportRecordActions(t *testing.T) {
+	assert := assert.New(t)
+
+	record := newHistoryExportRecord("ch", FetchResponseItem{
+		Message:   map[string]interface{}{"text": "hi"},
+		Timetoken: "10",
+		MessageActions: map[string]PNHistoryMessageActionsTypeMap{
+			"reaction": {ActionsTypeValues: map[string][]PNHistoryMessageActionTypeVal{
+				"smile": {{UUID: "u2", ActionTimetoken: "12"}, {UUID: "u1", ActionTimetoken: "11"}},
+			}},
+		},
+		File: PNFileDetails{ID: "id", Name: "name.txt", URL: "https://file"},
+	})
+	row := record.csv([]string{"message", "file_name", "actions"})
+	assert.Equal(`{"text":"hi"}`, row[0])
+	assert.Equal("name.txt", row[1])
+	assert.Equal(`[{"type":"reaction","value":"smile","actionTimetoken":"11","messageTimetoken":"","uuid":"u1"},{"type":"reaction","value":"smile","actionTimetoken":"12","messageTimetoken":"","uuid":"u2"}]`, row[2])
+}
+
+func TestExportHistoryWithActionsFollowsMore(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, moreBounds := range []bool{true, false} {
+		queries := []string{}
+		pn := newFetchActionsTestPubNub(t, timetokens(1, 60), moreBounds, &queries)
+
+		var out bytes.Buffer
+		res, err := pn.ExportHistory(nil, &out, HistoryExportOptions{
+			Channels:              []string{"a"},
+			End:                   3,
+			IncludeMessageActions: true,
+			Format:                HistoryExportCSV,
+			Columns:               []string{"timetoken"},
+			SkipHeader:            true,
+		})
+		assert.Nil(err)
+		assert.Equal(58, res.Count, "more bounds %v", moreBounds)
+		assert.Equal(map[string]int64{"a": 60}, res.Cursors)
+		assert.Equal(strings.Join(formatTimetokens(timetokens(3, 60)), "\n")+"\n", out.String())
+		assert.Equal([]string{"start=&end=3&max=25", "start=&end=28&max=25", "start=&end=53&max=25"}, queries, "more bounds %v", moreBounds)
+
+		// the resumed export continues after the cursor
+		queries = queries[:0]
+		out.Reset()
+		res, err = pn.ExportHistory(nil, &out, HistoryExportOptions{Channels: []string{"a"}, IncludeMessageActions: true, ResumeFrom: res.Cursors})
+		assert.Nil(err)
+		assert.Equal(0, res.Count)
+		assert.Equal([]string{"start=&end=61&max=25"}, queries)
+		pn.Destroy()
+	}
+}
@@ -0,0 +1,224 @@
+package pubnub
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pubnub/go/v7/pnerr"
+)
+
+// defaultPurgeDeleteInterval is the default delay between two DeleteMessages requests of a purge.
+const defaultPurgeDeleteInterval = 200 * time.Millisecond
+
+// PurgeMatcher reports whether a message of the channel must be deleted.
+type PurgeMatcher func(channel string, item FetchResponseItem) bool
+
+// PurgeByPublisher matches the messages published by one of the UUIDs.
+func PurgeByPublisher(uuids ...string) PurgeMatcher {
+	return func(channel string, item FetchResponseItem) bool {
+		for _, uuid := range uuids {
+			if item.UUID == uuid {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// PurgeByMeta matches the messages whose meta holds the key with the value.
+func PurgeByMeta(key string, value interface{}) PurgeMatcher {
+	return func(channel string, item FetchResponseItem) bool {
+		meta, ok := item.Meta.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		v, ok := meta[key]
+		return ok && reflect.DeepEqual(v, value)
+	}
+}
+
+// PurgeMessagesOptions configures PurgeMessages.
+type PurgeMessagesOptions struct {
+	Channels []string
+	// Start is the timetoken the scan stops at (exclusive), 0 to scan up to the latest message.
+	Start int64
+	// End is the timetoken the scan starts from (inclusive), 0 to scan from the oldest message.
+	End int64
+	// Match selects the messages to delete.
+	Match PurgeMatcher
+	// DryRun computes and reports the delete ranges without deleting the messages.
+	DryRun bool
+	// DeleteInterval is the min delay between two DeleteMessages requests, 200ms when 0.
+	DeleteInterval time.Duration
+}
+
+// PNPurgeRange is a range of matching messages deleted by a single DeleteMessages request.
+type PNPurgeRange struct {
+	Channel string
+	// Start and End are the DeleteMessages bounds, the range holds the messages
+	// with Start < timetoken <= End.
+	Start int64
+	End   int64
+	// Timetokens are the timetokens of the matching messages starting in the
+	// range, the later chunks of a chunked message may be in other ranges.
+	Timetokens []string
+	Deleted    bool
+}
+
+// PurgeMessagesResult is the outcome of PurgeMessages.
+type PurgeMessagesResult struct {
+	// Scanned is the number of messages read.
+	Scanned int
+	// Matched is the number of messages selected by Match.
+	Matched int
+	// Deleted is the number of messages starting in the deleted ranges, 0 on a dry run.
+	Deleted int
+	Ranges  []PNPurgeRange
+}
+
+// PurgeMessages deletes the messages of the channels, between End and Start,
+// selected by Match. The history is scanned with Fetch and the consecutive
+// matching messages are grouped into a single range, so that no other message
+// is deleted. The ranges are deleted with DeleteMessages, one request every
+// DeleteInterval. When the purge fails the result reports the ranges deleted so far.
+func (pn *PubNub) PurgeMessages(ctx Context, opts PurgeMessagesOptions) (PurgeMessagesResult, error) {
+	result := PurgeMessagesResult{Ranges: []PNPurgeRange{}}
+
+	if len(opts.Channels) == 0 {
+		return result, pnerr.NewValidationError("PurgeMessages", StrMissingChannel)
+	}
+	if opts.Match == nil {
+		return result, pnerr.NewValidationError("PurgeMessages", "Missing Match")
+	}
+	if !opts.DryRun && pn.Config.SecretKey == "" {
+		return result, pnerr.NewValidationError("PurgeMessages", StrMissingSecretKey)
+	}
+	if ctx == nil {
+		ctx = pn.ctx
+	}
+	interval := opts.DeleteInterval
+	if interval <= 0 {
+		interval = defaultPurgeDeleteInterval
+	}
+
+	var lastDelete time.Time
+	for _, ch := range opts.Channels {
+		ranges, err := pn.purgeRanges(ctx, ch, opts, &result)
+		if err != nil {
+			return result, err
+		}
+
+		for _, r := range ranges {
+			if !opts.DryRun {
+				if wait := interval - time.Since(lastDelete); !lastDelete.IsZero() && wait > 0 {
+					select {
+					case <-ctx.Done():
+						return result, ctx.Err()
+					case <-time.After(wait):
+					}
+				}
+				lastDelete = time.Now()
+
+				_, _, err := pn.DeleteMessagesWithContext(ctx).
+					Channel(ch).
+					Start(r.Start).
+					End(r.End).
+					Execute()
+				if err != nil {
+					return result, err
+				}
+				r.Deleted = true
+				result.Deleted += len(r.Timetokens)
+			}
+			result.Ranges = append(result.Ranges, r)
+		}
+	}
+
+	return result, nil
+}
+
+// purgeRanges scans the history of the channel from the oldest to the newest
+// message and returns the runs of consecutive matching messages. A chunked
+// message is expanded to the timetokens of all its chunks, which may be
+// interleaved with other messages.
+func (pn *PubNub) purgeRanges(ctx Context, channel string, opts PurgeMessagesOptions, result *PurgeMessagesResult) ([]PNPurgeRange, error) {
+	builder := pn.FetchWithContext(ctx).
+		Channels([]string{channel}).
+		Reverse(true).
+		IncludeMeta(true).
+		IncludeUUID(true)
+	if opts.Start > 0 {
+		builder.Start(opts.Start)
+	}
+	if opts.End > 0 {
+		builder.End(opts.End)
+	}
+
+	// matching holds every scanned timetoken, messages holds the timetoken of
+	// each matching message, keyed by the timetoken of its first chunk
+	matching := make(map[int64]bool)
+	messages := make(map[int64]string)
+	it := builder.Iterate(ctx)
+	for it.Next() {
+		item := it.Item()
+		result.Scanned++
+		match := opts.Match(channel, item)
+		if match {
+			result.Matched++
+		}
+
+		timetokens := item.ChunkTimetokens
+		if len(timetokens) == 0 {
+			timetokens = []string{item.Timetoken}
+		}
+		for i, timetoken := range timetokens {
+			tt, err := strconv.ParseInt(timetoken, 10, 64)
+			if err != nil {
+				continue
+			}
+			if m, ok := matching[tt]; !ok || m {
+				matching[tt] = match
+			}
+			if match && i == 0 {
+				messages[tt] = item.Timetoken
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return []PNPurgeRange{}, err
+	}
+
+	sorted := make([]int64, 0, len(matching))
+	for tt := range matching {
+		sorted = append(sorted, tt)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	ranges := []PNPurgeRange{}
+	var current *PNPurgeRange
+	for _, tt := range sorted {
+		if !matching[tt] {
+			if current != nil {
+				ranges = append(ranges, *current)
+				current = nil
+			}
+			continue
+		}
+
+		if current == nil {
+			// start is exclusive
+			current = &PNPurgeRange{Channel: channel, Start: tt - 1}
+		}
+		current.End = tt
+		if timetoken, ok := messages[tt]; ok {
+			current.Timetokens = append(current.Timetokens, timetoken)
+		}
+	}
+	if current != nil {
+		ranges = append(ranges, *current)
+	}
+
+	return ranges, nil
+}
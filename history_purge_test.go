@@ -0,0 +1,167 @@
+package pubnub
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newPurgeTestPubNub serves the history of a single channel, where the message
+// with timetoken tt was published by publishers[tt-1], and applies the deletes.
+// The message is messages[tt] when set, m<tt> otherwise.
+func newPurgeTestPubNub(publishers []string, messages map[int64]interface{}, deletes *[][2]int64) *PubNub {
+	pn := NewPubNub(NewDemoConfig())
+
+	var mu sync.Mutex
+	history := map[int64]string{}
+	for i, p := range publishers {
+		history[int64(i+1)] = p
+	}
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		q := req.URL.Query()
+		if req.Method == "DELETE" {
+			start, _ := strconv.ParseInt(q.Get("start"), 10, 64)
+			end, _ := strconv.ParseInt(q.Get("end"), 10, 64)
+			*deletes = append(*deletes, [2]int64{start, end})
+			for tt := range history {
+				if tt > start && tt <= end {
+					delete(history, tt)
+				}
+			}
+			return stubResponse(req, `{"status": 200, "error": false, "error_message": ""}`), nil
+		}
+
+		max, _ := strconv.Atoi(q.Get("max"))
+		end, _ := strconv.ParseInt(q.Get("end"), 10, 64)
+		items := []interface{}{}
+		for tt := end; tt <= int64(len(publishers)) && len(items) < max; tt++ {
+			if p, ok := history[tt]; ok {
+				var message interface{} = "m" + strconv.FormatInt(tt, 10)
+				if m, ok := messages[tt]; ok {
+					message = m
+				}
+				items = append(items, map[string]interface{}{
+					"message":   message,
+					"timetoken": strconv.FormatInt(tt, 10),
+					"uuid":      p,
+					"meta":      map[string]interface{}{"publisher": p},
+				})
+			}
+		}
+		ch := req.URL.Opaque[strings.LastIndex(req.URL.Opaque, "/")+1:]
+		body, _ := json.Marshal(map[string]interface{}{"status": 200, "error": false, "channels": map[string]interface{}{ch: items}})
+		return stubResponse(req, string(body)), nil
+	})})
+
+	return pn
+}
+
+func TestPurgeMessagesRanges(t *testing.T) {
+	assert := assert.New(t)
+
+	publishers := []string{"spam", "spam", "ok", "spam"}
+	for i := 0; i < 120; i++ {
+		publishers = append(publishers, "spam")
+	}
+	publishers = append(publishers, "ok")
+	deletes := [][2]int64{}
+	pn := newPurgeTestPubNub(publishers, nil, &deletes)
+	defer pn.Destroy()
+
+	res, err := pn.PurgeMessages(nil, PurgeMessagesOptions{
+		Channels:       []string{"ch"},
+		Match:          PurgeByPublisher("spam"),
+		DeleteInterval: time.Millisecond,
+	})
+	assert.Nil(err)
+	assert.Equal(125, res.Scanned)
+	assert.Equal(123, res.Matched)
+	assert.Equal(123, res.Deleted)
+	assert.Equal([][2]int64{{0, 2}, {3, 124}}, deletes)
+	assert.Equal(2, len(res.Ranges))
+	assert.Equal([]string{"1", "2"}, res.Ranges[0].Timetokens)
+	assert.True(res.Ranges[1].Deleted)
+
+	res, err = pn.PurgeMessages(nil, PurgeMessagesOptions{Channels: []string{"ch"}, Match: PurgeByPublisher("spam")})
+	assert.Nil(err)
+	assert.Equal(2, res.Scanned)
+	assert.Equal(0, res.Matched)
+	assert.Equal(2, len(deletes))
+}
+
+func TestPurgeMessagesDryRun(t *testing.T) {
+	assert := assert.New(t)
+
+	deletes := [][2]int64{}
+	pn := newPurgeTestPubNub([]string{"a", "b", "a", "a"}, nil, &deletes)
+	defer pn.Destroy()
+
+	res, err := pn.PurgeMessages(nil, PurgeMessagesOptions{
+		Channels: []string{"ch"},
+		End:      2,
+		Match:    PurgeByMeta("publisher", "a"),
+		DryRun:   true,
+	})
+	assert.Nil(err)
+	assert.Equal(3, res.Scanned)
+	assert.Equal(2, res.Matched)
+	assert.Equal(0, res.Deleted)
+	assert.Equal([]PNPurgeRange{{Channel: "ch", Start: 2, End: 4, Timetokens: []string{"3", "4"}}}, res.Ranges)
+	assert.Equal(0, len(deletes))
+}
+
+func TestPurgeMessagesChunkedMessages(t *testing.T) {
+	assert := assert.New(t)
+
+	chunk := func(id string, index int, data string) interface{} {
+		return map[string]interface{}{chunkEnvelopeKey: map[string]interface{}{"id": id, "index": index, "count": 2, "data": data}}
+	}
+	// the chunks of the spam message are interleaved with an other message
+	deletes := [][2]int64{}
+	pn := newPurgeTestPubNub([]string{"ok", "spam", "ok", "spam", "ok"}, map[int64]interface{}{
+		2: chunk("a", 0, `"sp`),
+		4: chunk("a", 1, `am"`),
+	}, &deletes)
+	defer pn.Destroy()
+
+	res, err := pn.PurgeMessages(nil, PurgeMessagesOptions{
+		Channels:       []string{"ch"},
+		Match:          PurgeByPublisher("spam"),
+		DeleteInterval: time.Millisecond,
+	})
+	assert.Nil(err)
+	assert.Equal(4, res.Scanned)
+	assert.Equal(1, res.Matched)
+	assert.Equal(1, res.Deleted)
+	assert.Equal([][2]int64{{1, 2}, {3, 4}}, deletes)
+	assert.Equal([]string{"2"}, res.Ranges[0].Timetokens)
+	assert.Empty(res.Ranges[1].Timetokens)
+
+	res, err = pn.PurgeMessages(nil, PurgeMessagesOptions{Channels: []string{"ch"}, Match: PurgeByPublisher("spam")})
+	assert.Nil(err)
+	assert.Equal(3, res.Scanned)
+	assert.Equal(0, res.Matched)
+}
+
+func TestPurgeMessagesValidation(t *testing.T) {
+	assert := assert.New(t)
+	config := NewDemoConfig()
+	config.SecretKey = ""
+	pn := NewPubNub(config)
+
+	_, err := pn.PurgeMessages(nil, PurgeMessagesOptions{Match: PurgeByPublisher("a")})
+	assert.Contains(err.Error(), StrMissingChannel)
+	_, err = pn.PurgeMessages(nil, PurgeMessagesOptions{Channels: []string{"ch"}})
+	assert.Contains(err.Error(), "Missing Match")
+	_, err = pn.PurgeMessages(nil, PurgeMessagesOptions{Channels: []string{"ch"}, Match: PurgeByPublisher("a")})
+	assert.Contains(err.Error(), StrMissingSecretKey)
+}
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"strconv"
 
+	"github.com/pubnub/go/v7/crypto"
 	"github.com/pubnub/go/v7/pnerr"
 	"github.com/pubnub/go/v7/utils"
 
@@ -57,6 +58,16 @@ func (b *historyBuilder) End(end int64) *historyBuilder {
 	return b
 }
 
+// WithStart sets the Start Timetoken for the History request.
+func (b *historyBuilder) WithStart(start Timetoken) *historyBuilder {
+	return b.Start(start.Int64())
+}
+
+// WithEnd sets the End Timetoken for the History request.
+func (b *historyBuilder) WithEnd(end Timetoken) *historyBuilder {
+	return b.End(end.Int64())
+}
+
 // Count sets the number of items to return in the History request.
 func (b *historyBuilder) Count(count int) *historyBuilder {
 	b.opts.Count = count
@@ -88,6 +99,14 @@ func (b *historyBuilder) QueryParam(queryParam map[string]string) *historyBuilde
 	return b
 }
 
+// CryptoModule sets the cryptography module the messages are decrypted with,
+// overriding the Config one and the one resolved for the channel.
+func (b *historyBuilder) CryptoModule(cryptoModule crypto.CryptoModule) *historyBuilder {
+	b.opts.CryptoModule = cryptoModule
+
+	return b
+}
+
 // Transport sets the Transport for the History request.
 func (b *historyBuilder) Transport(tr http.RoundTripper) *historyBuilder {
 	b.opts.Transport = tr
@@ -114,6 +133,8 @@ type historyOpts struct {
 	QueryParam map[string]string
 	WithMeta   bool
 
+	CryptoModule crypto.CryptoModule
+
 	// default: 100
 	Count int
 
@@ -167,7 +188,7 @@ func (o *historyOpts) buildQuery() (*url.Values, error) {
 
 	q.Set("reverse", strconv.FormatBool(o.Reverse))
 	q.Set("include_token", strconv.FormatBool(o.IncludeTimetoken))
-	q.Set("include_meta", strconv.FormatBool(o.WithMeta))
+	q.Set("include_meta", strconv.FormatBool(o.pubnub.includeMeta(o.WithMeta)))
 
 	SetQueryParam(q, o.QueryParam)
 
@@ -212,6 +233,13 @@ func logAndCreateNewResponseParsingError(o *historyOpts, err error, jsonBody str
 	return e
 }
 
+func (o *historyOpts) cryptoModule() crypto.CryptoModule {
+	if o.CryptoModule != nil {
+		return o.CryptoModule
+	}
+	return o.pubnub.channelCryptoModule(o.Channel)
+}
+
 func getHistoryItemsWithoutTimetoken(historyResponseRaw []byte, o *historyOpts, err1 error, jsonBytes []byte) ([]HistoryResponseItem, *pnerr.ResponseParsingError) {
 	var historyResponseItems []interface{}
 	err0 := json.Unmarshal(historyResponseRaw, &historyResponseItems)
@@ -225,7 +253,7 @@ func getHistoryItemsWithoutTimetoken(historyResponseRaw []byte, o *historyOpts,
 
 	for i, v := range historyResponseItems {
 		o.pubnub.Config.Log.Println(v)
-		items[i].Message, items[i].Error = parseCipherInterface(v, o.pubnub.Config, o.pubnub.getCryptoModule())
+		items[i].Message, items[i].Error = parseCipherInterface(v, o.pubnub.Config, o.cryptoModule())
 	}
 	return items, nil
 }
@@ -238,13 +266,16 @@ func getHistoryItemsWithTimetoken(historyResponseItems []HistoryResponseItem, o
 	for i, v := range historyResponseItems {
 		if v.Message != nil {
 			o.pubnub.Config.Log.Println(v.Message)
-			items[i].Message, items[i].Error = parseCipherInterface(v.Message, o.pubnub.Config, o.pubnub.getCryptoModule())
+			items[i].Message, items[i].Error = parseCipherInterface(v.Message, o.pubnub.Config, o.cryptoModule())
 
 			o.pubnub.Config.Log.Println(v.Timetoken)
 			items[i].Timetoken = v.Timetoken
 
 			o.pubnub.Config.Log.Println(v.Meta)
-			items[i].Meta = v.Meta
+			items[i].Meta = requestedMeta(v.Meta, o.WithMeta)
+			if items[i].Error == nil {
+				items[i].Message, items[i].Error = decodeWithCodec(o.pubnub, items[i].Message, v.Meta)
+			}
 		} else {
 			b = true
 			break
@@ -597,3 +597,29 @@ func TestHistoryCryptoModuleWithNoEncryptedMessage(t *testing.T) {
 	pnconfig.CipherKey = ""
 }
 
+func TestHistoryRequestCryptoModule(t *testing.T) {
+	assert := assert.New(t)
+	pubnub := NewPubNub(NewDemoConfig())
+	module, err := crypto.NewAesCbcCryptoModule("enigma", true)
+	assert.Nil(err)
+
+	// Rust generated cipher text
+	jsonString := []byte(`[["UE5FRAFBQ1JIEALf+E65kseYJwTw2J6BUk9MePHiCcBCS+8ykXLkBIOA"],14991775432719844,14991868111600528]`)
+
+	opts := pubnub.initHistoryOpts()
+	opts.CryptoModule = module
+	resp, _, err := newHistoryResponse(jsonString, opts, fakeResponseState)
+	assert.Nil(err)
+	assert.Equal("test", resp.Messages[0].Message)
+	assert.Nil(resp.Messages[0].Error)
+
+	pubnub.Config.CryptoModuleResolver = func(channel string) crypto.CryptoModule {
+		if channel == "ch" {
+			return module
+		}
+		return nil
+	}
+	resp, _, err = newHistoryResponse(jsonString, pubnub.initHistoryOpts(), fakeResponseState)
+	assert.Nil(err)
+	assert.Equal("test", resp.Messages[0].Message)
+}
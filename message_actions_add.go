@@ -51,6 +51,11 @@ func (b *addMessageActionsBuilder) MessageTimetoken(timetoken string) *addMessag
 	return b
 }
 
+// WithMessageTimetoken sets the timetoken of the message the action is added to.
+func (b *addMessageActionsBuilder) WithMessageTimetoken(timetoken Timetoken) *addMessageActionsBuilder {
+	return b.MessageTimetoken(timetoken.String())
+}
+
 func (b *addMessageActionsBuilder) Action(action MessageAction) *addMessageActionsBuilder {
 	b.opts.Action = action
 
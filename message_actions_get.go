@@ -52,6 +52,16 @@ func (b *getMessageActionsBuilder) End(timetoken string) *getMessageActionsBuild
 	return b
 }
 
+// WithStart sets the Start Timetoken for the GetMessageActions request.
+func (b *getMessageActionsBuilder) WithStart(timetoken Timetoken) *getMessageActionsBuilder {
+	return b.Start(timetoken.String())
+}
+
+// WithEnd sets the End Timetoken for the GetMessageActions request.
+func (b *getMessageActionsBuilder) WithEnd(timetoken Timetoken) *getMessageActionsBuilder {
+	return b.End(timetoken.String())
+}
+
 func (b *getMessageActionsBuilder) Limit(limit int) *getMessageActionsBuilder {
 	b.opts.Limit = limit
 
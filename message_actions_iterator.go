@@ -0,0 +1,101 @@
+package pubnub
+
+import (
+	"sort"
+	"strconv"
+)
+
+// MessageActionsIterator pages through the message actions of a channel,
+// following the `more` links of the GetMessageActions responses. The actions
+// are yielded from the newest to the oldest.
+//
+//	it := pn.GetMessageActions().Channel("ch").Iterate(ctx, "reaction")
+//	for it.Next() {
+//		fmt.Println(it.Action().ActionValue)
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type MessageActionsIterator struct {
+	pageIterator
+	opts        getMessageActionsOpts
+	actionTypes map[string]bool
+	buffer      []PNMessageActionsResponse
+	current     PNMessageActionsResponse
+	done        bool
+}
+
+// Iterate returns an iterator over the message actions between Start and End.
+// When action types are given, only the actions of these types are yielded.
+// The iteration stops when the context is done.
+func (b *getMessageActionsBuilder) Iterate(ctx Context, actionTypes ...string) *MessageActionsIterator {
+	it := &MessageActionsIterator{
+		pageIterator: newPageIterator(ctx, b.opts.endpointOpts),
+		opts:         *b.opts,
+	}
+	it.opts.ctx = it.ctx
+	if len(actionTypes) > 0 {
+		it.actionTypes = make(map[string]bool, len(actionTypes))
+		for _, t := range actionTypes {
+			it.actionTypes[t] = true
+		}
+	}
+
+	return it
+}
+
+// Next advances the iterator to the next action, fetching the next page when needed.
+// It returns false when the actions are exhausted, on error or when the context is done.
+func (it *MessageActionsIterator) Next() bool {
+	if !it.next(func() int { return len(it.buffer) }, func() bool { return it.done }, it.fetchPage) {
+		return false
+	}
+	it.current = it.buffer[0]
+	it.buffer = it.buffer[1:]
+
+	return true
+}
+
+// Action returns the current action.
+func (it *MessageActionsIterator) Action() PNMessageActionsResponse {
+	return it.current
+}
+
+// Err returns the error which stopped the iteration, nil when the actions were exhausted.
+func (it *MessageActionsIterator) Err() error {
+	return it.err
+}
+
+func (it *MessageActionsIterator) fetchPage() {
+	opts := it.opts
+	resp, _, err := (&getMessageActionsBuilder{opts: &opts}).Execute()
+	if err != nil {
+		it.fail(err)
+		return
+	}
+
+	actions := []PNMessageActionsResponse{}
+	for _, action := range resp.Data {
+		if it.actionTypes == nil || it.actionTypes[action.ActionType] {
+			actions = append(actions, action)
+		}
+	}
+	sort.SliceStable(actions, func(i, j int) bool {
+		ti, _ := strconv.ParseInt(actions[i].ActionTimetoken, 10, 64)
+		tj, _ := strconv.ParseInt(actions[j].ActionTimetoken, 10, 64)
+		return ti > tj
+	})
+	it.buffer = append(it.buffer, actions...)
+
+	if len(resp.Data) == 0 || resp.More.Start == "" || resp.More.Start == it.opts.Start {
+		it.done = true
+		return
+	}
+	it.opts.Start = resp.More.Start
+	if resp.More.End != "" {
+		it.opts.End = resp.More.End
+	}
+	if resp.More.Limit > 0 {
+		it.opts.Limit = resp.More.Limit
+	}
+}
@@ -0,0 +1,85 @@
+package pubnub
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newMessageActionsIteratorTestPubNub(queries *[]string) *PubNub {
+	pn := NewPubNub(NewDemoConfig())
+
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		q := req.URL.Query()
+		*queries = append(*queries, q.Get("start")+"|"+q.Get("end")+"|"+q.Get("limit"))
+		switch q.Get("start") {
+		case "":
+			return stubResponse(req, `{"status":200,"data":[
+				{"type":"reaction","value":"a","uuid":"u1","actionTimetoken":"30","messageTimetoken":"1"},
+				{"type":"receipt","value":"read","uuid":"u2","actionTimetoken":"40","messageTimetoken":"1"}],
+				"more":{"url":"/v1/message-actions/demo/channel/ch?start=30&end=5&limit=2","start":"30","end":"5","limit":2}}`), nil
+		case "30":
+			return stubResponse(req, `{"status":200,"data":[
+				{"type":"reaction","value":"b","uuid":"u1","actionTimetoken":"10","messageTimetoken":"1"},
+				{"type":"reaction","value":"c","uuid":"u3","actionTimetoken":"20","messageTimetoken":"1"}],
+				"more":{"url":"/v1/message-actions/demo/channel/ch?start=10&end=5&limit=2","start":"10","end":"5","limit":2}}`), nil
+		default:
+			return stubResponse(req, `{"status":200,"data":[]}`), nil
+		}
+	})})
+
+	return pn
+}
+
+func TestMessageActionsIteratorFollowsMore(t *testing.T) {
+	assert := assert.New(t)
+
+	queries := []string{}
+	pn := newMessageActionsIteratorTestPubNub(&queries)
+	defer pn.Destroy()
+
+	it := pn.GetMessageActions().Channel("ch").End("5").Limit(2).Iterate(context.Background())
+	timetokens := []string{}
+	for it.Next() {
+		timetokens = append(timetokens, it.Action().ActionTimetoken)
+	}
+	assert.Nil(it.Err())
+	assert.Equal([]string{"40", "30", "20", "10"}, timetokens)
+	assert.Equal([]string{"|5|2", "30|5|2", "10|5|2"}, queries)
+}
+
+func TestMessageActionsIteratorFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	queries := []string{}
+	pn := newMessageActionsIteratorTestPubNub(&queries)
+	defer pn.Destroy()
+
+	it := pn.GetMessageActions().Channel("ch").Iterate(nil, "reaction")
+	values := []string{}
+	for it.Next() {
+		assert.Equal("reaction", it.Action().ActionType)
+		values = append(values, it.Action().ActionValue)
+	}
+	assert.Nil(it.Err())
+	assert.Equal([]string{"a", "c", "b"}, values)
+}
+
+func TestMessageActionsIteratorContextCancelled(t *testing.T) {
+	assert := assert.New(t)
+
+	queries := []string{}
+	pn := newMessageActionsIteratorTestPubNub(&queries)
+	defer pn.Destroy()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	it := pn.GetMessageActions().Channel("ch").Iterate(ctx)
+	assert.True(it.Next())
+	cancel()
+	assert.False(it.Next())
+	assert.Equal(context.Canceled, it.Err())
+	assert.Equal(1, len(queries))
+}
@@ -51,6 +51,16 @@ func (b *removeMessageActionsBuilder) ActionTimetoken(timetoken string) *removeM
 	return b
 }
 
+// WithMessageTimetoken sets the timetoken of the message the action is removed from.
+func (b *removeMessageActionsBuilder) WithMessageTimetoken(timetoken Timetoken) *removeMessageActionsBuilder {
+	return b.MessageTimetoken(timetoken.String())
+}
+
+// WithActionTimetoken sets the timetoken of the action to remove.
+func (b *removeMessageActionsBuilder) WithActionTimetoken(timetoken Timetoken) *removeMessageActionsBuilder {
+	return b.ActionTimetoken(timetoken.String())
+}
+
 // QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
 func (b *removeMessageActionsBuilder) QueryParam(queryParam map[string]string) *removeMessageActionsBuilder {
 	b.opts.QueryParam = queryParam
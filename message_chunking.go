@@ -0,0 +1,516 @@
+package pubnub
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pubnub/go/v7/crypto"
+	"github.com/pubnub/go/v7/pnerr"
+	"github.com/pubnub/go/v7/utils"
+)
+
+// chunkEnvelopeKey is the key of the envelope wrapping each chunk of a chunked message.
+const chunkEnvelopeKey = "pn_chunk"
+
+// defaultPublishChunkSize is the max size in bytes of the data carried by a single chunk.
+const defaultPublishChunkSize = 16 * 1024
+
+// maxChunkCount is the max number of chunks of a chunked message, published or received.
+const maxChunkCount = 1024
+
+// chunkEnvelope is a single part of a chunked message.
+// {"pn_chunk": {"id": "3b7b1c8e-...", "index": 0, "count": 3, "data": "..."}}
+type chunkEnvelope struct {
+	ID    string `json:"id"`
+	Index int    `json:"index"`
+	Count int    `json:"count"`
+	Data  string `json:"data"`
+}
+
+func (c chunkEnvelope) toMessage() map[string]interface{} {
+	return map[string]interface{}{
+		chunkEnvelopeKey: map[string]interface{}{
+			"id":    c.ID,
+			"index": c.Index,
+			"count": c.Count,
+			"data":  c.Data,
+		},
+	}
+}
+
+// parseChunkEnvelope checks if the received message is a chunk of a chunked message.
+func parseChunkEnvelope(message interface{}) (chunkEnvelope, bool) {
+	var chunk chunkEnvelope
+
+	msg, ok := message.(map[string]interface{})
+	if !ok || len(msg) != 1 {
+		return chunk, false
+	}
+	envelope, ok := msg[chunkEnvelopeKey].(map[string]interface{})
+	if !ok {
+		return chunk, false
+	}
+	if chunk.ID, ok = envelope["id"].(string); !ok || chunk.ID == "" {
+		return chunk, false
+	}
+	if chunk.Data, ok = envelope["data"].(string); !ok {
+		return chunk, false
+	}
+	index, ok := envelope["index"].(float64)
+	if !ok || index != math.Trunc(index) {
+		return chunk, false
+	}
+	count, ok := envelope["count"].(float64)
+	if !ok || count != math.Trunc(count) {
+		return chunk, false
+	}
+	// the count sizes the buffer of the chunks, it is bounded before the conversion
+	if count <= 0 || count > maxChunkCount || index < 0 || index >= count {
+		return chunk, false
+	}
+	chunk.Index = int(index)
+	chunk.Count = int(count)
+
+	return chunk, true
+}
+
+// splitIntoChunks splits the payload into parts of at most size bytes
+// without breaking multi-byte characters.
+func splitIntoChunks(payload string, size int) []string {
+	if size < utf8.UTFMax {
+		size = utf8.UTFMax
+	}
+	chunks := []string{}
+	for len(payload) > size {
+		end := size
+		for end > 0 && !utf8.RuneStart(payload[end]) {
+			end--
+		}
+		chunks = append(chunks, payload[:end])
+		payload = payload[end:]
+	}
+	if len(payload) > 0 {
+		chunks = append(chunks, payload)
+	}
+
+	return chunks
+}
+
+// chunkedPayload serializes and (when a crypto module is available) encrypts
+// the message as a whole, before it is split into chunks.
+func (o *publishOpts) chunkedPayload() (string, error) {
+	if module := o.cryptoModule(); module != nil {
+		return serializeAndEncrypt(module, o.Message, o.Serialize)
+	}
+	if o.Serialize {
+		jsonEncBytes, errEnc := json.Marshal(o.Message)
+		if errEnc != nil {
+			return "", errEnc
+		}
+		return string(jsonEncBytes), nil
+	}
+	if serializedMsg, ok := o.Message.(string); ok {
+		return serializedMsg, nil
+	}
+
+	return "", pnerr.NewBuildRequestError("chunkedPayload: Message is not JSON serialized.")
+}
+
+// executeChunkedPublish publishes the message as a series of ordered chunks
+// sharing the same envelope ID. Messages which fit into a single chunk are
+// published as is. The response carries the timetoken of the first chunk.
+func executeChunkedPublish(o *publishOpts) (*PublishResponse, StatusResponse, error) {
+	if err := o.validate(); err != nil {
+		return emptyPublishResponse, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+	}
+
+	payload, err := o.chunkedPayload()
+	if err != nil {
+		return emptyPublishResponse, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+	}
+
+	size := o.ChunkSize
+	if size <= 0 {
+		size = defaultPublishChunkSize
+	}
+
+	if len(payload) <= size {
+		rawJSON, status, err := executeRequest(o)
+		if err != nil {
+			return emptyPublishResponse, status, err
+		}
+		return newPublishResponse(rawJSON, status)
+	}
+
+	parts := splitIntoChunks(payload, size)
+	if len(parts) > maxChunkCount {
+		err := newValidationError(o, fmt.Sprintf("%s: %d chunks, max %d", StrMessageTooLarge, len(parts), maxChunkCount))
+		return emptyPublishResponse, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+	}
+	id := utils.UUID()
+	o.pubnub.Config.Log.Printf("publishing %d chunks with id %s\n", len(parts), id)
+
+	var first *PublishResponse
+	var status StatusResponse
+	published := make([]int64, 0, len(parts))
+	for i, part := range parts {
+		chunkOpts := *o
+		chunkOpts.Message = chunkEnvelope{ID: id, Index: i, Count: len(parts), Data: part}.toMessage()
+		chunkOpts.Serialize = true
+		chunkOpts.UsePost = true
+		chunkOpts.isChunk = true
+
+		rawJSON, s, err := executeRequest(&chunkOpts)
+		if err == nil {
+			var resp *PublishResponse
+			if resp, s, err = newPublishResponse(rawJSON, s); err == nil {
+				if first == nil {
+					first = resp
+				}
+				status = s
+				published = append(published, resp.Timestamp)
+				continue
+			}
+		}
+		if len(published) == 0 {
+			return emptyPublishResponse, s, err
+		}
+		chunkErr := &ChunkedPublishError{ID: id, Index: i, Count: len(parts), Timetokens: published, Err: err}
+		chunkErr.Deleted, chunkErr.CleanupErr = o.deleteChunks(published)
+		return emptyPublishResponse, s, chunkErr
+	}
+
+	return first, status, nil
+}
+
+// ChunkedPublishError is returned when a chunked publish fails after some of
+// its chunks were published. The published chunks are deleted from the history
+// when possible, the subscribers drop the incomplete set after the timeout.
+type ChunkedPublishError struct {
+	// ID is the ID of the chunk set.
+	ID string
+	// Index is the index of the chunk which failed, out of Count chunks.
+	Index int
+	Count int
+	// Timetokens are the timetokens of the published chunks.
+	Timetokens []int64
+	// Deleted is true when the published chunks were deleted from the history.
+	Deleted bool
+	// CleanupErr is the error which prevented the deletion of the published chunks.
+	CleanupErr error
+	// Err is the error of the failed chunk.
+	Err error
+}
+
+func (e *ChunkedPublishError) Error() string {
+	msg := fmt.Sprintf("chunked publish %s failed at chunk %d of %d: %s", e.ID, e.Index+1, e.Count, e.Err)
+	if e.CleanupErr != nil {
+		return fmt.Sprintf("%s; %d published chunks not deleted: %s", msg, len(e.Timetokens), e.CleanupErr)
+	}
+	if e.Deleted {
+		return fmt.Sprintf("%s; %d published chunks deleted", msg, len(e.Timetokens))
+	}
+	return msg
+}
+
+func (e *ChunkedPublishError) Unwrap() error {
+	return e.Err
+}
+
+// deleteChunks deletes the published chunks of a failed chunked publish. Nothing
+// is deleted when the chunks were not stored. DeleteMessages requires the secret key.
+func (o *publishOpts) deleteChunks(timetokens []int64) (bool, error) {
+	if o.setShouldStore && !o.ShouldStore {
+		return false, nil
+	}
+	for _, tt := range timetokens {
+		// start is exclusive
+		_, _, err := o.pubnub.DeleteMessagesWithContext(o.ctx).
+			Channel(o.Channel).
+			Start(tt - 1).
+			End(tt).
+			Transport(o.Transport).
+			Execute()
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// decodeChunkedPayload turns the joined data of a complete chunk set back into the original message.
+func decodeChunkedPayload(data string, pnConf *Config, module crypto.CryptoModule) (interface{}, error) {
+	if module != nil {
+		return parseCipherInterface(data, pnConf, module)
+	}
+	var intf interface{}
+	if err := json.Unmarshal([]byte(data), &intf); err != nil {
+		pnConf.Log.Println("Unmarshal chunked payload: err", err)
+		return data, err
+	}
+
+	return intf, nil
+}
+
+type chunkSet struct {
+	parts     []string
+	received  int
+	timetoken int64
+	timer     *time.Timer
+}
+
+// chunkReassembler buffers the chunks of the chunked messages until every
+// part of a set is received. Incomplete sets are dropped after the timeout.
+type chunkReassembler struct {
+	sync.Mutex
+	timeout  time.Duration
+	sets     map[string]*chunkSet
+	onExpire func(channel, id string, received, count int)
+}
+
+func newChunkReassembler(timeout time.Duration, onExpire func(channel, id string, received, count int)) *chunkReassembler {
+	return &chunkReassembler{
+		timeout:  timeout,
+		sets:     make(map[string]*chunkSet),
+		onExpire: onExpire,
+	}
+}
+
+// add stores the chunk and returns the joined data and the timetoken of the
+// first chunk once the set is complete.
+func (r *chunkReassembler) add(channel string, chunk chunkEnvelope, timetoken int64) (string, int64, bool) {
+	key := channel + "/" + chunk.ID
+
+	r.Lock()
+	defer r.Unlock()
+
+	set, ok := r.sets[key]
+	if !ok {
+		set = &chunkSet{
+			parts: make([]string, chunk.Count),
+		}
+		if r.timeout > 0 {
+			set.timer = time.AfterFunc(r.timeout, func() {
+				r.expire(channel, chunk.ID, key)
+			})
+		}
+		r.sets[key] = set
+	}
+	if chunk.Count != len(set.parts) || set.parts[chunk.Index] != "" {
+		return "", 0, false
+	}
+	set.parts[chunk.Index] = chunk.Data
+	set.received++
+	if chunk.Index == 0 {
+		set.timetoken = timetoken
+	}
+	if set.received < len(set.parts) {
+		return "", 0, false
+	}
+
+	if set.timer != nil {
+		set.timer.Stop()
+	}
+	delete(r.sets, key)
+
+	return strings.Join(set.parts, ""), set.timetoken, true
+}
+
+func (r *chunkReassembler) expire(channel, id, key string) {
+	r.Lock()
+	set, ok := r.sets[key]
+	if ok {
+		delete(r.sets, key)
+	}
+	r.Unlock()
+
+	if ok && r.onExpire != nil {
+		r.onExpire(channel, id, set.received, len(set.parts))
+	}
+}
+
+func (r *chunkReassembler) destroy() {
+	r.Lock()
+	for key, set := range r.sets {
+		if set.timer != nil {
+			set.timer.Stop()
+		}
+		delete(r.sets, key)
+	}
+	r.Unlock()
+}
+
+// reassembleFetchChunks replaces the chunks of every complete chunk set in
+// the fetched items by a single item carrying the full message. The item
+// takes its position and metadata from the first chunk. Chunks of incomplete
+// sets, split over several pages, are returned as is with an error, the
+// FetchIterator joins them with the chunks of the next pages.
+func (o *fetchOpts) reassembleFetchChunks(channel string, items []FetchResponseItem, raw []interface{}) []FetchResponseItem {
+	type fetchChunkSet struct {
+		first      int
+		parts      []string
+		timetokens []string
+		received   int
+	}
+	sets := make(map[string]*fetchChunkSet)
+	chunks := make(map[int]chunkEnvelope)
+
+	for i := range items {
+		chunk, ok := parseChunkEnvelope(raw[i])
+		if !ok {
+			continue
+		}
+		set, ok := sets[chunk.ID]
+		if !ok {
+			set = &fetchChunkSet{first: -1, parts: make([]string, chunk.Count), timetokens: make([]string, chunk.Count)}
+			sets[chunk.ID] = set
+		}
+		if chunk.Count != len(set.parts) || set.parts[chunk.Index] != "" {
+			continue
+		}
+		set.parts[chunk.Index] = chunk.Data
+		set.timetokens[chunk.Index] = items[i].Timetoken
+		set.received++
+		if chunk.Index == 0 {
+			set.first = i
+		}
+		chunks[i] = chunk
+	}
+
+	if len(chunks) == 0 {
+		return items
+	}
+
+	result := make([]FetchResponseItem, 0, len(items))
+	for i, item := range items {
+		chunk, ok := chunks[i]
+		if !ok {
+			result = append(result, item)
+			continue
+		}
+		set := sets[chunk.ID]
+		if set.received < len(set.parts) || set.first < 0 {
+			item.Error = fmt.Errorf("incomplete chunked message %s: received %d of %d chunks", chunk.ID, set.received, len(set.parts))
+			item.chunk = &chunk
+			result = append(result, item)
+			continue
+		}
+		if i != set.first {
+			continue
+		}
+		item.ChunkTimetokens = set.timetokens
+		result = append(result, o.decodeChunkedItem(channel, item, strings.Join(set.parts, "")))
+	}
+
+	return result
+}
+
+// decodeChunkedItem sets the message of the item of the first chunk of a set to the joined data of the set.
+func (o *fetchOpts) decodeChunkedItem(channel string, item FetchResponseItem, data string) FetchResponseItem {
+	item.Message, item.Error = decodeChunkedPayload(data, o.pubnub.Config, o.cryptoModule(channel))
+	item.chunk = nil
+	if item.Error == nil {
+		item.Message, item.Error = decodeWithCodec(o.pubnub, item.Message, item.Meta)
+	}
+	if filesPayload, okFile := item.Message.(map[string]interface{}); okFile {
+		f, m := ParseFileInfo(filesPayload)
+		if f.Name != "" && f.ID != "" {
+			item.File = f
+			item.Files = ParseFileList(filesPayload)
+			item.Message = m
+		}
+	}
+	return item
+}
+
+type fetchStitchSet struct {
+	parts      []string
+	timetokens []string
+	received   int
+	first      *FetchResponseItem
+	// items are the incomplete items of the set, yielded as is when the set isn't completed
+	items []FetchResponseItem
+}
+
+// fetchChunkStitcher joins the chunk sets split over several Fetch pages.
+type fetchChunkStitcher struct {
+	opts *fetchOpts
+	sets map[string]*fetchStitchSet
+	// order of the sets of each channel, by first received chunk
+	order map[string][]string
+}
+
+func newFetchChunkStitcher(opts *fetchOpts) *fetchChunkStitcher {
+	return &fetchChunkStitcher{
+		opts:  opts,
+		sets:  make(map[string]*fetchStitchSet),
+		order: make(map[string][]string),
+	}
+}
+
+// add returns the item when it isn't a chunk of an incomplete set, or the
+// reassembled item once the last chunk of the set is added.
+func (s *fetchChunkStitcher) add(channel string, item FetchResponseItem) (FetchResponseItem, bool) {
+	if item.chunk == nil {
+		return item, true
+	}
+	chunk := *item.chunk
+	key := channel + "/" + chunk.ID
+
+	set, ok := s.sets[key]
+	if !ok {
+		set = &fetchStitchSet{parts: make([]string, chunk.Count), timetokens: make([]string, chunk.Count)}
+		s.sets[key] = set
+		s.order[channel] = append(s.order[channel], key)
+	}
+	if chunk.Count != len(set.parts) || set.parts[chunk.Index] != "" {
+		return item, true
+	}
+	set.parts[chunk.Index] = chunk.Data
+	set.timetokens[chunk.Index] = item.Timetoken
+	set.received++
+	set.items = append(set.items, item)
+	if chunk.Index == 0 {
+		first := item
+		set.first = &first
+	}
+	if set.received < len(set.parts) {
+		return FetchResponseItem{}, false
+	}
+
+	s.remove(channel, key)
+	set.first.ChunkTimetokens = set.timetokens
+	return s.opts.decodeChunkedItem(channel, *set.first, strings.Join(set.parts, "")), true
+}
+
+// flush returns the items of the sets of the channel which couldn't be completed.
+func (s *fetchChunkStitcher) flush(channel string) []FetchResponseItem {
+	items := []FetchResponseItem{}
+	for _, key := range s.order[channel] {
+		set := s.sets[key]
+		for _, item := range set.items {
+			item.Error = fmt.Errorf("incomplete chunked message %s: received %d of %d chunks", item.chunk.ID, set.received, len(set.parts))
+			item.chunk = nil
+			items = append(items, item)
+		}
+		delete(s.sets, key)
+	}
+	delete(s.order, channel)
+	return items
+}
+
+func (s *fetchChunkStitcher) remove(channel, key string) {
+	delete(s.sets, key)
+	order := s.order[channel]
+	for i, k := range order {
+		if k == key {
+			s.order[channel] = append(order[:i:i], order[i+1:]...)
+			break
+		}
+	}
+}
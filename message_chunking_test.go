@@ -0,0 +1,311 @@
+package pubnub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func stubResponse(req *http.Request, body string) *http.Response {
+	return &http.Response{
+		Request:    req,
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+		Header:     make(http.Header),
+	}
+}
+
+func TestSplitIntoChunks(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"abcd", "efgh", "ij"}, splitIntoChunks("abcdefghij", 4))
+	assert.Equal([]string{"abcdefg"}, splitIntoChunks("abcdefg", 10))
+	assert.Equal([]string{}, splitIntoChunks("", 10))
+
+	chunks := splitIntoChunks("aéééé", 4)
+	assert.Equal("aéééé", strings.Join(chunks, ""))
+	for _, c := range chunks {
+		assert.True(len(c) <= 4)
+		assert.Equal(c, string([]rune(c)))
+	}
+}
+
+func TestParseChunkEnvelope(t *testing.T) {
+	assert := assert.New(t)
+
+	var msg interface{}
+	json.Unmarshal([]byte(`{"pn_chunk":{"id":"abc","index":1,"count":3,"data":"xyz"}}`), &msg)
+
+	chunk, ok := parseChunkEnvelope(msg)
+	assert.True(ok)
+	assert.Equal(chunkEnvelope{ID: "abc", Index: 1, Count: 3, Data: "xyz"}, chunk)
+
+	json.Unmarshal([]byte(`{"pn_chunk":{"id":"abc","index":3,"count":3,"data":"xyz"}}`), &msg)
+	_, ok = parseChunkEnvelope(msg)
+	assert.False(ok)
+
+	json.Unmarshal([]byte(`{"pn_chunk":{"id":"abc","index":0,"count":1,"data":"xyz"},"other":1}`), &msg)
+	_, ok = parseChunkEnvelope(msg)
+	assert.False(ok)
+
+	_, ok = parseChunkEnvelope("pn_chunk")
+	assert.False(ok)
+
+	for _, invalid := range []string{
+		`{"pn_chunk":{"id":"x","index":0,"count":1e15,"data":""}}`,
+		`{"pn_chunk":{"id":"x","index":0,"count":1025,"data":""}}`,
+		`{"pn_chunk":{"id":"x","index":0,"count":2.5,"data":""}}`,
+		`{"pn_chunk":{"id":"x","index":0.5,"count":2,"data":""}}`,
+	} {
+		json.Unmarshal([]byte(invalid), &msg)
+		_, ok = parseChunkEnvelope(msg)
+		assert.False(ok, invalid)
+	}
+}
+
+func TestChunkedPublishTooManyChunks(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	defer pn.Destroy()
+
+	requests := 0
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return stubResponse(req, `[1,"Sent","15000000000000000"]`), nil
+	})})
+
+	_, status, err := pn.Publish().Channel("ch").Message(strings.Repeat("a", maxChunkCount*16)).Chunked(true).ChunkSize(16).Execute()
+	assert.Contains(err.Error(), StrMessageTooLarge)
+	assert.Equal(err, status.Error)
+	assert.Equal(0, requests)
+}
+
+func TestChunkReassemblerOutOfOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	r := newChunkReassembler(time.Minute, nil)
+
+	_, _, complete := r.add("ch", chunkEnvelope{ID: "a", Index: 2, Count: 3, Data: "3"}, 30)
+	assert.False(complete)
+	_, _, complete = r.add("ch", chunkEnvelope{ID: "a", Index: 0, Count: 3, Data: "1"}, 10)
+	assert.False(complete)
+	_, _, complete = r.add("ch", chunkEnvelope{ID: "a", Index: 0, Count: 3, Data: "1"}, 10)
+	assert.False(complete)
+	data, tt, complete := r.add("ch", chunkEnvelope{ID: "a", Index: 1, Count: 3, Data: "2"}, 20)
+	assert.True(complete)
+	assert.Equal("123", data)
+	assert.Equal(int64(10), tt)
+	assert.Empty(r.sets)
+}
+
+func TestChunkReassemblerTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	var expiredID string
+	var received, count int
+	r := newChunkReassembler(10*time.Millisecond, func(channel, id string, rcv, cnt int) {
+		expiredID, received, count = id, rcv, cnt
+		wg.Done()
+	})
+
+	_, _, complete := r.add("ch", chunkEnvelope{ID: "a", Index: 0, Count: 2, Data: "1"}, 10)
+	assert.False(complete)
+	wg.Wait()
+
+	assert.Equal("a", expiredID)
+	assert.Equal(1, received)
+	assert.Equal(2, count)
+	r.Lock()
+	assert.Empty(r.sets)
+	r.Unlock()
+}
+
+func TestFetchResponseReassemblesChunks(t *testing.T) {
+	assert := assert.New(t)
+
+	jsonString := []byte(`{"status": 200, "error": false, "error_message": "", "channels": {"test":[{"message":"before","timetoken":"1"},{"message":{"pn_chunk":{"id":"a","index":0,"count":2,"data":"{\"text\":\"he"}},"timetoken":"2","uuid":"u1"},{"message":{"pn_chunk":{"id":"a","index":1,"count":2,"data":"llo\"}"}},"timetoken":"3","uuid":"u1"},{"message":{"pn_chunk":{"id":"b","index":1,"count":2,"data":"x"}},"timetoken":"4"}]}}`)
+
+	resp, _, err := newFetchResponse(jsonString, initFetchOpts(""), fakeResponseState)
+	assert.Nil(err)
+
+	items := resp.Messages["test"]
+	assert.Equal(3, len(items))
+	assert.Equal("before", items[0].Message)
+	assert.Equal(map[string]interface{}{"text": "hello"}, items[1].Message)
+	assert.Equal("2", items[1].Timetoken)
+	assert.Equal("u1", items[1].UUID)
+	assert.Equal([]string{"2", "3"}, items[1].ChunkTimetokens)
+	assert.Nil(items[1].Error)
+	assert.Equal("4", items[2].Timetoken)
+	assert.NotNil(items[2].Error)
+}
+
+func TestChunkedPublishWithCipher(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewDemoConfig()
+	config.CipherKey = "enigma"
+	pn := NewPubNub(config)
+	defer pn.Destroy()
+
+	var mu sync.Mutex
+	bodies := []string{}
+	timetoken := int64(15000000000000000)
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(req.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal("POST", req.Method)
+		bodies = append(bodies, string(body))
+		timetoken++
+		return stubResponse(req, fmt.Sprintf(`[1,"Sent","%d"]`, timetoken)), nil
+	})})
+
+	message := map[string]interface{}{"text": strings.Repeat("large ", 100)}
+	resp, _, err := pn.Publish().Channel("ch").Message(message).Chunked(true).ChunkSize(100).Execute()
+	assert.Nil(err)
+	assert.Equal(int64(15000000000000001), resp.Timestamp)
+	assert.True(len(bodies) > 1)
+
+	r := newChunkReassembler(0, nil)
+	var data string
+	var complete bool
+	for i, body := range bodies {
+		var envelope interface{}
+		assert.Nil(json.Unmarshal([]byte(body), &envelope))
+		chunk, ok := parseChunkEnvelope(envelope)
+		assert.True(ok)
+		assert.Equal(i, chunk.Index)
+		assert.Equal(len(bodies), chunk.Count)
+		data, _, complete = r.add("ch", chunk, int64(i))
+	}
+	assert.True(complete)
+
+	decoded, err := decodeChunkedPayload(data, pn.Config, pn.getCryptoModule())
+	assert.Nil(err)
+	assert.Equal(message, decoded)
+}
+
+func TestChunkedPublishSmallMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	defer pn.Destroy()
+
+	requests := 0
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		assert.Equal("GET", req.Method)
+		assert.False(strings.Contains(req.URL.String(), chunkEnvelopeKey))
+		return stubResponse(req, `[1,"Sent","15000000000000000"]`), nil
+	})})
+
+	resp, _, err := pn.Publish().Channel("ch").Message("hi").Chunked(true).Execute()
+	assert.Nil(err)
+	assert.Equal(int64(15000000000000000), resp.Timestamp)
+	assert.Equal(1, requests)
+}
+
+func TestFetchIteratorStitchesChunksAcrossPages(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	defer pn.Destroy()
+
+	history := []map[string]interface{}{
+		{"message": "before", "timetoken": "1"},
+		{"message": map[string]interface{}{"pn_chunk": map[string]interface{}{"id": "a", "index": 0, "count": 2, "data": `{"text":"he`}}, "timetoken": "2", "uuid": "u1"},
+		{"message": map[string]interface{}{"pn_chunk": map[string]interface{}{"id": "a", "index": 1, "count": 2, "data": `llo"}`}}, "timetoken": "3", "uuid": "u1"},
+		{"message": map[string]interface{}{"pn_chunk": map[string]interface{}{"id": "b", "index": 1, "count": 2, "data": "x"}}, "timetoken": "4"},
+		{"message": "after", "timetoken": "5"},
+	}
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		q := req.URL.Query()
+		start, _ := strconv.ParseInt(q.Get("start"), 10, 64)
+		items := []interface{}{}
+		for _, item := range history {
+			tt, _ := strconv.ParseInt(item["timetoken"].(string), 10, 64)
+			if start == 0 || tt < start {
+				items = append(items, item)
+			}
+		}
+		if len(items) > 2 {
+			items = items[len(items)-2:]
+		}
+		body, _ := json.Marshal(map[string]interface{}{"status": 200, "channels": map[string]interface{}{"ch": items}})
+		return stubResponse(req, string(body)), nil
+	})})
+
+	it := pn.Fetch().Channels([]string{"ch"}).Count(2).Iterate(context.Background())
+	items := []FetchResponseItem{}
+	for it.Next() {
+		items = append(items, it.Item())
+	}
+	assert.Nil(it.Err())
+
+	assert.Equal(4, len(items))
+	assert.Equal("after", items[0].Message)
+	assert.Equal(map[string]interface{}{"text": "hello"}, items[1].Message)
+	assert.Equal("2", items[1].Timetoken)
+	assert.Equal([]string{"2", "3"}, items[1].ChunkTimetokens)
+	assert.Nil(items[1].Error)
+	assert.Equal("before", items[2].Message)
+	assert.Equal("4", items[3].Timetoken)
+	assert.NotNil(items[3].Error)
+}
+
+func TestChunkedPublishFailureDeletesPublishedChunks(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewDemoConfig()
+	config.SecretKey = "secret"
+	pn := NewPubNub(config)
+	defer pn.Destroy()
+
+	publishes := 0
+	deleted := []string{}
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == "DELETE" {
+			deleted = append(deleted, req.URL.Query().Get("start")+"-"+req.URL.Query().Get("end"))
+			return stubResponse(req, `{"status":200,"error":false}`), nil
+		}
+		publishes++
+		if publishes == 3 {
+			resp := stubResponse(req, `{"status":400,"error":true,"message":"Invalid"}`)
+			resp.StatusCode = 400
+			return resp, nil
+		}
+		return stubResponse(req, fmt.Sprintf(`[1,"Sent","1500000000000000%d"]`, publishes)), nil
+	})})
+
+	message := map[string]interface{}{"text": strings.Repeat("large ", 100)}
+	_, _, err := pn.Publish().Channel("ch").Message(message).Chunked(true).ChunkSize(100).Execute()
+
+	chunkErr, ok := err.(*ChunkedPublishError)
+	assert.True(ok)
+	assert.Equal(2, chunkErr.Index)
+	assert.Equal([]int64{15000000000000001, 15000000000000002}, chunkErr.Timetokens)
+	assert.True(chunkErr.Deleted)
+	assert.Nil(chunkErr.CleanupErr)
+	assert.Equal([]string{"15000000000000000-15000000000000001", "15000000000000001-15000000000000002"}, deleted)
+	assert.Contains(err.Error(), "failed at chunk 3 of")
+}
@@ -57,6 +57,15 @@ func (b *messageCountsBuilder) ChannelsTimetoken(channelsTimetoken []int64) *mes
 	return b
 }
 
+// WithChannelsTimetoken sets the timetokens, in order of the channels list.
+func (b *messageCountsBuilder) WithChannelsTimetoken(channelsTimetoken []Timetoken) *messageCountsBuilder {
+	timetokens := make([]int64, len(channelsTimetoken))
+	for i, tt := range channelsTimetoken {
+		timetokens[i] = tt.Int64()
+	}
+	return b.ChannelsTimetoken(timetokens)
+}
+
 // QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
 func (b *messageCountsBuilder) QueryParam(queryParam map[string]string) *messageCountsBuilder {
 	b.opts.QueryParam = queryParam
@@ -0,0 +1,51 @@
+package pubnub
+
+// pageIterator is the state shared by the iterators paging through the
+// responses of an endpoint: the context stopping the iteration and the error
+// which stopped it. The iterators buffer the entries of the fetched pages.
+type pageIterator struct {
+	ctx Context
+	err error
+}
+
+// newPageIterator returns the state of an iterator stopped by ctx, the context
+// of the endpoint when nil, or the one of the client.
+func newPageIterator(ctx Context, opts endpointOpts) pageIterator {
+	if ctx == nil {
+		ctx = opts.ctx
+	}
+	if ctx == nil {
+		ctx = opts.pubnub.ctx
+	}
+	return pageIterator{ctx: ctx}
+}
+
+// next fetches pages until entries are buffered. It returns false on error,
+// when the context is done or when exhausted reports there is no page left.
+func (it *pageIterator) next(buffered func() int, exhausted func() bool, fetchPage func()) bool {
+	for buffered() == 0 {
+		if it.err != nil || exhausted() || it.ctxErr() {
+			return false
+		}
+		fetchPage()
+	}
+	return !it.ctxErr()
+}
+
+func (it *pageIterator) ctxErr() bool {
+	select {
+	case <-it.ctx.Done():
+		it.err = it.ctx.Err()
+		return true
+	default:
+		return false
+	}
+}
+
+// fail stops the iteration with the error of a page request, or with the
+// error of the context when it is done.
+func (it *pageIterator) fail(err error) {
+	if !it.ctxErr() {
+		it.err = err
+	}
+}
@@ -0,0 +1,45 @@
+package pubnub
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageIterator(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+
+	// a nil context falls back to the one of the client
+	it := newPageIterator(nil, endpointOpts{pubnub: pn})
+	assert.Equal(pn.ctx, it.ctx)
+
+	pages := [][]int{{1, 2}, {}, {3}}
+	buffer := []int{}
+	fetchPage := func() {
+		buffer = append(buffer, pages[0]...)
+		pages = pages[1:]
+	}
+	exhausted := func() bool { return len(pages) == 0 }
+	values := []int{}
+	for it.next(func() int { return len(buffer) }, exhausted, fetchPage) {
+		values = append(values, buffer[0])
+		buffer = buffer[1:]
+	}
+	assert.Equal([]int{1, 2, 3}, values)
+	assert.Nil(it.err)
+
+	it.fail(errors.New("page failed"))
+	assert.False(it.next(func() int { return 0 }, exhausted, fetchPage))
+	assert.Equal("page failed", it.err.Error())
+
+	// the error of a done context is reported instead of the one of the request
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	it = newPageIterator(ctx, endpointOpts{pubnub: pn})
+	it.fail(errors.New("page failed"))
+	assert.Equal(context.Canceled, it.err)
+}
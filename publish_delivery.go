@@ -0,0 +1,316 @@
+package pubnub
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pubnub/go/v7/utils"
+)
+
+const (
+	// deliveryAckIDKey is the meta key flagging a message for delivery tracking.
+	deliveryAckIDKey = "pn_ack_id"
+	// deliveryAckToKey is the meta key carrying the channel the acks should be sent to.
+	deliveryAckToKey = "pn_ack_to"
+	// deliveryAckSignalKey is the key of the Signal payload acknowledging a message.
+	deliveryAckSignalKey = "pn_ack"
+
+	defaultDeliveryAckTimeout = 10
+	// deliveryDedupWindow is how long the acknowledged ack IDs are remembered to drop resent duplicates.
+	deliveryDedupWindow = 10 * time.Minute
+)
+
+// StrInvalidDeliveryMeta shows `Meta must be a map to track delivery` message
+const StrInvalidDeliveryMeta = "Meta must be a map[string]interface{} to track delivery"
+
+// ErrDeliveryTimeout is the error of a PNDelivery when the expected receivers did not acknowledge the message after all the retries.
+var ErrDeliveryTimeout = errors.New("pubnub: delivery not acknowledged")
+
+// PNDelivery tracks the acknowledgements of a message published with ExecuteWithDelivery.
+type PNDelivery struct {
+	sync.RWMutex
+	// Timetoken of the first publish of the message.
+	Timetoken int64
+	// ID of the acknowledgement, carried in the meta of the message.
+	ID string
+
+	expected []string
+	acked    map[string]bool
+	attempts int
+	done     chan struct{}
+	err      error
+	callback func(*PNDelivery)
+}
+
+// Done is closed when the delivery is acknowledged by the expected receivers or when the retries are exhausted.
+func (d *PNDelivery) Done() <-chan struct{} {
+	return d.done
+}
+
+// Wait blocks until the delivery is resolved or the context is done.
+// It returns nil when all expected receivers acknowledged the message.
+func (d *PNDelivery) Wait(ctx Context) error {
+	if ctx == nil {
+		<-d.done
+		return d.Err()
+	}
+	select {
+	case <-d.done:
+		return d.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Err returns ErrDeliveryTimeout when the delivery failed and nil otherwise.
+func (d *PNDelivery) Err() error {
+	d.RLock()
+	defer d.RUnlock()
+	return d.err
+}
+
+// Attempts returns the number of times the message was published.
+func (d *PNDelivery) Attempts() int {
+	d.RLock()
+	defer d.RUnlock()
+	return d.attempts
+}
+
+// Acknowledged returns the UUIDs of the receivers which acknowledged the message.
+func (d *PNDelivery) Acknowledged() []string {
+	d.RLock()
+	defer d.RUnlock()
+	uuids := make([]string, 0, len(d.acked))
+	for uuid := range d.acked {
+		uuids = append(uuids, uuid)
+	}
+	return uuids
+}
+
+// Missing returns the expected receivers which did not acknowledge the message yet.
+func (d *PNDelivery) Missing() []string {
+	d.RLock()
+	defer d.RUnlock()
+	return d.missing()
+}
+
+func (d *PNDelivery) missing() []string {
+	missing := []string{}
+	for _, uuid := range d.expected {
+		if !d.acked[uuid] {
+			missing = append(missing, uuid)
+		}
+	}
+	return missing
+}
+
+// ack records the ack of the receiver, returns true if the delivery is complete.
+func (d *PNDelivery) ack(uuid string) bool {
+	d.Lock()
+	defer d.Unlock()
+	d.acked[uuid] = true
+	if len(d.expected) == 0 {
+		return true
+	}
+	return len(d.missing()) == 0
+}
+
+func (d *PNDelivery) resolve(err error) {
+	d.Lock()
+	select {
+	case <-d.done:
+		d.Unlock()
+		return
+	default:
+	}
+	d.err = err
+	close(d.done)
+	d.Unlock()
+
+	if d.callback != nil {
+		d.callback(d)
+	}
+}
+
+// deliveryManager tracks the pending deliveries on the publisher side, and
+// acknowledges the tracked messages on the subscriber side.
+type deliveryManager struct {
+	sync.Mutex
+	pubnub   *PubNub
+	pending  map[string]*PNDelivery
+	received map[string]time.Time
+}
+
+func newDeliveryManager(pubnub *PubNub) *deliveryManager {
+	return &deliveryManager{
+		pubnub:   pubnub,
+		pending:  make(map[string]*PNDelivery),
+		received: make(map[string]time.Time),
+	}
+}
+
+// handleAck resolves the pending delivery acknowledged by the signal.
+// Returns true when the signal was an ack and was consumed.
+func (m *deliveryManager) handleAck(signal *PNMessage) bool {
+	if !m.pubnub.requestReplyManager.isInbox(signal.Channel) {
+		return false
+	}
+	payload, ok := signal.Message.(map[string]interface{})
+	if !ok || len(payload) != 1 {
+		return false
+	}
+	id, ok := payload[deliveryAckSignalKey].(string)
+	if !ok {
+		return false
+	}
+
+	m.Lock()
+	delivery, ok := m.pending[id]
+	m.Unlock()
+	if !ok {
+		m.pubnub.Config.Log.Println("dropping late or unknown ack", id, signal.Publisher)
+		return true
+	}
+
+	if delivery.ack(signal.Publisher) {
+		m.Lock()
+		delete(m.pending, id)
+		m.Unlock()
+		delivery.resolve(nil)
+	}
+	return true
+}
+
+// acknowledge sends the ack of a received message carrying the delivery tracking flag.
+// Returns false if the message is a resent duplicate of an already acknowledged message.
+func (m *deliveryManager) acknowledge(payload subscribeMessage) bool {
+	id, ok := metaValue(payload.UserMetadata, deliveryAckIDKey)
+	if !ok {
+		return true
+	}
+	ackTo, ok := metaValue(payload.UserMetadata, deliveryAckToKey)
+	if !ok || payload.IssuingClientID == m.pubnub.Config.UUID {
+		return true
+	}
+
+	now := time.Now()
+	m.Lock()
+	for k, t := range m.received {
+		if now.Sub(t) > deliveryDedupWindow {
+			delete(m.received, k)
+		}
+	}
+	_, duplicate := m.received[id]
+	m.received[id] = now
+	m.Unlock()
+
+	if !m.pubnub.Config.SuppressDeliveryAcks {
+		go func() {
+			// the ack is a plain JSON signal, the schemas of the user don't apply to it
+			ack := m.pubnub.Signal().
+				Channel(ackTo).
+				Message(map[string]interface{}{deliveryAckSignalKey: id})
+			ack.opts.isInternal = true
+			_, _, err := ack.Execute()
+			if err != nil {
+				m.pubnub.Config.Log.Println("delivery ack failed", id, err)
+			}
+		}()
+	}
+
+	return !duplicate
+}
+
+// track publishes the message with the delivery tracking flag and starts waiting for the acks.
+func (m *deliveryManager) track(b *publishBuilder) (*PNDelivery, StatusResponse, error) {
+	meta, ok := copyMeta(b.opts.Meta)
+	if !ok {
+		err := newValidationError(b.opts, StrInvalidDeliveryMeta)
+		return nil, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+	}
+
+	ctx := b.opts.ctx
+	if ctx == nil {
+		ctx = m.pubnub.ctx
+	}
+	ackTo, err := m.pubnub.requestReplyManager.ensureInbox(ctx)
+	if err != nil {
+		return nil, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+	}
+
+	delivery := &PNDelivery{
+		ID:       utils.UUID(),
+		expected: b.opts.AckReceivers,
+		acked:    make(map[string]bool),
+		done:     make(chan struct{}),
+		callback: b.opts.onDelivery,
+	}
+	meta[deliveryAckIDKey] = delivery.ID
+	meta[deliveryAckToKey] = ackTo
+	b.opts.Meta = meta
+
+	m.Lock()
+	m.pending[delivery.ID] = delivery
+	m.Unlock()
+
+	resp, status, err := b.Execute()
+	if err != nil {
+		m.Lock()
+		delete(m.pending, delivery.ID)
+		m.Unlock()
+		return nil, status, err
+	}
+	delivery.Lock()
+	delivery.Timetoken = resp.Timestamp
+	delivery.attempts = 1
+	delivery.Unlock()
+
+	go m.retry(b, delivery)
+
+	return delivery, status, nil
+}
+
+// retry resends the message every AckTimeout seconds until it is acknowledged
+// or AckRetries are exhausted.
+func (m *deliveryManager) retry(b *publishBuilder, delivery *PNDelivery) {
+	timeout := b.opts.AckTimeout
+	if timeout <= 0 {
+		timeout = defaultDeliveryAckTimeout
+	}
+
+	for {
+		timer := time.NewTimer(time.Duration(timeout) * time.Second)
+		select {
+		case <-delivery.done:
+			timer.Stop()
+			return
+		case <-m.pubnub.ctx.Done():
+			timer.Stop()
+			m.expire(delivery, ErrRequestCancelled)
+			return
+		case <-timer.C:
+		}
+
+		if delivery.Attempts() > b.opts.AckRetries {
+			m.expire(delivery, fmt.Errorf("%w: missing acks from %v", ErrDeliveryTimeout, delivery.Missing()))
+			return
+		}
+
+		resend := *b.opts
+		if _, _, err := (&publishBuilder{opts: &resend}).Execute(); err != nil {
+			m.pubnub.Config.Log.Println("delivery resend failed", delivery.ID, err)
+		}
+		delivery.Lock()
+		delivery.attempts++
+		delivery.Unlock()
+	}
+}
+
+func (m *deliveryManager) expire(delivery *PNDelivery, err error) {
+	m.Lock()
+	delete(m.pending, delivery.ID)
+	m.Unlock()
+	delivery.resolve(err)
+}
@@ -0,0 +1,184 @@
+package pubnub
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeliveryAcknowledged(t *testing.T) {
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	var publishedMeta map[string]interface{}
+	pn := newRequestReplyTestPubNub(t, func(channel string, meta map[string]interface{}) {
+		mu.Lock()
+		publishedMeta = meta
+		mu.Unlock()
+	})
+	defer pn.Destroy()
+
+	callbacks := make(chan *PNDelivery, 1)
+	delivery, _, err := pn.Publish().
+		Channel("ch").
+		Message("hi").
+		Meta(map[string]interface{}{"a": "b"}).
+		AckReceivers([]string{"u1", "u2"}).
+		OnDelivery(func(d *PNDelivery) { callbacks <- d }).
+		ExecuteWithDelivery()
+	assert.Nil(err)
+	assert.Equal(int64(15000000000000001), delivery.Timetoken)
+
+	mu.Lock()
+	assert.Equal("b", publishedMeta["a"])
+	assert.Equal(delivery.ID, publishedMeta[deliveryAckIDKey])
+	assert.Equal(requestInboxPrefix+pn.Config.UUID, publishedMeta[deliveryAckToKey])
+	mu.Unlock()
+
+	inbox := requestInboxPrefix + pn.Config.UUID
+	ack := map[string]interface{}{deliveryAckSignalKey: delivery.ID}
+	assert.False(pn.deliveryManager.handleAck(&PNMessage{Channel: "other", Message: ack, Publisher: "u1"}))
+	assert.True(pn.deliveryManager.handleAck(&PNMessage{Channel: inbox, Message: ack, Publisher: "u1"}))
+	assert.Equal([]string{"u2"}, delivery.Missing())
+	assert.True(pn.deliveryManager.handleAck(&PNMessage{Channel: inbox, Message: ack, Publisher: "u2"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(delivery.Wait(ctx))
+	assert.Equal(delivery, <-callbacks)
+	assert.Equal(1, delivery.Attempts())
+	assert.Empty(pn.deliveryManager.pending)
+}
+
+func TestDeliveryRetriesAndTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	publishes := 0
+	pn := newRequestReplyTestPubNub(t, func(channel string, meta map[string]interface{}) {
+		mu.Lock()
+		publishes++
+		mu.Unlock()
+	})
+	defer pn.Destroy()
+
+	delivery, _, err := pn.Publish().
+		Channel("ch").
+		Message("hi").
+		AckReceivers([]string{"u1"}).
+		AckTimeout(1).
+		AckRetries(1).
+		ExecuteWithDelivery()
+	assert.Nil(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = delivery.Wait(ctx)
+	assert.True(errors.Is(err, ErrDeliveryTimeout))
+	assert.Equal([]string{"u1"}, delivery.Missing())
+	assert.Equal(2, delivery.Attempts())
+
+	mu.Lock()
+	assert.Equal(2, publishes)
+	mu.Unlock()
+	assert.Empty(pn.deliveryManager.pending)
+}
+
+func TestDeliveryInvalidMeta(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+
+	delivery, _, err := pn.Publish().Channel("ch").Message("hi").Meta("meta").ExecuteWithDelivery()
+	assert.Nil(delivery)
+	assert.Contains(err.Error(), StrInvalidDeliveryMeta)
+}
+
+func TestDeliveryAcknowledgeDeduplicates(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	defer pn.Destroy()
+
+	signals := make(chan string, 2)
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Opaque, "/signal/") {
+			signals <- req.URL.Opaque
+		}
+		return stubResponse(req, `[1,"Sent","15000000000000000"]`), nil
+	})})
+
+	// the acks are not validated against the schemas of the user
+	assert.Nil(pn.RegisterSchema("*", []byte(testChatSchema)))
+
+	payload := subscribeMessage{
+		IssuingClientID: "publisher",
+		UserMetadata: map[string]interface{}{
+			deliveryAckIDKey: "id1",
+			deliveryAckToKey: "pn-inbox-publisher",
+		},
+	}
+	assert.True(pn.deliveryManager.acknowledge(payload))
+	select {
+	case signal := <-signals:
+		assert.Contains(signal, "/pn-inbox-publisher/")
+	case <-time.After(time.Second):
+		assert.Fail("expected an ack")
+	}
+
+	// resent duplicates are acknowledged again but not announced
+	assert.False(pn.deliveryManager.acknowledge(payload))
+	select {
+	case <-signals:
+	case <-time.After(time.Second):
+		assert.Fail("expected an ack")
+	}
+
+	// own and untracked messages are not acknowledged
+	payload.IssuingClientID = pn.Config.UUID
+	assert.True(pn.deliveryManager.acknowledge(payload))
+	assert.True(pn.deliveryManager.acknowledge(subscribeMessage{IssuingClientID: "publisher"}))
+	select {
+	case <-signals:
+		assert.Fail("unexpected ack")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDeliveryNotAcknowledgedWhenDecryptionFails(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewDemoConfig()
+	config.CipherKey = "enigma"
+	pn := NewPubNub(config)
+	defer pn.Destroy()
+
+	signals := make(chan string, 1)
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Opaque, "/signal/") {
+			signals <- req.URL.Opaque
+		}
+		return stubResponse(req, `[1,"Sent","15000000000000000"]`), nil
+	})})
+
+	processSubscribePayload(pn.subscriptionManager, subscribeMessage{
+		Channel:         "ch",
+		Payload:         "not encrypted",
+		IssuingClientID: "publisher",
+		UserMetadata: map[string]interface{}{
+			deliveryAckIDKey: "id1",
+			deliveryAckToKey: "pn-inbox-publisher",
+		},
+	})
+	select {
+	case <-signals:
+		assert.Fail("unexpected ack of a message which couldn't be decrypted")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
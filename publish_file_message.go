@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"github.com/pubnub/go/v7/crypto"
 	"github.com/pubnub/go/v7/pnerr"
 	"github.com/pubnub/go/v7/utils"
 	"io/ioutil"
@@ -105,6 +106,14 @@ func (b *publishFileMessageBuilder) Transport(tr http.RoundTripper) *publishFile
 	return b
 }
 
+// CryptoModule sets the cryptography module the message is encrypted with,
+// overriding the Config one and the one resolved for the channel.
+func (b *publishFileMessageBuilder) CryptoModule(cryptoModule crypto.CryptoModule) *publishFileMessageBuilder {
+	b.opts.CryptoModule = cryptoModule
+
+	return b
+}
+
 // QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
 func (b *publishFileMessageBuilder) QueryParam(queryParam map[string]string) *publishFileMessageBuilder {
 	b.opts.QueryParam = queryParam
@@ -137,6 +146,14 @@ type publishFileMessageOpts struct {
 	FileName       string
 	QueryParam     map[string]string
 	Transport      http.RoundTripper
+	CryptoModule   crypto.CryptoModule
+}
+
+func (o *publishFileMessageOpts) cryptoModule() crypto.CryptoModule {
+	if o.CryptoModule != nil {
+		return o.CryptoModule
+	}
+	return o.pubnub.channelCryptoModule(o.Channel)
 }
 
 func (o *publishFileMessageOpts) validate() error {
@@ -201,7 +218,7 @@ func (o *publishFileMessageOpts) buildPath() (string, error) {
 		}
 	}
 
-	if o.pubnub.getCryptoModule() != nil {
+	if o.cryptoModule() != nil {
 		var msg string
 		var p *publishBuilder
 		if o.context() != nil {
@@ -210,6 +227,8 @@ func (o *publishFileMessageOpts) buildPath() (string, error) {
 			p = newPublishBuilder(o.pubnub)
 		}
 		p.opts.Message = o.Message
+		p.opts.Channel = o.Channel
+		p.opts.CryptoModule = o.CryptoModule
 
 		msg, errJSONMarshal := p.opts.encryptProcessing()
 		if errJSONMarshal != nil {
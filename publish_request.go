@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strconv"
 
+	"github.com/pubnub/go/v7/crypto"
 	"github.com/pubnub/go/v7/pnerr"
 	"github.com/pubnub/go/v7/utils"
 
@@ -34,11 +35,27 @@ type publishOpts struct {
 	DoNotReplicate bool
 	QueryParam     map[string]string
 
+	Chunked   bool
+	ChunkSize int
+
+	Codec        Codec
+	CryptoModule crypto.CryptoModule
+
+	AckReceivers []string
+	AckTimeout   int
+	AckRetries   int
+	onDelivery   func(*PNDelivery)
+
 	Transport http.RoundTripper
 
 	// nil hacks
 	setTTL         bool
 	setShouldStore bool
+
+	// the message is a chunk of an already serialized and encrypted payload
+	isChunk bool
+	// the message is already encoded with the codec and validated
+	isEncoded bool
 }
 
 // PublishResponse is the response after the execution on Publish and Fire operations.
@@ -154,6 +171,70 @@ func (b *publishBuilder) DoNotReplicate(repl bool) *publishBuilder {
 	return b
 }
 
+// Chunked when true splits the messages larger than the chunk size into ordered
+// chunks, which are reassembled by the subscribers and in Fetch results.
+// The serialized (and encrypted) payload is split, each chunk is sent using HTTP POST.
+func (b *publishBuilder) Chunked(chunked bool) *publishBuilder {
+	b.opts.Chunked = chunked
+
+	return b
+}
+
+// ChunkSize sets the max size in bytes of the data carried by a single chunk (default 16KB).
+// Used only when Chunked is true.
+func (b *publishBuilder) ChunkSize(size int) *publishBuilder {
+	b.opts.ChunkSize = size
+
+	return b
+}
+
+// Codec sets the codec the message is encoded with before encryption, overriding the Config Codec.
+func (b *publishBuilder) Codec(codec Codec) *publishBuilder {
+	b.opts.Codec = codec
+
+	return b
+}
+
+// CryptoModule sets the cryptography module the message is encrypted with,
+// overriding the Config one and the one resolved for the channel.
+func (b *publishBuilder) CryptoModule(cryptoModule crypto.CryptoModule) *publishBuilder {
+	b.opts.CryptoModule = cryptoModule
+
+	return b
+}
+
+// AckReceivers sets the UUIDs of the receivers expected to acknowledge the message.
+// When empty the first acknowledgement resolves the delivery. Used only with ExecuteWithDelivery.
+func (b *publishBuilder) AckReceivers(uuids []string) *publishBuilder {
+	b.opts.AckReceivers = uuids
+
+	return b
+}
+
+// AckTimeout sets the time in seconds to wait for the acknowledgements before resending the message (default 10).
+// Used only with ExecuteWithDelivery.
+func (b *publishBuilder) AckTimeout(timeout int) *publishBuilder {
+	b.opts.AckTimeout = timeout
+
+	return b
+}
+
+// AckRetries sets the number of times the message is resent when it is not acknowledged in time.
+// Used only with ExecuteWithDelivery.
+func (b *publishBuilder) AckRetries(retries int) *publishBuilder {
+	b.opts.AckRetries = retries
+
+	return b
+}
+
+// OnDelivery sets the callback called once the delivery is acknowledged or has failed.
+// Used only with ExecuteWithDelivery.
+func (b *publishBuilder) OnDelivery(callback func(*PNDelivery)) *publishBuilder {
+	b.opts.onDelivery = callback
+
+	return b
+}
+
 // Transport sets the Transport for the Publish request.
 func (b *publishBuilder) Transport(tr http.RoundTripper) *publishBuilder {
 	b.opts.Transport = tr
@@ -170,7 +251,16 @@ func (b *publishBuilder) QueryParam(queryParam map[string]string) *publishBuilde
 
 // Execute runs the Publish request.
 func (b *publishBuilder) Execute() (*PublishResponse, StatusResponse, error) {
-	rawJSON, status, err := executeRequest(b.opts)
+	opts, err := b.opts.encodeWithCodec()
+	if err != nil {
+		return emptyPublishResponse, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+	}
+
+	if opts.Chunked {
+		return executeChunkedPublish(opts)
+	}
+
+	rawJSON, status, err := executeRequest(opts)
 	if err != nil {
 		return emptyPublishResponse, status, err
 	}
@@ -178,6 +268,13 @@ func (b *publishBuilder) Execute() (*PublishResponse, StatusResponse, error) {
 	return newPublishResponse(rawJSON, status)
 }
 
+// ExecuteWithDelivery runs the Publish request with the delivery tracking flag in the meta.
+// The subscribers acknowledge the message with a Signal, the returned PNDelivery is resolved
+// when the expected receivers have acknowledged it or once the AckRetries are exhausted.
+func (b *publishBuilder) ExecuteWithDelivery() (*PNDelivery, StatusResponse, error) {
+	return b.opts.pubnub.deliveryManager.track(b)
+}
+
 func (o *publishOpts) validate() error {
 	if o.config().PublishKey == "" {
 		return newValidationError(o, StrMissingPubKey)
@@ -195,16 +292,62 @@ func (o *publishOpts) validate() error {
 		return newValidationError(o, StrMissingMessage)
 	}
 
+	if !o.isChunk && !o.isEncoded {
+		if err := o.pubnub.schemaRegistry.validateOutgoing(o.Channel, o.Message, !o.Serialize); err != nil {
+			return newValidationError(o, err.Error())
+		}
+	}
+
 	return nil
 }
 
+// encodeWithCodec returns a copy of the opts with the message encoded by the codec
+// and the codec name in the meta. The opts are returned as is for JSON messages.
+func (o *publishOpts) encodeWithCodec() (*publishOpts, error) {
+	codec := o.pubnub.outgoingCodec(o.Codec)
+	if codec == nil {
+		return o, nil
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	meta, ok := copyMeta(o.Meta)
+	if !ok {
+		return nil, newValidationError(o, StrInvalidCodecMeta)
+	}
+	meta[codecMetaKey] = codec.Name()
+
+	payload, err := encodeWithCodec(codec, o.Message, !o.Serialize)
+	if err != nil {
+		return nil, pnerr.NewBuildRequestError(fmt.Sprintf("Codec %s: %s", codec.Name(), err.Error()))
+	}
+
+	encoded := *o
+	encoded.Message = payload
+	encoded.Meta = meta
+	encoded.Serialize = true
+	encoded.isEncoded = true
+
+	return &encoded, nil
+}
+
+func (o *publishOpts) cryptoModule() crypto.CryptoModule {
+	if o.isChunk {
+		return nil
+	}
+	if o.CryptoModule != nil {
+		return o.CryptoModule
+	}
+	return o.pubnub.channelCryptoModule(o.Channel)
+}
+
 func (o *publishOpts) encryptProcessing() (string, error) {
 	var msg string
 	var errJSONMarshal error
 
 	o.pubnub.Config.Log.Println("EncryptString: encrypting", fmt.Sprintf("%s", o.Message))
 	if o.pubnub.Config.DisablePNOtherProcessing {
-		if msg, errJSONMarshal = serializeEncryptAndSerialize(o.pubnub.getCryptoModule(), o.Message, o.Serialize); errJSONMarshal != nil {
+		if msg, errJSONMarshal = serializeEncryptAndSerialize(o.cryptoModule(), o.Message, o.Serialize); errJSONMarshal != nil {
 			o.pubnub.Config.Log.Printf("error in serializing: %v\n", errJSONMarshal)
 			return "", errJSONMarshal
 		}
@@ -218,7 +361,7 @@ func (o *publishOpts) encryptProcessing() (string, error) {
 
 			if ok {
 				o.pubnub.Config.Log.Println(ok, msgPart)
-				encMsg, errJSONMarshal := serializeAndEncrypt(o.pubnub.getCryptoModule(), msgPart, o.Serialize)
+				encMsg, errJSONMarshal := serializeAndEncrypt(o.cryptoModule(), msgPart, o.Serialize)
 				if errJSONMarshal != nil {
 					o.pubnub.Config.Log.Printf("error in serializing: %v\n", errJSONMarshal)
 					return "", errJSONMarshal
@@ -231,14 +374,14 @@ func (o *publishOpts) encryptProcessing() (string, error) {
 				}
 				msg = string(jsonEncBytes)
 			} else {
-				if msg, errJSONMarshal = serializeEncryptAndSerialize(o.pubnub.getCryptoModule(), o.Message, o.Serialize); errJSONMarshal != nil {
+				if msg, errJSONMarshal = serializeEncryptAndSerialize(o.cryptoModule(), o.Message, o.Serialize); errJSONMarshal != nil {
 					o.pubnub.Config.Log.Printf("error in serializing: %v\n", errJSONMarshal)
 					return "", errJSONMarshal
 				}
 			}
 			break
 		default:
-			if msg, errJSONMarshal = serializeEncryptAndSerialize(o.pubnub.getCryptoModule(), o.Message, o.Serialize); errJSONMarshal != nil {
+			if msg, errJSONMarshal = serializeEncryptAndSerialize(o.cryptoModule(), o.Message, o.Serialize); errJSONMarshal != nil {
 				o.pubnub.Config.Log.Printf("error in serializing: %v\n", errJSONMarshal)
 				return "", errJSONMarshal
 			}
@@ -261,7 +404,7 @@ func (o *publishOpts) buildPath() (string, error) {
 	var msg string
 	var errJSONMarshal error
 
-	if o.pubnub.getCryptoModule() != nil {
+	if o.cryptoModule() != nil {
 		if msg, errJSONMarshal = o.encryptProcessing(); errJSONMarshal != nil {
 			return "", errJSONMarshal
 		}
@@ -336,7 +479,7 @@ func (o *publishOpts) buildQuery() (*url.Values, error) {
 
 func (o *publishOpts) buildBody() ([]byte, error) {
 	if o.UsePost {
-		if o.pubnub.getCryptoModule() != nil {
+		if o.cryptoModule() != nil {
 			msg, errJSONMarshal := o.encryptProcessing()
 			if errJSONMarshal != nil {
 				return []byte{}, errJSONMarshal
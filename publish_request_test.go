@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"testing"
 
+	"github.com/pubnub/go/v7/crypto"
 	h "github.com/pubnub/go/v7/tests/helpers"
 	"github.com/stretchr/testify/assert"
 )
@@ -529,3 +530,35 @@ func TestPublishValidateSubscribeKey(t *testing.T) {
 
 	assert.Equal("pubnub/validation: pubnub: Publish: Missing Subscribe Key", opts.validate().Error())
 }
+
+func TestPublishCryptoModule(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.UseRandomInitializationVector = false
+	pn.Config.CipherKey = "enigma"
+	expected, err := newPublishBuilder(pn).Channel("ch").Message("yay!").opts.buildPath()
+	assert.Nil(err)
+	pn.Config.CipherKey = ""
+
+	module, err := crypto.NewLegacyCryptoModule("enigma", false)
+	assert.Nil(err)
+
+	path, err := newPublishBuilder(pn).Channel("ch").Message("yay!").CryptoModule(module).opts.buildPath()
+	assert.Nil(err)
+	assert.Equal(expected, path)
+
+	pn.Config.CryptoModuleResolver = func(channel string) crypto.CryptoModule {
+		if channel == "ch" {
+			return module
+		}
+		return nil
+	}
+	path, err = newPublishBuilder(pn).Channel("ch").Message("yay!").opts.buildPath()
+	assert.Nil(err)
+	assert.Equal(expected, path)
+
+	path, err = newPublishBuilder(pn).Channel("other").Message("yay!").opts.buildPath()
+	assert.Nil(err)
+	assert.Equal("/publish/demo/demo/0/other/0/%22yay%21%22", path)
+}
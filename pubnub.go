@@ -53,8 +53,22 @@ const (
 	StrMissingFileID = "Missing File ID"
 	// StrMissingFileName shows `Missing File Name` message
 	StrMissingFileName = "Missing File Name"
+	// StrMissingFile shows Missing File message
+	StrMissingFile = "Missing File"
+	// StrInvalidDownloadOffset shows `Invalid Offset` message
+	StrInvalidDownloadOffset = "Invalid Offset"
+	// StrDownloadOffsetEncrypted shows `Offset can't be used with an encrypted file` message
+	StrDownloadOffsetEncrypted = "Offset can't be used with an encrypted file"
+	// StrFileSizeMismatch shows `Unexpected file size` message
+	StrFileSizeMismatch = "Unexpected file size"
+	// StrFileContentTypeMismatch shows `Unexpected file content type` message
+	StrFileContentTypeMismatch = "Unexpected file content type"
+	// StrInvalidContentRange shows `Invalid content range` message
+	StrInvalidContentRange = "Invalid content range"
 	// StrMissingToken shows `Missing PAMv3 token` message
 	StrMissingToken = "Missing PAMv3 token"
+	// StrMessageTooLarge shows `Message too large` message
+	StrMessageTooLarge = "Message too large"
 )
 
 // PubNub No server connection will be established when you create a new PubNub object.
@@ -77,6 +91,9 @@ type PubNub struct {
 	tokenManager         *TokenManager
 	previousCipherKey    string
 	previousIvFlag       bool
+	requestReplyManager  *requestReplyManager
+	deliveryManager      *deliveryManager
+	schemaRegistry       *schemaRegistry
 }
 
 // TODO this needs to be tested
@@ -97,6 +114,17 @@ func (pn *PubNub) getCryptoModule() crypto.CryptoModule {
 	return nil
 }
 
+// channelCryptoModule returns the cryptography module of the channel, given by
+// Config.CryptoModuleResolver, or the Config one.
+func (pn *PubNub) channelCryptoModule(channel string) crypto.CryptoModule {
+	if resolver := pn.Config.CryptoModuleResolver; resolver != nil {
+		if module := resolver(channel); module != nil {
+			return module
+		}
+	}
+	return pn.getCryptoModule()
+}
+
 // Publish is used to send a message to all subscribers of a channel.
 func (pn *PubNub) Publish() *publishBuilder {
 	return newPublishBuilder(pn)
@@ -629,6 +657,16 @@ func (pn *PubNub) WhereNowWithContext(ctx Context) *whereNowBuilder {
 	return newWhereNowBuilderWithContext(pn, ctx)
 }
 
+// WhereNowBatch returns the channels of many UUIDs, the WhereNow requests are run concurrently.
+func (pn *PubNub) WhereNowBatch() *whereNowBatchBuilder {
+	return newWhereNowBatchBuilder(pn)
+}
+
+// WhereNowBatchWithContext returns the channels of many UUIDs, the WhereNow requests are run concurrently.
+func (pn *PubNub) WhereNowBatchWithContext(ctx Context) *whereNowBatchBuilder {
+	return newWhereNowBatchBuilderWithContext(pn, ctx)
+}
+
 // Time This function will return a 17 digit precision Unix epoch.
 func (pn *PubNub) Time() *timeBuilder {
 	return newTimeBuilder(pn)
@@ -669,6 +707,27 @@ func (pn *PubNub) SendFileWithContext(ctx Context) *sendFileBuilder {
 	return newSendFileBuilderWithContext(pn, ctx)
 }
 
+// SendFiles sends several files to a channel concurrently, with a message per file or a combined message.
+func (pn *PubNub) SendFiles() *sendFilesBuilder {
+	return newSendFilesBuilder(pn)
+}
+
+// SendFilesWithContext sends several files to a channel concurrently, with a message per file or a combined message.
+func (pn *PubNub) SendFilesWithContext(ctx Context) *sendFilesBuilder {
+	return newSendFilesBuilderWithContext(pn, ctx)
+}
+
+// ResumeSendFile resumes a SendFile from the state of its SendFileError. File or
+// Reader must be set again when the state is at the upload step.
+func (pn *PubNub) ResumeSendFile(state PendingFileSend) *sendFileBuilder {
+	return newResumeSendFileBuilder(pn, pn.ctx, state)
+}
+
+// ResumeSendFileWithContext resumes a SendFile from the state of its SendFileError.
+func (pn *PubNub) ResumeSendFileWithContext(ctx Context, state PendingFileSend) *sendFileBuilder {
+	return newResumeSendFileBuilder(pn, ctx, state)
+}
+
 // ListFiles Provides the ability to fetch all files in a channel.
 func (pn *PubNub) ListFiles() *listFilesBuilder {
 	return newListFilesBuilder(pn)
@@ -719,6 +778,45 @@ func (pn *PubNub) PublishFileMessageWithContext(ctx Context) *publishFileMessage
 	return newPublishFileMessageBuilderWithContext(pn, ctx)
 }
 
+// Request publishes the payload to the channel and waits for the matching reply or the context deadline.
+// The message meta carries a correlation ID and the inbox channel of this client, which is subscribed to on the first call.
+func (pn *PubNub) Request(ctx Context, channel string, payload interface{}) (*PNMessage, error) {
+	return pn.requestReplyManager.request(ctx, channel, payload)
+}
+
+// Reply publishes the payload as the reply to a message sent using Request.
+func (pn *PubNub) Reply(message *PNMessage, payload interface{}) (*PublishResponse, StatusResponse, error) {
+	return pn.requestReplyManager.reply(message, payload)
+}
+
+// RegisterSchema registers a JSON Schema document validating the messages sent to and received on the channels
+// matching the pattern. The pattern is a channel name, a wildcard `prefix.*` or `*` for all the channels.
+// A schema using keywords which aren't supported is rejected.
+func (pn *PubNub) RegisterSchema(channelPattern string, schema []byte) error {
+	validator, err := newJSONSchemaValidator(schema)
+	if err != nil {
+		return err
+	}
+	pn.schemaRegistry.register(channelPattern, validator)
+
+	return nil
+}
+
+// RegisterValidator registers a validator func for the messages sent to and received on the channels matching the pattern.
+func (pn *PubNub) RegisterValidator(channelPattern string, validator MessageValidator) {
+	pn.schemaRegistry.register(channelPattern, validator)
+}
+
+// UnregisterSchema removes the schemas and validators registered for the channel pattern.
+func (pn *PubNub) UnregisterSchema(channelPattern string) {
+	pn.schemaRegistry.unregister(channelPattern)
+}
+
+// SetInvalidMessageHandler sets the handler receiving the messages which failed the validation. Without a handler they are dropped.
+func (pn *PubNub) SetInvalidMessageHandler(handler InvalidMessageHandler) {
+	pn.schemaRegistry.setInvalidMessageHandler(handler)
+}
+
 // Destroy stops all open requests, removes listeners, closes heartbeats, and cleans up.
 func (pn *PubNub) Destroy() {
 	pn.Config.Log.Println("Calling Destroy")
@@ -745,7 +843,10 @@ func (pn *PubNub) Destroy() {
 	pn.requestWorkers.Close()
 	pn.Config.Log.Println("after close requestWorkers")
 	pn.tokenManager.CleanUp()
-	pn.client.CloseIdleConnections()
+	// the client is created with the first request
+	if pn.client != nil {
+		pn.client.CloseIdleConnections()
+	}
 
 }
 
@@ -798,6 +899,9 @@ func NewPubNub(pnconf *Config) *PubNub {
 	pn.jobQueue = make(chan *JobQItem)
 	pn.requestWorkers = pn.newNonSubQueueProcessor(pnconf.MaxWorkers, ctx)
 	pn.tokenManager = newTokenManager(pn, ctx)
+	pn.requestReplyManager = newRequestReplyManager(pn)
+	pn.deliveryManager = newDeliveryManager(pn)
+	pn.schemaRegistry = newSchemaRegistry()
 
 	return pn
 }
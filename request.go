@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"mime/multipart"
 	"net/http"
 	"net/url"
 	"time"
@@ -76,11 +75,86 @@ func buildBody(opts endpoint, url *url.URL) (io.Reader, error) {
 }
 
 func executeRequest(opts endpoint) ([]byte, StatusResponse, error) {
+	res, url, startTimestamp, status, err := sendRequest(opts)
+	if err != nil {
+		return nil, status, err
+	}
+
+	val, status, err := parseResponse(res, opts)
+	// Already wrapped error
+	if err != nil {
+		opts.config().Log.Println("res.StatusCode, status, err.Error()", res.StatusCode, status, err.Error())
+		return nil, status, err
+	}
+
+	elapsedTime := time.Since(startTimestamp)
+
+	manager := opts.telemetryManager()
+	manager.StoreLatency(elapsedTime.Seconds(), opts.operationType())
+
+	if opts.httpMethod() != "POSTFORM" {
+		opts.config().Log.Println("PNUnknownCategory", string(val), responseInfoOf(res, url, opts))
+	}
+	status = createStatus(PNUnknownCategory, string(val), responseInfoOf(res, url, opts), nil)
+
+	return val, status, nil
+}
+
+// executeStreamRequest runs the request like executeRequest but returns the
+// response with its body unread, the caller has to close it. The responses
+// other than 200 and 206 are returned as errors.
+func executeStreamRequest(opts endpoint) (*http.Response, StatusResponse, error) {
+	res, url, startTimestamp, status, err := sendRequest(opts)
+	if err != nil {
+		return nil, status, err
+	}
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
+		_, status, err = parseResponse(res, opts)
+		opts.config().Log.Println("res.StatusCode, status, err.Error()", res.StatusCode, status, err.Error())
+		return nil, status, err
+	}
+
+	elapsedTime := time.Since(startTimestamp)
+
+	manager := opts.telemetryManager()
+	manager.StoreLatency(elapsedTime.Seconds(), opts.operationType())
+
+	return res, createStatus(PNUnknownCategory, "", responseInfoOf(res, url, opts), nil), nil
+}
+
+func responseInfoOf(res *http.Response, url *url.URL, opts endpoint) ResponseInfo {
+	responseInfo := ResponseInfo{
+		StatusCode:       res.StatusCode,
+		OriginalResponse: res,
+		Operation:        opts.operationType(),
+		Origin:           url.Host,
+	}
+
+	if url.Scheme == "https" {
+		responseInfo.TLSEnabled = true
+	}
+
+	if uuid, ok := url.Query()["uuid"]; ok {
+		responseInfo.UUID = uuid[0]
+	}
+
+	if auth, ok := url.Query()["auth"]; ok {
+		responseInfo.AuthKey = auth[0]
+	}
+
+	return responseInfo
+}
+
+// sendRequest validates and sends the request of the endpoint, it returns the
+// response, the URL called and the time the request was started.
+func sendRequest(opts endpoint) (*http.Response, *url.URL, time.Time, StatusResponse, error) {
+	var startTimestamp time.Time
 	err := opts.validate()
 
 	if err != nil {
 		opts.config().Log.Println("PNUnknownCategory", err)
-		return nil,
+		return nil, nil, startTimestamp,
 			createStatus(PNUnknownCategory, "", ResponseInfo{}, err),
 			err
 	}
@@ -89,7 +163,7 @@ func executeRequest(opts endpoint) ([]byte, StatusResponse, error) {
 
 	if err != nil {
 		opts.config().Log.Println("PNUnknownCategory", err)
-		return nil,
+		return nil, nil, startTimestamp,
 			createStatus(PNUnknownCategory, "", ResponseInfo{}, err),
 			err
 	}
@@ -101,30 +175,35 @@ func executeRequest(opts endpoint) ([]byte, StatusResponse, error) {
 	if opts.httpMethod() == "POST" {
 		body, err := buildBody(opts, url)
 		if err != nil {
-			return nil, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+			return nil, nil, startTimestamp, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
 		}
 		req, err = newRequest("POST", url, body, opts.config().UseHTTP2)
 		req.Header.Set("Content-Type", "application/json")
 	} else if opts.httpMethod() == "POSTFORM" {
 
-		body, w, _, err := opts.buildBodyMultipartFileUpload()
+		body, contentType, contentLength, err := opts.buildBodyMultipartFileUpload()
 		if err != nil {
-			return nil, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+			return nil, nil, startTimestamp, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
 		}
 
-		req, err = newRequestForMultipartWriter("POST", url.RequestURI(), &body, w, opts.config().UseHTTP2)
+		req, err = newRequestForMultipartWriter("POST", url.RequestURI(), body, opts.config().UseHTTP2)
 		if err != nil {
+			body.Close()
 			opts.config().Log.Println("POST ERROR : ", err)
-			return nil, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+			return nil, nil, startTimestamp, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
 		}
 
-		req.Header.Set("Content-Type", w.FormDataContentType())
+		req.Header.Set("Content-Type", contentType)
+		// the body is streamed, it is sent chunked when its length is unknown
+		if contentLength >= 0 {
+			req.ContentLength = contentLength
+		}
 	} else if opts.httpMethod() == "DELETE" {
 		req, err = newRequest("DELETE", url, nil, opts.config().UseHTTP2)
 	} else if opts.httpMethod() == "PATCH" {
 		body, err := buildBody(opts, url)
 		if err != nil {
-			return nil, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+			return nil, nil, startTimestamp, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
 		}
 
 		req, err = newRequest("PATCH", url, body, opts.config().UseHTTP2)
@@ -134,11 +213,15 @@ func executeRequest(opts endpoint) ([]byte, StatusResponse, error) {
 
 	if err != nil {
 		opts.config().Log.Println("PNUnknownCategory", err, url)
-		return nil,
+		return nil, nil, startTimestamp,
 			createStatus(PNUnknownCategory, "", ResponseInfo{}, err),
 			err
 	}
 
+	for key, values := range opts.requestHeaders() {
+		req.Header[key] = values
+	}
+
 	ctx := opts.context()
 	if ctx != nil {
 		// with !go1.7 you can't assign context directly to a request,
@@ -149,10 +232,10 @@ func executeRequest(opts endpoint) ([]byte, StatusResponse, error) {
 
 	client := opts.client()
 
-	startTimestamp := time.Now()
+	startTimestamp = time.Now()
 
 	var res *http.Response
-	runRequestWorker := false
+	runRequestWorker := opts.useRequestWorkers()
 
 	switch opts.operationType() {
 	case PNPublishOperation, PNAccessManagerGrant:
@@ -176,51 +259,15 @@ func executeRequest(opts endpoint) ([]byte, StatusResponse, error) {
 		e := pnerr.NewConnectionError("Failed to execute request", err)
 
 		opts.config().Log.Println("PNUnknownCategory", e.Error(), url)
-		return nil,
+		return nil, nil, startTimestamp,
 			createStatus(PNUnknownCategory, "", ResponseInfo{}, e),
 			e
 	}
 
-	val, status, err := parseResponse(res, opts)
-	// Already wrapped error
-	if err != nil {
-		opts.config().Log.Println("res.StatusCode, status, err.Error()", res.StatusCode, status, err.Error())
-		return nil, status, err
-	}
-
-	elapsedTime := time.Since(startTimestamp)
-
-	manager := opts.telemetryManager()
-	manager.StoreLatency(elapsedTime.Seconds(), opts.operationType())
-
-	responseInfo := ResponseInfo{
-		StatusCode:       res.StatusCode,
-		OriginalResponse: res,
-		Operation:        opts.operationType(),
-		Origin:           url.Host,
-	}
-
-	if url.Scheme == "https" {
-		responseInfo.TLSEnabled = true
-	}
-
-	if uuid, ok := url.Query()["uuid"]; ok {
-		responseInfo.UUID = uuid[0]
-	}
-
-	if auth, ok := url.Query()["auth"]; ok {
-		responseInfo.AuthKey = auth[0]
-	}
-
-	if opts.httpMethod() != "POSTFORM" {
-		opts.config().Log.Println("PNUnknownCategory", string(val), responseInfo)
-	}
-	status = createStatus(PNUnknownCategory, string(val), responseInfo, nil)
-
-	return val, status, nil
+	return res, url, startTimestamp, StatusResponse{}, nil
 }
 
-func newRequestForMultipartWriter(method string, URL string, body io.Reader, writer *multipart.Writer, useHTTP2 bool) (*http.Request, error) {
+func newRequestForMultipartWriter(method string, URL string, body io.Reader, useHTTP2 bool) (*http.Request, error) {
 	req, err := http.NewRequest(method, URL, body)
 	if useHTTP2 {
 		req.Proto = "HTTP/2.0"
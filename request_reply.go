@@ -0,0 +1,214 @@
+package pubnub
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/pubnub/go/v7/pnerr"
+	"github.com/pubnub/go/v7/utils"
+)
+
+const (
+	// requestCorrelationIDKey is the meta key carrying the correlation ID of a request and its reply.
+	requestCorrelationIDKey = "pn_correlation_id"
+	// requestReplyToKey is the meta key carrying the inbox channel the reply should be sent to.
+	requestReplyToKey = "pn_reply_to"
+	// requestInboxPrefix is the prefix of the per-client inbox channel.
+	requestInboxPrefix = "pn-inbox-"
+)
+
+// StrMissingReplyTo shows `Message is not a request` message
+const StrMissingReplyTo = "Message is not a request, missing reply to channel or correlation ID"
+
+// ErrRequestCancelled is returned by Request when the PubNub instance is destroyed while waiting for the reply.
+var ErrRequestCancelled = errors.New("pubnub: request cancelled")
+
+// requestReplyManager correlates the replies received on the inbox channel
+// with the pending requests.
+type requestReplyManager struct {
+	sync.Mutex
+	pubnub *PubNub
+	inbox  string
+	// connecting is closed once the subscription including the inbox is connected
+	connecting chan struct{}
+	pending    map[string]chan *PNMessage
+}
+
+func newRequestReplyManager(pubnub *PubNub) *requestReplyManager {
+	return &requestReplyManager{
+		pubnub:  pubnub,
+		pending: make(map[string]chan *PNMessage),
+	}
+}
+
+// ensureInbox subscribes to the inbox channel of this client and waits for the
+// subscription to connect, so that no reply published afterwards is missed. The
+// inbox is added to the current subscription, the other channels keep their cursor.
+func (m *requestReplyManager) ensureInbox(ctx Context) (string, error) {
+	m.Lock()
+	subscribe := m.connecting == nil && (m.inbox == "" || !m.isInboxSubscribed())
+	if subscribe {
+		m.inbox = requestInboxPrefix + m.pubnub.Config.UUID
+		m.connecting = make(chan struct{})
+	}
+	inbox, connecting := m.inbox, m.connecting
+	m.Unlock()
+
+	if subscribe {
+		m.pubnub.Subscribe().Channels([]string{inbox}).Execute()
+	}
+	if connecting == nil {
+		return inbox, nil
+	}
+
+	select {
+	case <-connecting:
+		return inbox, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-m.pubnub.ctx.Done():
+		return "", ErrRequestCancelled
+	}
+}
+
+// connected is called by the subscription manager when the subscription is connected.
+func (m *requestReplyManager) connected() {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.connecting != nil && m.isInboxSubscribed() {
+		close(m.connecting)
+		m.connecting = nil
+	}
+}
+
+func (m *requestReplyManager) isInboxSubscribed() bool {
+	for _, ch := range m.pubnub.GetSubscribedChannels() {
+		if ch == m.inbox {
+			return true
+		}
+	}
+	return false
+}
+
+// isInbox returns true if the channel is the inbox channel of this client.
+func (m *requestReplyManager) isInbox(channel string) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.inbox != "" && channel == m.inbox
+}
+
+func (m *requestReplyManager) addPending(correlationID string) chan *PNMessage {
+	reply := make(chan *PNMessage, 1)
+	m.Lock()
+	m.pending[correlationID] = reply
+	m.Unlock()
+
+	return reply
+}
+
+func (m *requestReplyManager) removePending(correlationID string) {
+	m.Lock()
+	delete(m.pending, correlationID)
+	m.Unlock()
+}
+
+// handleReply routes a message received on the inbox channel to the pending request.
+// Returns true when the message was consumed, replies received after the request
+// timed out or was cancelled are dropped. The messages without correlation ID
+// aren't replies, they are announced to the listeners.
+func (m *requestReplyManager) handleReply(message *PNMessage) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.inbox == "" || message.Channel != m.inbox {
+		return false
+	}
+
+	correlationID, ok := metaValue(message.UserMetadata, requestCorrelationIDKey)
+	if !ok {
+		return false
+	}
+	if reply, ok := m.pending[correlationID]; ok {
+		delete(m.pending, correlationID)
+		reply <- message
+	} else {
+		m.pubnub.Config.Log.Println("dropping late or unknown reply", correlationID)
+	}
+
+	return true
+}
+
+func metaValue(meta interface{}, key string) (string, bool) {
+	metaMap, ok := meta.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	v, ok := metaMap[key].(string)
+
+	return v, ok && v != ""
+}
+
+func (m *requestReplyManager) request(ctx Context, channel string, payload interface{}) (*PNMessage, error) {
+	pn := m.pubnub
+	if ctx == nil {
+		ctx = pn.ctx
+	}
+
+	inbox, err := m.ensureInbox(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	correlationID := utils.UUID()
+	reply := m.addPending(correlationID)
+
+	_, _, err = pn.PublishWithContext(ctx).
+		Channel(channel).
+		Message(payload).
+		Meta(map[string]interface{}{
+			requestCorrelationIDKey: correlationID,
+			requestReplyToKey:       inbox,
+		}).
+		Execute()
+	if err != nil {
+		m.removePending(correlationID)
+		return nil, err
+	}
+
+	select {
+	case message := <-reply:
+		return message, nil
+	case <-ctx.Done():
+		m.removePending(correlationID)
+		return nil, ctx.Err()
+	case <-pn.ctx.Done():
+		m.removePending(correlationID)
+		return nil, ErrRequestCancelled
+	}
+}
+
+func (m *requestReplyManager) reply(message *PNMessage, payload interface{}) (*PublishResponse, StatusResponse, error) {
+	var replyTo, correlationID string
+	var ok bool
+
+	if message != nil {
+		replyTo, ok = metaValue(message.UserMetadata, requestReplyToKey)
+		if ok {
+			correlationID, ok = metaValue(message.UserMetadata, requestCorrelationIDKey)
+		}
+	}
+	if !ok {
+		err := pnerr.NewValidationError("Reply", StrMissingReplyTo)
+		return emptyPublishResponse, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+	}
+
+	return m.pubnub.Publish().
+		Channel(replyTo).
+		Message(payload).
+		Meta(map[string]interface{}{
+			requestCorrelationIDKey: correlationID,
+		}).
+		Execute()
+}
@@ -0,0 +1,179 @@
+package pubnub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newRequestReplyTestPubNub(t *testing.T, onPublish func(channel string, meta map[string]interface{})) *PubNub {
+	pn := NewPubNub(NewDemoConfig())
+
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		path := req.URL.Opaque
+		if strings.Contains(path, "/publish/") {
+			parts := strings.Split(path, "/")
+			channel, _ := url.PathUnescape(parts[7])
+			var meta map[string]interface{}
+			rawMeta, _ := url.QueryUnescape(req.URL.Query().Get("meta"))
+			json.Unmarshal([]byte(rawMeta), &meta)
+			if onPublish != nil {
+				onPublish(channel, meta)
+			}
+			return stubResponse(req, `[1,"Sent","15000000000000001"]`), nil
+		}
+		return stubResponse(req, `{"status": 200, "message": "OK", "service": "Presence"}`), nil
+	})})
+	pn.SetSubscribeClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if tt := req.URL.Query().Get("tt"); tt == "" || tt == "0" {
+			return stubResponse(req, `{"t":{"t":"15000000000000000","r":1},"m":[]}`), nil
+		}
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})})
+
+	return pn
+}
+
+func TestReplyMissingMeta(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+
+	_, _, err := pn.Reply(&PNMessage{Message: "hi", UserMetadata: map[string]interface{}{"a": "b"}}, "reply")
+	assert.Contains(err.Error(), StrMissingReplyTo)
+
+	_, _, err = pn.Reply(nil, "reply")
+	assert.Contains(err.Error(), StrMissingReplyTo)
+}
+
+func TestReplyPublishesToInbox(t *testing.T) {
+	assert := assert.New(t)
+
+	var publishedChannel string
+	var publishedMeta map[string]interface{}
+	pn := newRequestReplyTestPubNub(t, func(channel string, meta map[string]interface{}) {
+		publishedChannel = channel
+		publishedMeta = meta
+	})
+	defer pn.Destroy()
+
+	request := &PNMessage{
+		Message: "ping",
+		UserMetadata: map[string]interface{}{
+			requestCorrelationIDKey: "id1",
+			requestReplyToKey:       "pn-inbox-client",
+		},
+	}
+	_, _, err := pn.Reply(request, "pong")
+	assert.Nil(err)
+	assert.Equal("pn-inbox-client", publishedChannel)
+	assert.Equal(map[string]interface{}{requestCorrelationIDKey: "id1"}, publishedMeta)
+}
+
+func TestHandleReplyLateReply(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+
+	m := newRequestReplyManager(pn)
+	m.inbox = "pn-inbox-client"
+
+	assert.False(m.handleReply(&PNMessage{Channel: "other"}))
+
+	reply := m.addPending("id1")
+	assert.True(m.handleReply(&PNMessage{Channel: "pn-inbox-client", Message: "pong", UserMetadata: map[string]interface{}{requestCorrelationIDKey: "id1"}}))
+	assert.Equal("pong", (<-reply).Message)
+
+	// the request is no longer pending, the second reply is dropped
+	assert.True(m.handleReply(&PNMessage{Channel: "pn-inbox-client", Message: "pong", UserMetadata: map[string]interface{}{requestCorrelationIDKey: "id1"}}))
+	assert.Empty(m.pending)
+
+	// the messages which aren't replies are announced to the listeners
+	assert.False(m.handleReply(&PNMessage{Channel: "pn-inbox-client", Message: "hello"}))
+}
+
+func TestRequestReceivesReply(t *testing.T) {
+	assert := assert.New(t)
+
+	var pn *PubNub
+	pn = newRequestReplyTestPubNub(t, func(channel string, meta map[string]interface{}) {
+		if channel != "service" {
+			return
+		}
+		go pn.requestReplyManager.handleReply(&PNMessage{
+			Channel:      meta[requestReplyToKey].(string),
+			Message:      "pong",
+			UserMetadata: map[string]interface{}{requestCorrelationIDKey: meta[requestCorrelationIDKey]},
+		})
+	})
+	defer pn.Destroy()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	reply, err := pn.Request(ctx, "service", "ping")
+	assert.Nil(err)
+	assert.Equal("pong", reply.Message)
+	assert.Contains(pn.GetSubscribedChannels(), requestInboxPrefix+pn.Config.UUID)
+}
+
+func TestRequestTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newRequestReplyTestPubNub(t, nil)
+	defer pn.Destroy()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	reply, err := pn.Request(ctx, "service", "ping")
+	assert.Nil(reply)
+	assert.Equal(context.DeadlineExceeded, err)
+	assert.Empty(pn.requestReplyManager.pending)
+}
+
+func TestRequestKeepsSubscriptionCursor(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newRequestReplyTestPubNub(t, nil)
+	defer pn.Destroy()
+
+	var mu sync.Mutex
+	subscribes := []string{}
+	pn.SetSubscribeClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		tt := req.URL.Query().Get("tt")
+		mu.Lock()
+		subscribes = append(subscribes, tt)
+		mu.Unlock()
+		if tt == "" {
+			// the handshakes return the current time
+			return stubResponse(req, fmt.Sprintf(`{"t":{"t":"1%d000000000000000","r":1},"m":[]}`, 4+len(subscribes))), nil
+		}
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})})
+
+	pn.Subscribe().Channels([]string{"other"}).Execute()
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	inbox, err := pn.requestReplyManager.ensureInbox(ctx)
+	assert.Nil(err)
+	assert.Contains(pn.GetSubscribedChannels(), inbox)
+	assert.Contains(pn.GetSubscribedChannels(), "other")
+
+	mu.Lock()
+	defer mu.Unlock()
+	// the inbox subscription resumes from the cursor of the current subscription
+	assert.Equal([]string{"", "15000000000000000", "", "15000000000000000"}, subscribes)
+}
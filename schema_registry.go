@@ -0,0 +1,406 @@
+package pubnub
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MessageValidator validates a message sent to or received on a channel.
+// The message is passed in its decoded JSON form. A non nil error rejects the message.
+type MessageValidator func(channel string, message interface{}) error
+
+// InvalidMessageHandler is called with the received messages which failed the validation,
+// instead of announcing them to the listeners.
+type InvalidMessageHandler func(message *PNMessage, err error)
+
+type schemaEntry struct {
+	pattern   string
+	validator MessageValidator
+}
+
+// schemaRegistry holds the validators registered per channel pattern. A
+// pattern is either a channel name, a wildcard `prefix.*` or `*` matching
+// every channel.
+type schemaRegistry struct {
+	sync.RWMutex
+	entries   []schemaEntry
+	onInvalid InvalidMessageHandler
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{}
+}
+
+func (r *schemaRegistry) register(pattern string, validator MessageValidator) {
+	r.Lock()
+	r.entries = append(r.entries, schemaEntry{pattern: pattern, validator: validator})
+	r.Unlock()
+}
+
+func (r *schemaRegistry) unregister(pattern string) {
+	r.Lock()
+	entries := r.entries[:0]
+	for _, e := range r.entries {
+		if e.pattern != pattern {
+			entries = append(entries, e)
+		}
+	}
+	r.entries = entries
+	r.Unlock()
+}
+
+func (r *schemaRegistry) setInvalidMessageHandler(handler InvalidMessageHandler) {
+	r.Lock()
+	r.onInvalid = handler
+	r.Unlock()
+}
+
+func (r *schemaRegistry) invalidMessageHandler() InvalidMessageHandler {
+	r.RLock()
+	defer r.RUnlock()
+	return r.onInvalid
+}
+
+func (r *schemaRegistry) validators(channel string) []MessageValidator {
+	r.RLock()
+	defer r.RUnlock()
+	validators := []MessageValidator{}
+	for _, e := range r.entries {
+		if matchChannelPattern(e.pattern, channel) {
+			validators = append(validators, e.validator)
+		}
+	}
+	return validators
+}
+
+func matchChannelPattern(pattern, channel string) bool {
+	if pattern == "*" || pattern == channel {
+		return true
+	}
+	if strings.HasSuffix(pattern, ".*") {
+		return strings.HasPrefix(channel, strings.TrimSuffix(pattern, "*"))
+	}
+	return false
+}
+
+// validate runs the validators matching the channel on a decoded JSON message.
+func (r *schemaRegistry) validate(channel string, message interface{}) error {
+	for _, validator := range r.validators(channel) {
+		if err := validator(channel, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateOutgoing decodes the message as it will be sent and validates it.
+func (r *schemaRegistry) validateOutgoing(channel string, message interface{}, serialized bool) error {
+	if r == nil || len(r.validators(channel)) == 0 {
+		return nil
+	}
+
+	var data []byte
+	if s, ok := message.(string); ok && serialized {
+		data = []byte(s)
+	} else {
+		var err error
+		if data, err = json.Marshal(message); err != nil {
+			return err
+		}
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	return r.validate(channel, decoded)
+}
+
+// jsonSchema is the compiled form of the supported subset of JSON Schema:
+// type, enum, const, properties, required, additionalProperties, items,
+// minItems, maxItems, minLength, maxLength, pattern, minimum and maximum.
+type jsonSchema struct {
+	types                []string
+	enum                 []interface{}
+	properties           map[string]*jsonSchema
+	required             []string
+	additionalProperties *jsonSchema
+	noAdditional         bool
+	items                *jsonSchema
+	minItems, maxItems   int
+	minLength, maxLength int
+	minimum, maximum     *float64
+	pattern              *regexp.Regexp
+}
+
+func newJSONSchemaValidator(document []byte) (MessageValidator, error) {
+	var raw interface{}
+	if err := json.Unmarshal(document, &raw); err != nil {
+		return nil, err
+	}
+	schema, err := compileJSONSchema(raw)
+	if err != nil {
+		return nil, err
+	}
+	return func(channel string, message interface{}) error {
+		return schema.validate("$", message)
+	}, nil
+}
+
+// jsonSchemaKeywords are the supported keywords, with the annotations which don't affect the validation.
+var jsonSchemaKeywords = map[string]bool{
+	"type": true, "enum": true, "const": true, "properties": true, "required": true,
+	"additionalProperties": true, "items": true, "minItems": true, "maxItems": true,
+	"minLength": true, "maxLength": true, "pattern": true, "minimum": true, "maximum": true,
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true,
+}
+
+// compileJSONSchema returns an error for the keywords which aren't supported,
+// a schema using them would accept the messages they should reject.
+func compileJSONSchema(raw interface{}) (*jsonSchema, error) {
+	def, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema must be an object, got %T", raw)
+	}
+	keywords := make([]string, 0, len(def))
+	for key := range def {
+		keywords = append(keywords, key)
+	}
+	sort.Strings(keywords)
+	for _, key := range keywords {
+		if !jsonSchemaKeywords[key] {
+			return nil, fmt.Errorf("unsupported keyword %s", key)
+		}
+	}
+	s := &jsonSchema{minItems: -1, maxItems: -1, minLength: -1, maxLength: -1}
+
+	switch t := def["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			name, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid type %v", v)
+			}
+			s.types = append(s.types, name)
+		}
+	default:
+		return nil, fmt.Errorf("invalid type %v", t)
+	}
+
+	if enum, ok := def["enum"]; ok {
+		values, ok := enum.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid enum %v", enum)
+		}
+		s.enum = values
+	}
+	if c, ok := def["const"]; ok {
+		s.enum = []interface{}{c}
+	}
+
+	if rawProps, ok := def["properties"]; ok {
+		props, ok := rawProps.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid properties %v", rawProps)
+		}
+		s.properties = make(map[string]*jsonSchema)
+		for name, p := range props {
+			ps, err := compileJSONSchema(p)
+			if err != nil {
+				return nil, fmt.Errorf("properties.%s: %s", name, err)
+			}
+			s.properties[name] = ps
+		}
+	}
+	if rawRequired, ok := def["required"]; ok {
+		required, ok := rawRequired.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid required %v", rawRequired)
+		}
+		for _, v := range required {
+			name, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid required %v", v)
+			}
+			s.required = append(s.required, name)
+		}
+	}
+	switch ap := def["additionalProperties"].(type) {
+	case bool:
+		s.noAdditional = !ap
+	case map[string]interface{}:
+		aps, err := compileJSONSchema(ap)
+		if err != nil {
+			return nil, fmt.Errorf("additionalProperties: %s", err)
+		}
+		s.additionalProperties = aps
+	case nil:
+	default:
+		return nil, fmt.Errorf("invalid additionalProperties %v", ap)
+	}
+
+	if items, ok := def["items"]; ok {
+		is, err := compileJSONSchema(items)
+		if err != nil {
+			return nil, fmt.Errorf("items: %s", err)
+		}
+		s.items = is
+	}
+
+	for key, dst := range map[string]*int{
+		"minItems": &s.minItems, "maxItems": &s.maxItems,
+		"minLength": &s.minLength, "maxLength": &s.maxLength,
+	} {
+		if raw, ok := def[key]; ok {
+			v, ok := raw.(float64)
+			if !ok || v < 0 || v != math.Trunc(v) {
+				return nil, fmt.Errorf("invalid %s %v", key, raw)
+			}
+			*dst = int(v)
+		}
+	}
+	for key, dst := range map[string]**float64{"minimum": &s.minimum, "maximum": &s.maximum} {
+		if raw, ok := def[key]; ok {
+			v, ok := raw.(float64)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s %v", key, raw)
+			}
+			*dst = &v
+		}
+	}
+	if rawPattern, ok := def["pattern"]; ok {
+		p, ok := rawPattern.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid pattern %v", rawPattern)
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("pattern: %s", err)
+		}
+		s.pattern = re
+	}
+
+	return s, nil
+}
+
+func jsonTypeOf(v interface{}) string {
+	switch n := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if n == math.Trunc(n) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func (s *jsonSchema) validate(path string, v interface{}) error {
+	if len(s.types) > 0 {
+		actual := jsonTypeOf(v)
+		matched := false
+		for _, t := range s.types {
+			if t == actual || (t == "number" && actual == "integer") {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(s.types, " or "), actual)
+		}
+	}
+
+	if s.enum != nil {
+		matched := false
+		for _, e := range s.enum {
+			if reflect.DeepEqual(e, v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: value %v is not allowed", path, v)
+		}
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, ok := value[name]; !ok {
+				return fmt.Errorf("%s: missing required property %s", path, name)
+			}
+		}
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			ps, ok := s.properties[name]
+			if !ok {
+				if s.noAdditional {
+					return fmt.Errorf("%s: additional property %s is not allowed", path, name)
+				}
+				ps = s.additionalProperties
+			}
+			if ps != nil {
+				if err := ps.validate(path+"."+name, value[name]); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		if s.minItems >= 0 && len(value) < s.minItems {
+			return fmt.Errorf("%s: expected at least %d items", path, s.minItems)
+		}
+		if s.maxItems >= 0 && len(value) > s.maxItems {
+			return fmt.Errorf("%s: expected at most %d items", path, s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range value {
+				if err := s.items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := len([]rune(value))
+		if s.minLength >= 0 && length < s.minLength {
+			return fmt.Errorf("%s: expected at least %d characters", path, s.minLength)
+		}
+		if s.maxLength >= 0 && length > s.maxLength {
+			return fmt.Errorf("%s: expected at most %d characters", path, s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(value) {
+			return fmt.Errorf("%s: does not match pattern %s", path, s.pattern)
+		}
+	case float64:
+		if s.minimum != nil && value < *s.minimum {
+			return fmt.Errorf("%s: %v is less than %v", path, value, *s.minimum)
+		}
+		if s.maximum != nil && value > *s.maximum {
+			return fmt.Errorf("%s: %v is greater than %v", path, value, *s.maximum)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package pubnub
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pubnub/go/v7/pnerr"
+	"github.com/stretchr/testify/assert"
+)
+
+const testChatSchema = `{
+	"type": "object",
+	"required": ["text"],
+	"properties": {
+		"text": {"type": "string", "minLength": 1, "maxLength": 10},
+		"priority": {"type": "integer", "minimum": 0, "maximum": 5},
+		"tags": {"type": "array", "items": {"type": "string", "pattern": "^[a-z]+$"}, "maxItems": 2},
+		"kind": {"enum": ["chat", "system"]}
+	},
+	"additionalProperties": false
+}`
+
+func TestJSONSchemaValidator(t *testing.T) {
+	assert := assert.New(t)
+
+	validator, err := newJSONSchemaValidator([]byte(testChatSchema))
+	assert.Nil(err)
+
+	valid := map[string]interface{}{"text": "hi", "priority": float64(1), "tags": []interface{}{"a"}, "kind": "chat"}
+	assert.Nil(validator("ch", valid))
+
+	cases := map[string]interface{}{
+		"$: expected object, got string":              "hi",
+		"$: missing required property text":           map[string]interface{}{},
+		"$.text: expected at most 10 characters":      map[string]interface{}{"text": "hello world"},
+		"$.priority: expected integer, got number":    map[string]interface{}{"text": "hi", "priority": 1.5},
+		"$.priority: 6 is greater than 5":             map[string]interface{}{"text": "hi", "priority": float64(6)},
+		"$.tags[1]: does not match pattern ^[a-z]+$":  map[string]interface{}{"text": "hi", "tags": []interface{}{"a", "B"}},
+		"$.kind: value other is not allowed":          map[string]interface{}{"text": "hi", "kind": "other"},
+		"$: additional property extra is not allowed": map[string]interface{}{"text": "hi", "extra": true},
+	}
+	for expected, message := range cases {
+		err := validator("ch", message)
+		if assert.NotNil(err, expected) {
+			assert.Equal(expected, err.Error())
+		}
+	}
+
+	_, err = newJSONSchemaValidator([]byte(`{"properties": {"a": {"pattern": "("}}}`))
+	assert.NotNil(err)
+	_, err = newJSONSchemaValidator([]byte(`[]`))
+	assert.NotNil(err)
+}
+
+func TestJSONSchemaUnsupportedKeywords(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := map[string]string{
+		`{"oneOf": [{"type": "string"}, {"type": "integer"}]}`: "unsupported keyword oneOf",
+		`{"properties": {"a": {"$ref": "#/definitions/a"}}}`:   "properties.a: unsupported keyword $ref",
+		`{"type": "string", "format": "email"}`:                "unsupported keyword format",
+		`{"items": {"type": "number", "exclusiveMinimum": 0}}`: "items: unsupported keyword exclusiveMinimum",
+		`{"type": "number", "minimum": "5"}`:                   "invalid minimum 5",
+		`{"type": "array", "maxItems": 1.5}`:                   "invalid maxItems 1.5",
+		`{"type": "object", "required": "text"}`:               "invalid required text",
+	}
+	for schema, expected := range cases {
+		_, err := newJSONSchemaValidator([]byte(schema))
+		if assert.NotNil(err, schema) {
+			assert.Equal(expected, err.Error())
+		}
+	}
+
+	_, err := newJSONSchemaValidator([]byte(`{"$schema": "http://json-schema.org/draft-07/schema#", "title": "chat", "description": "a chat message", "type": "string"}`))
+	assert.Nil(err)
+}
+
+func TestMatchChannelPattern(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(matchChannelPattern("*", "any"))
+	assert.True(matchChannelPattern("chat", "chat"))
+	assert.True(matchChannelPattern("chat.*", "chat.room1"))
+	assert.False(matchChannelPattern("chat.*", "chat"))
+	assert.False(matchChannelPattern("chat.*", "chatroom"))
+	assert.False(matchChannelPattern("chat", "chat.room1"))
+}
+
+func TestPublishSchemaValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	assert.Nil(pn.RegisterSchema("chat.*", []byte(testChatSchema)))
+
+	_, _, err := pn.Publish().Channel("chat.room").Message(map[string]interface{}{"priority": 1}).Execute()
+	var validationErr *pnerr.ValidationError
+	assert.True(errors.As(err, &validationErr))
+	assert.Contains(err.Error(), "missing required property text")
+
+	_, _, err = pn.Fire().Channel("chat.room").Message(`{"text":""}`).Serialize(false).Execute()
+	assert.Contains(err.Error(), "expected at least 1 characters")
+
+	pn.RegisterValidator("signals", func(channel string, message interface{}) error {
+		if _, ok := message.(string); !ok {
+			return errors.New("signal must be a string")
+		}
+		return nil
+	})
+	_, _, err = pn.Signal().Channel("signals").Message(1).Execute()
+	assert.Contains(err.Error(), "signal must be a string")
+
+	// the messages of the other channels are not validated
+	opts := newPublishOpts(pn, pn.ctx)
+	opts.Channel = "other"
+	opts.Message = 1
+	assert.Nil(opts.validate())
+
+	pn.UnregisterSchema("chat.*")
+	opts.Channel = "chat.room"
+	assert.Nil(opts.validate())
+}
+
+func TestSubscribeInvalidMessageHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	assert.Nil(pn.RegisterSchema("chat", []byte(testChatSchema)))
+
+	var invalid *PNMessage
+	var invalidErr error
+	pn.SetInvalidMessageHandler(func(message *PNMessage, err error) {
+		invalid = message
+		invalidErr = err
+	})
+
+	assert.True(pn.subscriptionManager.validateMessage(&PNMessage{Channel: "chat", Message: map[string]interface{}{"text": "hi"}}))
+	assert.Nil(invalid)
+
+	assert.False(pn.subscriptionManager.validateMessage(&PNMessage{Channel: "chat", Message: "hi"}))
+	assert.Equal("hi", invalid.Message)
+	assert.Equal("$: expected object, got string", invalidErr.Error())
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"github.com/pubnub/go/v7/crypto"
 	"github.com/pubnub/go/v7/pnerr"
 	"github.com/pubnub/go/v7/utils"
 	"io/ioutil"
@@ -56,6 +57,15 @@ func (b *signalBuilder) usePost(post bool) *signalBuilder {
 	return b
 }
 
+// CryptoModule sets the cryptography module the signal is encrypted with. Signals
+// are not encrypted otherwise, and are not decrypted on subscribe, the receivers
+// have to decrypt them.
+func (b *signalBuilder) CryptoModule(cryptoModule crypto.CryptoModule) *signalBuilder {
+	b.opts.CryptoModule = cryptoModule
+
+	return b
+}
+
 // Transport sets the Transport for the objectAPICreateUsers request.
 func (b *signalBuilder) Transport(tr http.RoundTripper) *signalBuilder {
 	b.opts.Transport = tr
@@ -86,6 +96,11 @@ type signalOpts struct {
 	UsePost    bool
 	QueryParam map[string]string
 	Transport  http.RoundTripper
+
+	CryptoModule crypto.CryptoModule
+
+	// the message is an internal signal of the SDK, not validated against the schemas
+	isInternal bool
 }
 
 func (o *signalOpts) validate() error {
@@ -97,6 +112,13 @@ func (o *signalOpts) validate() error {
 		return newValidationError(o, StrMissingPubKey)
 	}
 
+	if o.isInternal {
+		return nil
+	}
+	if err := o.pubnub.schemaRegistry.validateOutgoing(o.Channel, o.Message, false); err != nil {
+		return newValidationError(o, err.Error())
+	}
+
 	return nil
 }
 
@@ -110,7 +132,7 @@ func (o *signalOpts) buildPath() (string, error) {
 	}
 
 	var msg string
-	jsonEncBytes, errEnc := json.Marshal(o.Message)
+	jsonEncBytes, errEnc := o.serializedMessage()
 	if errEnc != nil {
 		o.pubnub.Config.Log.Printf("ERROR: Publish error: %s\n", errEnc.Error())
 		return "", errEnc
@@ -133,9 +155,18 @@ func (o *signalOpts) buildQuery() (*url.Values, error) {
 	return q, nil
 }
 
+// serializedMessage returns the JSON of the message, encrypted with the CryptoModule when set.
+func (o *signalOpts) serializedMessage() ([]byte, error) {
+	if o.CryptoModule == nil {
+		return json.Marshal(o.Message)
+	}
+	msg, err := serializeEncryptAndSerialize(o.CryptoModule, o.Message, true)
+	return []byte(msg), err
+}
+
 func (o *signalOpts) buildBody() ([]byte, error) {
 	if o.UsePost {
-		jsonEncBytes, errEnc := json.Marshal(o.Message)
+		jsonEncBytes, errEnc := o.serializedMessage()
 		if errEnc != nil {
 			o.pubnub.Config.Log.Printf("ERROR: Signal error: %s\n", errEnc.Error())
 			return []byte{}, errEnc
@@ -1,9 +1,13 @@
 package pubnub
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"strings"
 	"testing"
 
+	"github.com/pubnub/go/v7/crypto"
 	h "github.com/pubnub/go/v7/tests/helpers"
 	"github.com/stretchr/testify/assert"
 )
@@ -144,3 +148,26 @@ func TestSignalResponseValuePass(t *testing.T) {
 	_, _, err := newSignalResponse(jsonBytes, opts, StatusResponse{})
 	assert.Nil(err)
 }
+
+func TestSignalCryptoModule(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.CipherKey = "enigma"
+	path, err := newSignalBuilder(pn).Channel("ch").Message("yay!").opts.buildPath()
+	assert.Nil(err)
+	assert.Equal("/signal/demo/demo/0/ch/0/%22yay%21%22", path)
+
+	module, err := crypto.NewAesCbcCryptoModule("enigma", true)
+	assert.Nil(err)
+	path, err = newSignalBuilder(pn).Channel("ch").Message("yay!").CryptoModule(module).opts.buildPath()
+	assert.Nil(err)
+
+	segment, err := url.PathUnescape(path[strings.LastIndex(path, "/")+1:])
+	assert.Nil(err)
+	var encrypted string
+	assert.Nil(json.Unmarshal([]byte(segment), &encrypted))
+	msg, err := parseCipherInterface(encrypted, pn.Config, module)
+	assert.Nil(err)
+	assert.Equal("yay!", msg)
+}
@@ -71,6 +71,11 @@ func (b *subscribeBuilder) Timetoken(tt int64) *subscribeBuilder {
 	return b
 }
 
+// WithTimetoken sets the timetoken to subscribe from.
+func (b *subscribeBuilder) WithTimetoken(tt Timetoken) *subscribeBuilder {
+	return b.Timetoken(tt.Int64())
+}
+
 // FilterExpression sets the custom filter expression.
 func (b *subscribeBuilder) FilterExpression(expr string) *subscribeBuilder {
 	b.operation.FilterExpression = expr
@@ -3,6 +3,7 @@ package pubnub
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/pubnub/go/v7/crypto"
 	"net/http"
 	"reflect"
@@ -42,6 +43,7 @@ type SubscriptionManager struct {
 	stateManager        *StateManager
 	pubnub              *PubNub
 	reconnectionManager *ReconnectionManager
+	chunkReassembler    *chunkReassembler
 	transport           http.RoundTripper
 	messages            chan subscribeMessage
 	ctx                 Context
@@ -107,6 +109,17 @@ func newSubscriptionManager(pubnub *PubNub, ctx Context) *SubscriptionManager {
 	manager.messages = make(chan subscribeMessage, 1000)
 	manager.reconnectionManager = newReconnectionManager(pubnub)
 	manager.channelsOpen = true
+	manager.chunkReassembler = newChunkReassembler(time.Duration(pubnub.Config.ChunkReassemblyTimeout)*time.Second, func(channel, id string, received, count int) {
+		pnStatus := &PNStatus{
+			Category:         PNChunkReassemblyTimeoutCategory,
+			ErrorData:        fmt.Errorf("chunked message %s timed out after receiving %d of %d chunks", id, received, count),
+			Error:            true,
+			Operation:        PNSubscribeOperation,
+			AffectedChannels: []string{channel},
+		}
+		pubnub.Config.Log.Println("Status: ", pnStatus)
+		manager.listenerManager.announceStatus(pnStatus)
+	})
 	manager.Unlock()
 
 	if manager.pubnub.Config.PNReconnectionPolicy != PNNonePolicy {
@@ -170,11 +183,11 @@ func (m *SubscriptionManager) Destroy() {
 		m.RLock()
 		m.channelsOpen = false
 		m.RUnlock()
-		m.exitSubscriptionManagerMutex.RLock()
+		m.exitSubscriptionManagerMutex.Lock()
 		if m.exitSubscriptionManager != nil {
 			close(m.exitSubscriptionManager)
 		}
-		m.exitSubscriptionManagerMutex.RUnlock()
+		m.exitSubscriptionManagerMutex.Unlock()
 		if m.listenerManager.exitListener != nil {
 			close(m.listenerManager.exitListener)
 		}
@@ -185,6 +198,7 @@ func (m *SubscriptionManager) Destroy() {
 			m.reconnectionManager.stopHeartbeatTimer()
 			close(m.reconnectionManager.exitReconnectionManager)
 		}
+		m.chunkReassembler.destroy()
 
 	}
 
@@ -399,6 +413,9 @@ func (m *SubscriptionManager) startSubscribeLoop() {
 			m.subscriptionStateAnnounced = true
 		}
 		m.Unlock()
+		if !announced && m.pubnub.requestReplyManager != nil {
+			m.pubnub.requestReplyManager.connected()
+		}
 
 		var envelope subscribeEnvelope
 		err = json.Unmarshal(res, &envelope)
@@ -516,6 +533,11 @@ func subscribeMessageWorker(m *SubscriptionManager) {
 	m.Unlock()
 	m.pubnub.Config.Log.Println("acquiring lock exitSubscriptionManagerMutex")
 	m.exitSubscriptionManagerMutex.Lock()
+	if !m.channelsOpen {
+		// destroyed, the exit channel is closed
+		m.exitSubscriptionManagerMutex.Unlock()
+		return
+	}
 	if m.exitSubscriptionManager != nil {
 		m.exitSubscriptionManager <- true
 		m.pubnub.Config.Log.Println("close exitSubscriptionManager")
@@ -639,6 +661,13 @@ func processNonPresencePayload(m *SubscriptionManager, payload subscribeMessage,
 	switch payload.MessageType {
 	case PNMessageTypeSignal:
 		pnMessageResult := createPNMessageResult(payload.Payload, actualCh, subscribedCh, channel, subscriptionMatch, payload.IssuingClientID, payload.UserMetadata, timetoken, /*no error*/nil)
+		if m.pubnub.deliveryManager != nil && m.pubnub.deliveryManager.handleAck(pnMessageResult) {
+			m.pubnub.Config.Log.Println("delivery ack received,", pnMessageResult)
+			return
+		}
+		if !m.validateMessage(pnMessageResult) {
+			return
+		}
 		m.pubnub.Config.Log.Println("announceSignal,", pnMessageResult)
 		m.listenerManager.announceSignal(pnMessageResult)
 	case PNMessageTypeObjects:
@@ -661,7 +690,7 @@ func processNonPresencePayload(m *SubscriptionManager, payload subscribeMessage,
 		m.listenerManager.announceMessageActionsEvent(pnMessageActionsEvent)
 	case PNMessageTypeFile:
 		var err error
-		messagePayload, err = parseCipherInterface(payload.Payload, m.pubnub.Config, m.pubnub.getCryptoModule())
+		messagePayload, err = parseCipherInterface(payload.Payload, m.pubnub.Config, m.pubnub.channelCryptoModule(channel))
 		if err != nil {
 			pnStatus := &PNStatus{
 				Category:         PNBadRequestCategory,
@@ -680,7 +709,20 @@ func processNonPresencePayload(m *SubscriptionManager, payload subscribeMessage,
 		m.listenerManager.announceFile(pnFilesEvent)
 	default:
 		var err error
-		messagePayload, err = parseCipherInterface(payload.Payload, m.pubnub.Config, m.pubnub.getCryptoModule())
+		if chunk, ok := parseChunkEnvelope(payload.Payload); ok {
+			data, firstTimetoken, complete := m.chunkReassembler.add(channel, chunk, timetoken)
+			if !complete {
+				m.pubnub.Config.Log.Println("chunk received,", chunk.ID, chunk.Index, chunk.Count)
+				return
+			}
+			timetoken = firstTimetoken
+			messagePayload, err = decodeChunkedPayload(data, m.pubnub.Config, m.pubnub.channelCryptoModule(channel))
+		} else {
+			messagePayload, err = parseCipherInterface(payload.Payload, m.pubnub.Config, m.pubnub.channelCryptoModule(channel))
+		}
+		if err == nil {
+			messagePayload, err = decodeWithCodec(m.pubnub, messagePayload, payload.UserMetadata)
+		}
 		if err != nil {
 			pnStatus := &PNStatus{
 				Category:         PNBadRequestCategory,
@@ -694,12 +736,42 @@ func processNonPresencePayload(m *SubscriptionManager, payload subscribeMessage,
 
 		}
 		pnMessageResult := createPNMessageResult(messagePayload, actualCh, subscribedCh, channel, subscriptionMatch, payload.IssuingClientID, payload.UserMetadata, timetoken, err)
+		if m.pubnub.requestReplyManager != nil && m.pubnub.requestReplyManager.handleReply(pnMessageResult) {
+			m.pubnub.Config.Log.Println("reply received,", pnMessageResult)
+			return
+		}
+		if err == nil && !m.validateMessage(pnMessageResult) {
+			return
+		}
+		// a message which couldn't be decrypted or decoded isn't acknowledged, so that it is resent
+		if err == nil && m.pubnub.deliveryManager != nil && !m.pubnub.deliveryManager.acknowledge(payload) {
+			m.pubnub.Config.Log.Println("dropping resent duplicate,", pnMessageResult)
+			return
+		}
 		m.pubnub.Config.Log.Println("announceMessage,", pnMessageResult)
 		m.listenerManager.announceMessage(pnMessageResult)
 	}
 	m.pubnub.Config.Log.Println("after announceMessage")
 }
 
+// validateMessage runs the validators registered for the channel of the received message.
+// Invalid messages are routed to the invalid message handler, returns false if the message is invalid.
+func (m *SubscriptionManager) validateMessage(message *PNMessage) bool {
+	if m.pubnub.schemaRegistry == nil {
+		return true
+	}
+	err := m.pubnub.schemaRegistry.validate(message.Channel, message.Message)
+	if err == nil {
+		return true
+	}
+
+	m.pubnub.Config.Log.Println("invalid message,", message.Channel, err)
+	if handler := m.pubnub.schemaRegistry.invalidMessageHandler(); handler != nil {
+		handler(message, err)
+	}
+	return false
+}
+
 func processSubscribePayload(m *SubscriptionManager, payload subscribeMessage) {
 	channel := payload.Channel
 	subscriptionMatch := payload.SubscriptionMatch
@@ -737,6 +809,12 @@ func createPNFilesEvent(filePayload interface{}, m *SubscriptionManager, actualC
 	if resGetFile != nil {
 		resp.PNFile.URL = resGetFile.URL
 	}
+	resp.PNFiles = ParseFileList(filesPayload)
+	for i, file := range resp.PNFiles {
+		if resGetFile, _, _ := m.pubnub.GetFileURL().Channel(channel).ID(file.ID).Name(file.Name).Execute(); resGetFile != nil {
+			resp.PNFiles[i].URL = resGetFile.URL
+		}
+	}
 
 	pnFilesEvent := &PNFilesEvent{
 		File:              resp,
@@ -685,3 +685,26 @@ func TestProcessSubscribeWithCryptoModuleNoEncryptedMessage(t *testing.T) {
 	<-done
 	//pn.Destroy()
 } 
+
+func TestChannelCryptoModule(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.CipherKey = "enigma"
+	pn.Config.UseRandomInitializationVector = false
+	module, err := crypto.NewAesCbcCryptoModule("other", true)
+	assert.Nil(err)
+	pn.Config.CryptoModuleResolver = func(channel string) crypto.CryptoModule {
+		if channel == "aes" {
+			return module
+		}
+		return nil
+	}
+
+	assert.Equal(module, pn.channelCryptoModule("aes"))
+	assert.Equal(pn.getCryptoModule(), pn.channelCryptoModule("ch"))
+
+	intf, err := parseCipherInterface("Wi24KS4pcTzvyuGOHubiXg==", pn.Config, pn.channelCryptoModule("ch"))
+	assert.Nil(err)
+	assert.Equal("yay!", intf)
+}
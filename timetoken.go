@@ -0,0 +1,69 @@
+package pubnub
+
+import (
+	"strconv"
+	"time"
+)
+
+// Timetoken is a PubNub timetoken: the number of 100 nanoseconds intervals since
+// the Unix epoch. The int64 timetokens of the responses convert to it directly,
+// Timetoken(msg.Timetoken), the string ones with ParseTimetoken.
+type Timetoken int64
+
+// TimetokenFromTime returns the timetoken of t.
+func TimetokenFromTime(t time.Time) Timetoken {
+	return Timetoken(t.UnixNano() / 100)
+}
+
+// ParseTimetoken parses a timetoken formatted as a decimal string.
+func ParseTimetoken(s string) (Timetoken, error) {
+	tt, err := strconv.ParseInt(s, 10, 64)
+	return Timetoken(tt), err
+}
+
+// Time returns the time of the timetoken.
+func (t Timetoken) Time() time.Time {
+	return time.Unix(0, int64(t)*100)
+}
+
+// Add returns the timetoken t+d, d is truncated to 100 nanoseconds.
+func (t Timetoken) Add(d time.Duration) Timetoken {
+	return t + Timetoken(d/100)
+}
+
+// Sub returns the duration t-u.
+func (t Timetoken) Sub(u Timetoken) time.Duration {
+	return time.Duration(t-u) * 100
+}
+
+// Int64 returns the timetoken as the int64 value the builders take.
+func (t Timetoken) Int64() int64 {
+	return int64(t)
+}
+
+// String formats the timetoken as the decimal string the builders take.
+func (t Timetoken) String() string {
+	return strconv.FormatInt(int64(t), 10)
+}
+
+// MarshalJSON encodes the timetoken as a string, as the PubNub APIs do,
+// timetokens don't fit in a float64 without losing precision.
+func (t Timetoken) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(t.String())), nil
+}
+
+// UnmarshalJSON decodes a timetoken from a string or a number.
+func (t *Timetoken) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		data = data[1 : len(data)-1]
+	}
+	tt, err := ParseTimetoken(string(data))
+	if err != nil {
+		return err
+	}
+	*t = tt
+	return nil
+}
@@ -0,0 +1,88 @@
+package pubnub
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimetokenTime(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2024, 3, 1, 12, 30, 0, 123456700, time.UTC)
+	tt := TimetokenFromTime(now)
+	assert.Equal(Timetoken(17092962001234567), tt)
+	assert.True(tt.Time().Equal(now))
+	assert.Equal(Timetoken(17092962011234567), tt.Add(time.Second))
+	assert.Equal(time.Second, tt.Add(time.Second).Sub(tt))
+	assert.Equal("17092962001234567", tt.String())
+	assert.Equal(int64(17092962001234567), tt.Int64())
+
+	parsed, err := ParseTimetoken("17092962001234567")
+	assert.Nil(err)
+	assert.Equal(tt, parsed)
+	_, err = ParseTimetoken("abc")
+	assert.NotNil(err)
+}
+
+func TestTimetokenJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	b, err := json.Marshal(map[string]Timetoken{"tt": 17092962001234567})
+	assert.Nil(err)
+	assert.Equal(`{"tt":"17092962001234567"}`, string(b))
+
+	var v struct {
+		A Timetoken
+		B Timetoken
+		C Timetoken
+	}
+	assert.Nil(json.Unmarshal([]byte(`{"A":"17092962001234567","B":17092962001234568,"C":null}`), &v))
+	assert.Equal(Timetoken(17092962001234567), v.A)
+	assert.Equal(Timetoken(17092962001234568), v.B)
+	assert.Equal(Timetoken(0), v.C)
+	assert.NotNil(json.Unmarshal([]byte(`{"A":"x"}`), &v))
+	assert.NotNil(json.Unmarshal([]byte(`{"A":"null"}`), &v))
+
+	var tt Timetoken
+	for _, malformed := range []string{`"17092962001234567`, `17092962001234567"`, `""17092962001234567""`, `"`} {
+		assert.NotNil(tt.UnmarshalJSON([]byte(malformed)), malformed)
+	}
+}
+
+func TestTimetokenBuilders(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	defer pn.Destroy()
+	tt := Timetoken(17092962001234567)
+
+	history := pn.History().WithStart(tt).WithEnd(tt.Add(time.Hour))
+	assert.Equal(int64(17092962001234567), history.opts.Start)
+	assert.Equal(int64(17092998001234567), history.opts.End)
+	assert.True(history.opts.setStart && history.opts.setEnd)
+
+	fetch := pn.Fetch().WithStart(tt)
+	assert.Equal(int64(17092962001234567), fetch.opts.Start)
+	assert.True(fetch.opts.setStart)
+
+	deleteMessages := pn.DeleteMessages().WithStart(tt - 1).WithEnd(tt)
+	assert.Equal(int64(17092962001234566), deleteMessages.opts.Start)
+	assert.Equal(int64(17092962001234567), deleteMessages.opts.End)
+
+	getActions := pn.GetMessageActions().WithStart(tt).WithEnd(tt - 10)
+	assert.Equal("17092962001234567", getActions.opts.Start)
+	assert.Equal("17092962001234557", getActions.opts.End)
+
+	assert.Equal("17092962001234567", pn.AddMessageAction().WithMessageTimetoken(tt).opts.MessageTimetoken)
+	remove := pn.RemoveMessageAction().WithMessageTimetoken(tt).WithActionTimetoken(tt + 1)
+	assert.Equal("17092962001234567", remove.opts.MessageTimetoken)
+	assert.Equal("17092962001234568", remove.opts.ActionTimetoken)
+
+	counts := pn.MessageCounts().WithChannelsTimetoken([]Timetoken{tt, tt + 1})
+	assert.Equal([]int64{17092962001234567, 17092962001234568}, counts.opts.ChannelsTimetoken)
+
+	subscribe := pn.Subscribe().WithTimetoken(tt)
+	assert.Equal(int64(17092962001234567), subscribe.operation.Timetoken)
+}
@@ -0,0 +1,437 @@
+package pubnub
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// maxChannelsPerMessageCounts is the max number of channels of a single MessageCounts request.
+const maxChannelsPerMessageCounts = 100
+
+// maxChannelsPerMembershipsFilter is the max number of channels of the filter of a single GetMemberships request.
+const maxChannelsPerMembershipsFilter = 20
+
+// DefaultLastReadField is the membership custom field MembershipLastReadStore stores the last read timetoken in.
+const DefaultLastReadField = "lastReadTimetoken"
+
+// LastReadStore persists the timetoken of the last read message of each channel of a user.
+type LastReadStore interface {
+	// LastRead returns the last read timetokens of the channels, the channels
+	// never read are not in the map.
+	LastRead(ctx Context, channels []string) (map[string]Timetoken, error)
+	SetLastRead(ctx Context, channel string, timetoken Timetoken) error
+}
+
+// MemoryLastReadStore keeps the last read timetokens in memory.
+type MemoryLastReadStore struct {
+	sync.RWMutex
+	lastRead map[string]Timetoken
+}
+
+// NewMemoryLastReadStore returns an empty MemoryLastReadStore.
+func NewMemoryLastReadStore() *MemoryLastReadStore {
+	return &MemoryLastReadStore{lastRead: make(map[string]Timetoken)}
+}
+
+// LastRead returns the last read timetokens of the channels.
+func (s *MemoryLastReadStore) LastRead(ctx Context, channels []string) (map[string]Timetoken, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	lastRead := make(map[string]Timetoken, len(channels))
+	for _, ch := range channels {
+		if tt, ok := s.lastRead[ch]; ok {
+			lastRead[ch] = tt
+		}
+	}
+	return lastRead, nil
+}
+
+// SetLastRead stores the last read timetoken of the channel.
+func (s *MemoryLastReadStore) SetLastRead(ctx Context, channel string, timetoken Timetoken) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.lastRead[channel] = timetoken
+	return nil
+}
+
+// FileLastReadStore keeps the last read timetokens in a JSON file.
+type FileLastReadStore struct {
+	sync.Mutex
+	path string
+}
+
+// NewFileLastReadStore returns a store reading and writing the file at path,
+// the file is created on the first SetLastRead.
+func NewFileLastReadStore(path string) *FileLastReadStore {
+	return &FileLastReadStore{path: path}
+}
+
+func (s *FileLastReadStore) load() (map[string]Timetoken, error) {
+	lastRead := make(map[string]Timetoken)
+	b, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return lastRead, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &lastRead); err != nil {
+		return nil, err
+	}
+	return lastRead, nil
+}
+
+// LastRead returns the last read timetokens of the channels.
+func (s *FileLastReadStore) LastRead(ctx Context, channels []string) (map[string]Timetoken, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	all, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	lastRead := make(map[string]Timetoken, len(channels))
+	for _, ch := range channels {
+		if tt, ok := all[ch]; ok {
+			lastRead[ch] = tt
+		}
+	}
+	return lastRead, nil
+}
+
+// SetLastRead stores the last read timetoken of the channel, the file is
+// replaced atomically.
+func (s *FileLastReadStore) SetLastRead(ctx Context, channel string, timetoken Timetoken) error {
+	s.Lock()
+	defer s.Unlock()
+
+	lastRead, err := s.load()
+	if err != nil {
+		return err
+	}
+	lastRead[channel] = timetoken
+	b, err := json.Marshal(lastRead)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// MembershipLastReadStore keeps the last read timetokens in a custom field of
+// the memberships of the user, so that they are shared by all the devices.
+type MembershipLastReadStore struct {
+	pubnub *PubNub
+	uuid   string
+	// Field is the custom field of the membership holding the timetoken, DefaultLastReadField by default.
+	Field string
+}
+
+// NewMembershipLastReadStore returns a store using the memberships of the uuid,
+// the UUID of the client when empty.
+func (pn *PubNub) NewMembershipLastReadStore(uuid string) *MembershipLastReadStore {
+	if uuid == "" {
+		uuid = pn.Config.UUID
+	}
+	return &MembershipLastReadStore{pubnub: pn, uuid: uuid, Field: DefaultLastReadField}
+}
+
+// channelsFilter returns the Objects filter matching the memberships of the channels.
+func channelsFilter(channels []string) string {
+	filter := ""
+	for i, ch := range channels {
+		if i > 0 {
+			filter += " || "
+		}
+		filter += "channel.id == " + strconv.Quote(ch)
+	}
+	return filter
+}
+
+// memberships returns the custom fields of the memberships of the channels.
+func (s *MembershipLastReadStore) memberships(ctx Context, channels []string) (map[string]map[string]interface{}, error) {
+	custom := make(map[string]map[string]interface{}, len(channels))
+	for from := 0; from < len(channels); from += maxChannelsPerMembershipsFilter {
+		to := from + maxChannelsPerMembershipsFilter
+		if to > len(channels) {
+			to = len(channels)
+		}
+		next := ""
+		for {
+			builder := s.pubnub.GetMembershipsWithContext(ctx).
+				UUID(s.uuid).
+				Include([]PNMembershipsInclude{PNMembershipsIncludeCustom}).
+				Filter(channelsFilter(channels[from:to])).
+				Limit(100)
+			if next != "" {
+				builder.Start(next)
+			}
+			resp, _, err := builder.Execute()
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range resp.Data {
+				custom[m.Channel.ID] = m.Custom
+			}
+			if resp.Next == "" || len(resp.Data) == 0 {
+				break
+			}
+			next = resp.Next
+		}
+	}
+	return custom, nil
+}
+
+// LastRead returns the last read timetokens of the channels.
+func (s *MembershipLastReadStore) LastRead(ctx Context, channels []string) (map[string]Timetoken, error) {
+	lastRead := make(map[string]Timetoken, len(channels))
+	if len(channels) == 0 {
+		return lastRead, nil
+	}
+	memberships, err := s.memberships(ctx, channels)
+	if err != nil {
+		return nil, err
+	}
+	for ch, custom := range memberships {
+		if tt, ok := customTimetoken(custom[s.Field]); ok {
+			lastRead[ch] = tt
+		}
+	}
+	return lastRead, nil
+}
+
+// customTimetoken parses the timetoken of a custom field. Only strings are
+// accepted, a JSON number can't hold a timetoken without losing precision.
+func customTimetoken(v interface{}) (Timetoken, bool) {
+	value, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	tt, err := ParseTimetoken(value)
+	return tt, err == nil
+}
+
+// SetLastRead stores the last read timetoken in the membership of the channel,
+// the other custom fields of the membership are kept.
+func (s *MembershipLastReadStore) SetLastRead(ctx Context, channel string, timetoken Timetoken) error {
+	memberships, err := s.memberships(ctx, []string{channel})
+	if err != nil {
+		return err
+	}
+	custom := make(map[string]interface{})
+	for k, v := range memberships[channel] {
+		custom[k] = v
+	}
+	custom[s.Field] = timetoken.String()
+
+	_, _, err = s.pubnub.SetMembershipsWithContext(ctx).
+		UUID(s.uuid).
+		Set([]PNMembershipsSet{{Channel: PNMembershipsChannel{ID: channel}, Custom: custom}}).
+		Execute()
+	return err
+}
+
+// UnreadTracker keeps the number of unread messages of channels, from the last
+// read timetokens of a LastReadStore. The counts are loaded with MessageCounts
+// and incremented with the messages received on the channels.
+type UnreadTracker struct {
+	sync.RWMutex
+	pubnub   *PubNub
+	store    LastReadStore
+	lastRead map[string]Timetoken
+	counts   map[string]int
+	// loaded holds the unread messages counted by MessageCounts, whose timetokens
+	// aren't known, received the timetokens of the ones counted by HandleMessage.
+	loaded   map[string]int
+	received map[string][]Timetoken
+}
+
+// NewUnreadTracker returns a tracker of the unread messages stored in the store.
+func (pn *PubNub) NewUnreadTracker(store LastReadStore) *UnreadTracker {
+	return &UnreadTracker{
+		pubnub:   pn,
+		store:    store,
+		lastRead: make(map[string]Timetoken),
+		counts:   make(map[string]int),
+		loaded:   make(map[string]int),
+		received: make(map[string][]Timetoken),
+	}
+}
+
+// Load reads the last read timetokens of the channels and counts their unread
+// messages, with one MessageCounts request per 100 channels. The channels never
+// read count all their stored messages.
+func (t *UnreadTracker) Load(ctx Context, channels []string) error {
+	lastRead, err := t.store.LastRead(ctx, channels)
+	if err != nil {
+		return err
+	}
+	counts, err := t.messageCounts(ctx, channels, lastRead)
+	if err != nil {
+		return err
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	for _, ch := range channels {
+		t.lastRead[ch] = lastRead[ch]
+		t.counts[ch] = counts[ch]
+		t.loaded[ch] = counts[ch]
+		t.received[ch] = nil
+	}
+	return nil
+}
+
+// messageCounts returns the number of messages of the channels after their
+// last read timetoken, all the stored messages for the channels never read.
+func (t *UnreadTracker) messageCounts(ctx Context, channels []string, lastRead map[string]Timetoken) (map[string]int, error) {
+	counts := make(map[string]int, len(channels))
+	for from := 0; from < len(channels); from += maxChannelsPerMessageCounts {
+		to := from + maxChannelsPerMessageCounts
+		if to > len(channels) {
+			to = len(channels)
+		}
+		batch := channels[from:to]
+		timetokens := make([]Timetoken, len(batch))
+		for i, ch := range batch {
+			timetokens[i] = lastRead[ch]
+			if timetokens[i] <= 0 {
+				timetokens[i] = 1
+			}
+		}
+		resp, _, err := t.pubnub.MessageCountsWithContext(ctx).
+			Channels(batch).
+			WithChannelsTimetoken(timetokens).
+			Execute()
+		if err != nil {
+			return nil, err
+		}
+		for _, ch := range batch {
+			counts[ch] = resp.Channels[ch]
+		}
+	}
+	return counts, nil
+}
+
+// MarkRead stores timetoken as the last read message of the channel. The unread
+// count of the channel keeps the messages received after the timetoken, it is
+// counted again with MessageCounts when the timetoken is older than the last
+// read message or when unread messages loaded by MessageCounts are left. The
+// messages received meanwhile are counted once MarkRead returns.
+func (t *UnreadTracker) MarkRead(ctx Context, channel string, timetoken Timetoken) error {
+	t.Lock()
+	defer t.Unlock()
+
+	loaded := 0
+	received := []Timetoken{}
+	if timetoken < t.lastRead[channel] || t.loaded[channel] > 0 {
+		counts, err := t.messageCounts(ctx, []string{channel}, map[string]Timetoken{channel: timetoken})
+		if err != nil {
+			return err
+		}
+		loaded = counts[channel]
+	} else {
+		for _, tt := range t.received[channel] {
+			if tt > timetoken {
+				received = append(received, tt)
+			}
+		}
+	}
+	if err := t.store.SetLastRead(ctx, channel, timetoken); err != nil {
+		return err
+	}
+
+	t.lastRead[channel] = timetoken
+	t.loaded[channel] = loaded
+	t.received[channel] = received
+	t.counts[channel] = loaded + len(received)
+	return nil
+}
+
+// HandleMessage counts a message received on a tracked channel as unread, unless
+// it was published by this client or is older than the last read message.
+// It returns the new count of the channel, false if the message isn't counted.
+func (t *UnreadTracker) HandleMessage(message *PNMessage) (int, bool) {
+	if message == nil || message.Publisher == t.pubnub.Config.UUID {
+		return 0, false
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	lastRead, ok := t.lastRead[message.Channel]
+	if !ok || Timetoken(message.Timetoken) <= lastRead {
+		return 0, false
+	}
+	t.received[message.Channel] = append(t.received[message.Channel], Timetoken(message.Timetoken))
+	t.counts[message.Channel]++
+	return t.counts[message.Channel], true
+}
+
+// Run counts the messages received on the channel, usually the Message channel
+// of a Listener, until the context is done. onChange, if not nil, is called with
+// each new count.
+func (t *UnreadTracker) Run(ctx Context, messages <-chan *PNMessage, onChange func(channel string, count int)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			if count, changed := t.HandleMessage(message); changed && onChange != nil {
+				onChange(message.Channel, count)
+			}
+		}
+	}
+}
+
+// Count returns the number of unread messages of the channel.
+func (t *UnreadTracker) Count(channel string) int {
+	t.RLock()
+	defer t.RUnlock()
+
+	return t.counts[channel]
+}
+
+// Counts returns the number of unread messages of the tracked channels.
+func (t *UnreadTracker) Counts() map[string]int {
+	t.RLock()
+	defer t.RUnlock()
+
+	counts := make(map[string]int, len(t.counts))
+	for ch, c := range t.counts {
+		counts[ch] = c
+	}
+	return counts
+}
+
+// Total returns the number of unread messages of all the tracked channels.
+func (t *UnreadTracker) Total() int {
+	t.RLock()
+	defer t.RUnlock()
+
+	total := 0
+	for _, c := range t.counts {
+		total += c
+	}
+	return total
+}
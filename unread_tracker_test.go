@@ -0,0 +1,189 @@
+package pubnub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newUnreadTestPubNub answers the MessageCounts requests with the number of
+// messages of each channel after its timetoken, the history of each channel
+// being timetokens 1 to 10.
+func newUnreadTestPubNub(requests *[]string) *PubNub {
+	pn := NewPubNub(NewDemoConfig())
+	var mu sync.Mutex
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		path := req.URL.Opaque
+		channels := strings.Split(path[strings.LastIndex(path, "/")+1:], ",")
+		q := req.URL.Query()
+		timetokens := strings.Split(q.Get("channelsTimetoken"), ",")
+		if q.Get("timetoken") != "" {
+			timetokens = []string{q.Get("timetoken")}
+		}
+
+		mu.Lock()
+		*requests = append(*requests, strings.Join(channels, ","))
+		mu.Unlock()
+
+		counts := map[string]int{}
+		for i, ch := range channels {
+			tt, _ := strconv.Atoi(timetokens[i])
+			if tt < 10 {
+				counts[ch] = 10 - tt
+			}
+		}
+		body, _ := json.Marshal(map[string]interface{}{"status": 200, "error": false, "channels": counts})
+		return stubResponse(req, string(body)), nil
+	})})
+	return pn
+}
+
+func TestUnreadTrackerLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := []string{}
+	pn := newUnreadTestPubNub(&requests)
+	defer pn.Destroy()
+
+	store := NewMemoryLastReadStore()
+	channels := []string{}
+	for i := 0; i < 150; i++ {
+		ch := fmt.Sprintf("ch%d", i)
+		channels = append(channels, ch)
+		assert.Nil(store.SetLastRead(nil, ch, Timetoken(i%10+1)))
+	}
+	assert.Nil(store.SetLastRead(nil, "ch0", 10))
+
+	tracker := pn.NewUnreadTracker(store)
+	assert.Nil(tracker.Load(nil, append(channels, "never-read")))
+	assert.Equal(2, len(requests))
+	assert.Equal(100, len(strings.Split(requests[0], ",")))
+	assert.Equal(0, tracker.Count("ch0"))
+	assert.Equal(9, tracker.Count("ch10"))
+	assert.Equal(5, tracker.Count("ch124"))
+	assert.Equal(9, tracker.Count("never-read"))
+	assert.Equal(151, len(tracker.Counts()))
+}
+
+func TestUnreadTrackerMessages(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := []string{}
+	pn := newUnreadTestPubNub(&requests)
+	defer pn.Destroy()
+
+	store := NewMemoryLastReadStore()
+	assert.Nil(store.SetLastRead(nil, "a", 8))
+	tracker := pn.NewUnreadTracker(store)
+	assert.Nil(tracker.Load(nil, []string{"a"}))
+	assert.Equal(2, tracker.Count("a"))
+
+	count, ok := tracker.HandleMessage(&PNMessage{Channel: "a", Timetoken: 11, Publisher: "other"})
+	assert.True(ok)
+	assert.Equal(3, count)
+	_, ok = tracker.HandleMessage(&PNMessage{Channel: "a", Timetoken: 12, Publisher: pn.Config.UUID})
+	assert.False(ok)
+	_, ok = tracker.HandleMessage(&PNMessage{Channel: "a", Timetoken: 7, Publisher: "other"})
+	assert.False(ok)
+	_, ok = tracker.HandleMessage(&PNMessage{Channel: "untracked", Timetoken: 12, Publisher: "other"})
+	assert.False(ok)
+
+	assert.Nil(tracker.MarkRead(nil, "a", 11))
+	assert.Equal(0, tracker.Count("a"))
+	_, ok = tracker.HandleMessage(&PNMessage{Channel: "a", Timetoken: 11, Publisher: "other"})
+	assert.False(ok)
+	lastRead, _ := store.LastRead(nil, []string{"a"})
+	assert.Equal(Timetoken(11), lastRead["a"])
+
+	// marking an older message as read counts the messages after it again
+	assert.Nil(tracker.MarkRead(nil, "a", 6))
+	assert.Equal(4, tracker.Count("a"))
+	lastRead, _ = store.LastRead(nil, []string{"a"})
+	assert.Equal(Timetoken(6), lastRead["a"])
+	assert.Nil(tracker.MarkRead(nil, "a", 11))
+	assert.Equal(0, tracker.Count("a"))
+
+	messages := make(chan *PNMessage, 2)
+	messages <- &PNMessage{Channel: "a", Timetoken: 12, Publisher: "other"}
+	messages <- &PNMessage{Channel: "a", Timetoken: 13, Publisher: "other"}
+	close(messages)
+	changes := []int{}
+	tracker.Run(pn.ctx, messages, func(channel string, count int) {
+		changes = append(changes, count)
+	})
+	assert.Equal([]int{1, 2}, changes)
+	assert.Equal(2, tracker.Total())
+
+	// marking a newer message as read keeps the messages received after it
+	requestsBefore := len(requests)
+	assert.Nil(tracker.MarkRead(nil, "a", 12))
+	assert.Equal(1, tracker.Count("a"))
+	assert.Equal(requestsBefore, len(requests))
+	count, ok = tracker.HandleMessage(&PNMessage{Channel: "a", Timetoken: 14, Publisher: "other"})
+	assert.True(ok)
+	assert.Equal(2, count)
+	assert.Nil(tracker.MarkRead(nil, "a", 14))
+	assert.Equal(0, tracker.Count("a"))
+}
+
+func TestFileLastReadStore(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "pubnub-unread")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "last-read.json")
+
+	store := NewFileLastReadStore(path)
+	lastRead, err := store.LastRead(nil, []string{"a"})
+	assert.Nil(err)
+	assert.Equal(0, len(lastRead))
+
+	assert.Nil(store.SetLastRead(nil, "a", 17092962001234567))
+	assert.Nil(store.SetLastRead(nil, "b", 2))
+
+	lastRead, err = NewFileLastReadStore(path).LastRead(nil, []string{"a", "c"})
+	assert.Nil(err)
+	assert.Equal(map[string]Timetoken{"a": 17092962001234567}, lastRead)
+	b, _ := ioutil.ReadFile(path)
+	assert.Equal(`{"a":"17092962001234567","b":"2"}`, string(b))
+}
+
+func TestMembershipLastReadStore(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewPubNub(NewDemoConfig())
+	var filter string
+	var setBody map[string]interface{}
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == "PATCH" {
+			b, _ := ioutil.ReadAll(req.Body)
+			json.Unmarshal(b, &setBody)
+			return stubResponse(req, `{"status":200,"data":[]}`), nil
+		}
+		filter = req.URL.Query().Get("filter")
+		return stubResponse(req, `{"status":200,"data":[{"channel":{"id":"a"},"custom":{"lastReadTimetoken":"15","role":"admin"}},{"channel":{"id":"b"},"custom":null},{"channel":{"id":"c"},"custom":{"lastReadTimetoken":15000000000000001}}]}`), nil
+	})})
+	defer pn.Destroy()
+
+	store := pn.NewMembershipLastReadStore("")
+	lastRead, err := store.LastRead(nil, []string{"a", "b"})
+	assert.Nil(err)
+	assert.Equal(map[string]Timetoken{"a": 15}, lastRead)
+	assert.Equal(`channel.id == "a" || channel.id == "b"`, filter)
+
+	assert.Nil(store.SetLastRead(nil, "a", 20))
+	assert.Equal([]interface{}{map[string]interface{}{
+		"channel": map[string]interface{}{"id": "a"},
+		"custom":  map[string]interface{}{"lastReadTimetoken": "20", "role": "admin"},
+	}}, setBody["set"])
+}
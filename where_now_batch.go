@@ -0,0 +1,196 @@
+package pubnub
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pubnub/go/v7/pnerr"
+)
+
+const defaultWhereNowBatchConcurrency = 5
+
+type whereNowBatchBuilder struct {
+	opts *whereNowBatchOpts
+}
+
+func newWhereNowBatchBuilder(pubnub *PubNub) *whereNowBatchBuilder {
+	return newWhereNowBatchBuilderWithContext(pubnub, pubnub.ctx)
+}
+
+func newWhereNowBatchBuilderWithContext(pubnub *PubNub,
+	context Context) *whereNowBatchBuilder {
+	builder := whereNowBatchBuilder{
+		opts: &whereNowBatchOpts{endpointOpts: endpointOpts{pubnub: pubnub, ctx: context}}}
+	return &builder
+}
+
+// UUIDs sets the UUIDs to fetch the where now info of.
+func (b *whereNowBatchBuilder) UUIDs(uuids []string) *whereNowBatchBuilder {
+	b.opts.UUIDs = uuids
+
+	return b
+}
+
+// Concurrency sets the max number of concurrent WhereNow requests, 5 by default.
+// It is capped by Config.MaxWorkers, the requests being run by the request workers.
+func (b *whereNowBatchBuilder) Concurrency(concurrency int) *whereNowBatchBuilder {
+	b.opts.Concurrency = concurrency
+
+	return b
+}
+
+// RateLimit sets the max number of WhereNow requests started per second, no limit when 0.
+func (b *whereNowBatchBuilder) RateLimit(requestsPerSecond int) *whereNowBatchBuilder {
+	b.opts.RateLimit = requestsPerSecond
+
+	return b
+}
+
+// QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URLs called by the API.
+func (b *whereNowBatchBuilder) QueryParam(queryParam map[string]string) *whereNowBatchBuilder {
+	b.opts.QueryParam = queryParam
+
+	return b
+}
+
+// Transport sets the Transport for the WhereNow requests.
+func (b *whereNowBatchBuilder) Transport(tr http.RoundTripper) *whereNowBatchBuilder {
+	b.opts.Transport = tr
+
+	return b
+}
+
+// Execute runs a WhereNow request per UUID. The UUIDs whose request failed are
+// reported in Errors, the error is returned only when the request is invalid or
+// every request failed. The status is the one of the last request which
+// succeeded, or of the last failed one when they all failed.
+func (b *whereNowBatchBuilder) Execute() (*WhereNowBatchResponse, StatusResponse, error) {
+	o := b.opts
+	if o.config().SubscribeKey == "" {
+		err := pnerr.NewValidationError("WhereNowBatch", StrMissingSubKey)
+		return emptyWhereNowBatchResponse, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+	}
+
+	uuids := []string{}
+	seen := make(map[string]bool, len(o.UUIDs))
+	for _, uuid := range o.UUIDs {
+		if uuid != "" && !seen[uuid] {
+			seen[uuid] = true
+			uuids = append(uuids, uuid)
+		}
+	}
+	if len(uuids) == 0 {
+		err := pnerr.NewValidationError("WhereNowBatch", StrMissingUUID)
+		return emptyWhereNowBatchResponse, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
+	}
+
+	concurrency := o.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultWhereNowBatchConcurrency
+	}
+	if maxWorkers := o.config().MaxWorkers; maxWorkers > 0 && concurrency > maxWorkers {
+		concurrency = maxWorkers
+	}
+
+	var ticks <-chan time.Time
+	if o.RateLimit > 0 {
+		interval := time.Second / time.Duration(o.RateLimit)
+		if interval <= 0 {
+			// more than one request per nanosecond
+			interval = time.Nanosecond
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	resp := &WhereNowBatchResponse{
+		Channels: make(map[string][]string, len(uuids)),
+		Errors:   make(map[string]error),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan string)
+	var lastErr error
+	var status, errStatus StatusResponse
+
+	for i := 0; i < concurrency && i < len(uuids); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for uuid := range jobs {
+				channels, s, err := o.whereNow(uuid, ticks)
+
+				mu.Lock()
+				if err != nil {
+					o.config().Log.Println("batch where now failed", uuid, err)
+					lastErr, errStatus = err, s
+					resp.Errors[uuid] = err
+				} else {
+					status = s
+					resp.Channels[uuid] = channels
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, uuid := range uuids {
+		jobs <- uuid
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(resp.Errors) == len(uuids) {
+		return resp, errStatus, lastErr
+	}
+
+	return resp, status, nil
+}
+
+type whereNowBatchOpts struct {
+	endpointOpts
+
+	UUIDs       []string
+	Concurrency int
+	RateLimit   int
+	QueryParam  map[string]string
+	Transport   http.RoundTripper
+}
+
+// whereNow runs the WhereNow request of the uuid, after the next tick when rate limited.
+func (o *whereNowBatchOpts) whereNow(uuid string, ticks <-chan time.Time) ([]string, StatusResponse, error) {
+	ctx := o.ctx
+	if ctx == nil {
+		ctx = o.pubnub.ctx
+	}
+	if ticks != nil {
+		select {
+		case <-ctx.Done():
+			return nil, createStatus(PNCancelledCategory, "", ResponseInfo{}, ctx.Err()), ctx.Err()
+		case <-ticks:
+		}
+	}
+
+	builder := newWhereNowBuilderWithContext(o.pubnub, ctx).
+		UUID(uuid).
+		QueryParam(o.QueryParam).
+		Transport(o.Transport)
+	builder.opts.inBatch = true
+
+	res, status, err := builder.Execute()
+	if err != nil {
+		return nil, status, err
+	}
+	return res.Channels, status, nil
+}
+
+var emptyWhereNowBatchResponse *WhereNowBatchResponse
+
+// WhereNowBatchResponse is the response of the WhereNowBatch request.
+type WhereNowBatchResponse struct {
+	// Channels holds the channels of each UUID.
+	Channels map[string][]string
+	// Errors holds the error of each UUID whose request failed.
+	Errors map[string]error
+}
@@ -0,0 +1,113 @@
+package pubnub
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newWhereNowBatchTestPubNub(inFlight, maxInFlight *int) *PubNub {
+	pn := NewPubNub(NewDemoConfig())
+	var mu sync.Mutex
+	pn.SetClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		*inFlight++
+		if *inFlight > *maxInFlight {
+			*maxInFlight = *inFlight
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		*inFlight--
+		mu.Unlock()
+
+		uuid := req.URL.Opaque[strings.LastIndex(req.URL.Opaque, "/")+1:]
+		if uuid == "bad" {
+			return &http.Response{
+				Request:    req,
+				StatusCode: 403,
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"status":403,"message":"Forbidden","error":true,"service":"Access Manager"}`)),
+				Header:     make(http.Header),
+			}, nil
+		}
+		return stubResponse(req, `{"status":200,"message":"OK","payload":{"channels":["`+uuid+`-1","`+uuid+`-2"]},"service":"Presence"}`), nil
+	})})
+	return pn
+}
+
+func TestWhereNowBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	inFlight, maxInFlight := 0, 0
+	pn := newWhereNowBatchTestPubNub(&inFlight, &maxInFlight)
+	defer pn.Destroy()
+
+	uuids := []string{"bad", "u0", "u1", "u2", "u3", "u4", "u5", "u6", "u7", "u0"}
+	resp, status, err := pn.WhereNowBatch().UUIDs(uuids).Concurrency(3).Execute()
+	assert.Nil(err)
+	assert.Equal(200, status.StatusCode)
+	assert.Equal(8, len(resp.Channels))
+	assert.Equal([]string{"u3-1", "u3-2"}, resp.Channels["u3"])
+	assert.Equal(1, len(resp.Errors))
+	assert.Contains(resp.Errors["bad"].Error(), "403")
+	assert.True(maxInFlight <= 3)
+	assert.True(maxInFlight > 1)
+
+	resp, status, err = pn.WhereNowBatch().UUIDs([]string{"bad"}).Execute()
+	assert.NotNil(err)
+	assert.Equal(403, status.StatusCode)
+	assert.Equal(1, len(resp.Errors))
+}
+
+func TestWhereNowBatchRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	inFlight, maxInFlight := 0, 0
+	pn := newWhereNowBatchTestPubNub(&inFlight, &maxInFlight)
+	defer pn.Destroy()
+
+	start := time.Now()
+	resp, _, err := pn.WhereNowBatch().UUIDs([]string{"a", "b", "c", "d", "e"}).RateLimit(50).Execute()
+	assert.Nil(err)
+	assert.Equal(5, len(resp.Channels))
+	assert.True(time.Since(start) >= 100*time.Millisecond)
+
+	// a nil context falls back to the context of the client
+	resp, _, err = pn.WhereNowBatchWithContext(nil).UUIDs([]string{"a", "b"}).RateLimit(50).Execute()
+	assert.Nil(err)
+	assert.Equal(2, len(resp.Channels))
+
+	// more than one request per nanosecond
+	resp, _, err = pn.WhereNowBatch().UUIDs([]string{"a", "b"}).RateLimit(2e9).Execute()
+	assert.Nil(err)
+	assert.Equal(2, len(resp.Channels))
+}
+
+func TestWhereNowBatchValidation(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+
+	_, status, err := pn.WhereNowBatch().UUIDs([]string{""}).Execute()
+	assert.Contains(err.Error(), StrMissingUUID)
+	assert.Equal(PNUnknownCategory, status.Category)
+
+	pn.Config.SubscribeKey = ""
+	_, _, err = pn.WhereNowBatch().UUIDs([]string{"a"}).Execute()
+	assert.Contains(err.Error(), StrMissingSubKey)
+}
+
+func TestWhereNowBatchUsesRequestWorkers(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+
+	builder := pn.WhereNow()
+	assert.False(builder.opts.useRequestWorkers())
+	builder.opts.inBatch = true
+	assert.True(builder.opts.useRequestWorkers())
+}
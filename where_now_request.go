@@ -47,6 +47,13 @@ func (b *whereNowBuilder) QueryParam(queryParam map[string]string) *whereNowBuil
 	return b
 }
 
+// Transport sets the Transport for the WhereNow request.
+func (b *whereNowBuilder) Transport(tr http.RoundTripper) *whereNowBuilder {
+	b.opts.Transport = tr
+
+	return b
+}
+
 // Execute runs the WhereNow request.
 func (b *whereNowBuilder) Execute() (*WhereNowResponse, StatusResponse, error) {
 	if len(b.opts.UUID) <= 0 {
@@ -67,6 +74,9 @@ type whereNowOpts struct {
 	UUID       string
 	QueryParam map[string]string
 	Transport  http.RoundTripper
+
+	// inBatch runs the request in the request workers, set by WhereNowBatch.
+	inBatch bool
 }
 
 func (o *whereNowOpts) validate() error {
@@ -101,6 +111,10 @@ func (o *whereNowOpts) connectTimeout() int {
 	return o.pubnub.Config.ConnectTimeout
 }
 
+func (o *whereNowOpts) useRequestWorkers() bool {
+	return o.inBatch
+}
+
 func (o *whereNowOpts) operationType() OperationType {
 	return PNWhereNowOperation
 }